@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	apiErr "eavesdropper/api/error"
+	cnfgs "eavesdropper/configurations"
+	"eavesdropper/dtos/requests"
+	"eavesdropper/dtos/resources"
+	"eavesdropper/dtos/responses"
+	"eavesdropper/errs"
+	"eavesdropper/services/audio"
+	"eavesdropper/services/clips"
+	"eavesdropper/services/transcripts"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+)
+
+// Cuts a clip out of the transcript audio, by offsets or around a segment, to be shared.
+// The clip is encoded on its own and expires, private by default.
+func CreateAudioClip(w http.ResponseWriter, r *http.Request) {
+	userID := r.PathValue("id")
+	transcriptID := r.PathValue("tId")
+	if userID == "" || transcriptID == "" {
+		apiErr.WriteJSONError(w, http.StatusBadRequest, "", "missing user or transcript id")
+		return
+	}
+
+	var req requests.CreateAudioClip
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apiErr.WriteJSONError(w, http.StatusBadRequest, "", "Invalid request body: "+err.Error())
+		return
+	}
+
+	clip, err := clips.CreateClip(r.Context(), userID, transcriptID, &req)
+	if err != nil {
+		writeAudioClipError(w, err, "Failed to create clip: ")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(audioClipToResponse(clip))
+}
+
+// Lists the clips of the transcript that did not expire, newest first
+func GetTranscriptAudioClips(w http.ResponseWriter, r *http.Request) {
+	userID := r.PathValue("id")
+	transcriptID := r.PathValue("tId")
+	if userID == "" || transcriptID == "" {
+		apiErr.WriteJSONError(w, http.StatusBadRequest, "", "missing user or transcript id")
+		return
+	}
+
+	transcriptClips, err := clips.GetTranscriptClips(r.Context(), userID, transcriptID)
+	if err != nil {
+		writeAudioClipError(w, err, "Failed to get clips: ")
+		return
+	}
+
+	response := make([]responses.AudioClipResponse, len(transcriptClips))
+	for i := range transcriptClips {
+		response[i] = audioClipToResponse(&transcriptClips[i])
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+func DeleteAudioClip(w http.ResponseWriter, r *http.Request) {
+	userID := r.PathValue("id")
+	clipID := r.PathValue("cId")
+	if userID == "" || clipID == "" {
+		apiErr.WriteJSONError(w, http.StatusBadRequest, "", "missing user or clip id")
+		return
+	}
+
+	if err := clips.DeleteClip(r.Context(), userID, clipID); err != nil {
+		writeAudioClipError(w, err, "Failed to delete clip: ")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Gets a clip. Public clips are visible to anyone, private ones to whoever can view the transcript.
+func GetAudioClip(w http.ResponseWriter, r *http.Request) {
+	clip, ok := viewableAudioClip(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(audioClipToResponse(clip))
+}
+
+// Streams the clip audio, with the access rules of GetAudioClip and the Range support of GetTranscriptAudio
+func GetAudioClipAudio(w http.ResponseWriter, r *http.Request) {
+	clip, ok := viewableAudioClip(w, r)
+	if !ok {
+		return
+	}
+
+	stored, err := audio.OpenClip(r.Context(), clip.StoragePath)
+	if err != nil {
+		writeAudioClipError(w, err, "Failed to read the clip audio: ")
+		return
+	}
+	defer stored.Content.Close()
+
+	w.Header().Set("Content-Type", stored.ContentType)
+	w.Header().Set("ETag", stored.ETag)
+	w.Header().Set("Cache-Control", "private, no-cache")
+	http.ServeContent(w, r, "", stored.UpdatedAt, stored.Content)
+}
+
+// Gets the clip of the 'id' path value when the caller may play it. The transcript must still be viewable,
+// by anyone for public clips, with the transcript access rules for private ones.
+func viewableAudioClip(w http.ResponseWriter, r *http.Request) (*resources.AudioClip, bool) {
+	clip, _, err := clips.GetClip(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeAudioClipError(w, err, "Failed to get clip: ")
+		return nil, false
+	}
+
+	if clip.IsPrivate {
+		_, _, ok := viewableTranscript(w, r, clip.TranscriptID)
+		return clip, ok
+	}
+
+	// Clips of trashed and deleted transcripts are gone with them
+	transcript, _, err := transcripts.GetTranscriptByID(r.Context(), clip.TranscriptID)
+	if err != nil {
+		apiErr.WriteJSONError(w, http.StatusNotFound, errs.ErrAudioClipNotFound.Error(), "Clip not found")
+		return nil, false
+	}
+	// A public clip never exposes a transcript made private after it was cut
+	if transcript.IsPrivate {
+		_, _, ok := viewableTranscript(w, r, clip.TranscriptID)
+		return clip, ok
+	}
+	return clip, true
+}
+
+func writeAudioClipError(w http.ResponseWriter, err error, msg string) {
+	switch {
+	case errors.Is(err, errs.ErrAudioClipNotFound):
+		apiErr.WriteJSONError(w, http.StatusNotFound, errs.ErrAudioClipNotFound.Error(), "Clip not found")
+	case errors.Is(err, errs.ErrInvalidAudioClip):
+		apiErr.WriteJSONError(w, http.StatusBadRequest, errs.ErrInvalidAudioClip.Error(), err.Error())
+	case errors.Is(err, errs.ErrTranscriptNotFound):
+		apiErr.WriteJSONError(w, http.StatusNotFound, errs.ErrTranscriptNotFound.Error(), "Transcript not found")
+	case errors.Is(err, errs.ErrArchivedAudioNotFound):
+		apiErr.WriteJSONError(w, http.StatusConflict, errs.ErrArchivedAudioNotFound.Error(), "The audio of this transcript is no longer stored")
+	case errors.Is(err, errs.ErrTranscoderBusy):
+		w.Header().Set("Retry-After", strconv.Itoa(int(cnfgs.TranscoderRetryAfter.Seconds())))
+		apiErr.WriteJSONError(w, http.StatusServiceUnavailable, errs.ErrTranscoderBusy.Error(), "Too many audio files are being processed, retry later")
+	default:
+		apiErr.WriteJSONError(w, http.StatusInternalServerError, "", msg+err.Error())
+	}
+}
+
+func audioClipToResponse(clip *resources.AudioClip) responses.AudioClipResponse {
+	return responses.AudioClipResponse{
+		ID:           clip.ID,
+		TranscriptID: clip.TranscriptID,
+		StartSeconds: clip.StartSeconds,
+		EndSeconds:   clip.EndSeconds,
+		SegmentIndex: clip.SegmentIndex,
+		Estimated:    clip.Estimated,
+		ContentType:  clip.ContentType,
+		Size:         clip.Size,
+		IsPrivate:    clip.IsPrivate,
+		AudioURL:     "/clips/" + clip.ID + "/audio",
+		ExpiresAt:    clip.ExpiresAt,
+		CreatedAt:    clip.CreatedAt,
+	}
+}
@@ -0,0 +1,265 @@
+package handlers
+
+import (
+	apiErr "eavesdropper/api/error"
+	"eavesdropper/dtos/requests"
+	"eavesdropper/dtos/resources"
+	"eavesdropper/dtos/responses"
+	"eavesdropper/errs"
+	db "eavesdropper/services/data/firestore/operations"
+	"eavesdropper/services/folders"
+	"eavesdropper/services/transcripts"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+func CreateFolder(w http.ResponseWriter, r *http.Request) {
+	userID := r.PathValue("id")
+	if userID == "" {
+		apiErr.WriteJSONError(w, http.StatusBadRequest, "", "missing user id")
+		return
+	}
+
+	var req requests.CreateFolder
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apiErr.WriteJSONError(w, http.StatusBadRequest, "", "Invalid request body: "+err.Error())
+		return
+	}
+
+	folder, err := folders.CreateFolder(r.Context(), userID, &req)
+	if err != nil {
+		writeFolderError(w, err, "Failed to create folder: ")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(folderToResponse(folder))
+}
+
+// Lists every folder of the user, by name. The client builds the tree from the parent ids.
+func GetFolders(w http.ResponseWriter, r *http.Request) {
+	userID := r.PathValue("id")
+	if userID == "" {
+		apiErr.WriteJSONError(w, http.StatusBadRequest, "", "missing user id")
+		return
+	}
+
+	userFolders, err := folders.GetFolders(r.Context(), userID)
+	if err != nil {
+		apiErr.WriteJSONError(w, http.StatusInternalServerError, "", "Failed to get folders: "+err.Error())
+		return
+	}
+
+	response := make([]responses.FolderResponse, len(userFolders))
+	for i, folder := range userFolders {
+		response[i] = folderToResponse(&folder)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+// Renames the folder and/or moves it under another one
+func UpdateFolder(w http.ResponseWriter, r *http.Request) {
+	userID := r.PathValue("id")
+	if userID == "" {
+		apiErr.WriteJSONError(w, http.StatusBadRequest, "", "missing user id")
+		return
+	}
+
+	folderID := r.PathValue("fId")
+	if folderID == "" {
+		apiErr.WriteJSONError(w, http.StatusBadRequest, "", "missing folder id")
+		return
+	}
+
+	var req requests.UpdateFolder
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apiErr.WriteJSONError(w, http.StatusBadRequest, "", "Invalid request body: "+err.Error())
+		return
+	}
+
+	folder, err := folders.UpdateFolder(r.Context(), userID, folderID, &req)
+	if err != nil {
+		writeFolderError(w, err, "Failed to update folder: ")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(folderToResponse(folder))
+}
+
+// Deletes the folder. The 'contents' query param says what happens to what it holds:
+// 'move' moves it to the parent folder, 'trash' trashes the transcripts and deletes the subfolders.
+// Without it only an empty folder is deleted, otherwise 409 is responded with the counts of its contents.
+func DeleteFolder(w http.ResponseWriter, r *http.Request) {
+	userID := r.PathValue("id")
+	if userID == "" {
+		apiErr.WriteJSONError(w, http.StatusBadRequest, "", "missing user id")
+		return
+	}
+
+	folderID := r.PathValue("fId")
+	if folderID == "" {
+		apiErr.WriteJSONError(w, http.StatusBadRequest, "", "missing folder id")
+		return
+	}
+
+	action := r.URL.Query().Get("contents")
+	if !folders.IsValidContentsAction(action) {
+		apiErr.WriteJSONError(w, http.StatusBadRequest, "", "The 'contents' query param must be 'move' or 'trash'")
+		return
+	}
+
+	response, err := folders.DeleteFolder(r.Context(), userID, folderID, folders.ContentsAction(action))
+	if errors.Is(err, errs.ErrFolderNotEmpty) {
+		apiErr.WriteJSONError(
+			w,
+			http.StatusConflict,
+			err.Error(),
+			fmt.Sprintf(
+				"The folder holds %d transcripts and %d folders, choose to move them or to trash them",
+				response.ContainedTranscripts,
+				response.ContainedFolders,
+			),
+		)
+		return
+	}
+	if err != nil {
+		writeFolderError(w, err, "Failed to delete folder: ")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+// Replaces the tags of the transcript
+func UpdateTranscriptTags(w http.ResponseWriter, r *http.Request) {
+	userID := r.PathValue("id")
+	if userID == "" {
+		apiErr.WriteJSONError(w, http.StatusBadRequest, "", "missing user id")
+		return
+	}
+
+	transcriptId := r.PathValue("tId")
+	if transcriptId == "" {
+		apiErr.WriteJSONError(w, http.StatusBadRequest, "", "missing transcript id")
+		return
+	}
+
+	var req requests.UpdateTranscriptTags
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apiErr.WriteJSONError(w, http.StatusBadRequest, "", "Invalid request body: "+err.Error())
+		return
+	}
+
+	tags, err := transcripts.SetTranscriptTags(r.Context(), userID, transcriptId, req.Tags)
+	if err != nil {
+		writeFolderError(w, err, "Failed to update transcript tags: ")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"tags": tags,
+	})
+}
+
+// Moves many transcripts to a folder at once, all or none are moved
+func MoveTranscripts(w http.ResponseWriter, r *http.Request) {
+	userID := r.PathValue("id")
+	if userID == "" {
+		apiErr.WriteJSONError(w, http.StatusBadRequest, "", "missing user id")
+		return
+	}
+
+	var req requests.MoveTranscripts
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apiErr.WriteJSONError(w, http.StatusBadRequest, "", "Invalid request body: "+err.Error())
+		return
+	}
+	if !validBulkTranscriptIDs(w, req.TranscriptIDs) {
+		return
+	}
+
+	err := transcripts.MoveTranscripts(r.Context(), userID, req.TranscriptIDs, req.FolderID)
+	if err != nil {
+		writeFolderError(w, err, "Failed to move transcripts: ")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// Adds and removes tags on many transcripts at once, all or none are updated
+func TagTranscripts(w http.ResponseWriter, r *http.Request) {
+	userID := r.PathValue("id")
+	if userID == "" {
+		apiErr.WriteJSONError(w, http.StatusBadRequest, "", "missing user id")
+		return
+	}
+
+	var req requests.TagTranscripts
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apiErr.WriteJSONError(w, http.StatusBadRequest, "", "Invalid request body: "+err.Error())
+		return
+	}
+	if !validBulkTranscriptIDs(w, req.TranscriptIDs) {
+		return
+	}
+	if len(req.Add) == 0 && len(req.Remove) == 0 {
+		apiErr.WriteJSONError(w, http.StatusBadRequest, "", "Missing the tags to add or remove")
+		return
+	}
+
+	err := transcripts.TagTranscripts(r.Context(), userID, req.TranscriptIDs, req.Add, req.Remove)
+	if err != nil {
+		writeFolderError(w, err, "Failed to tag transcripts: ")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func validBulkTranscriptIDs(w http.ResponseWriter, transcriptIDs []string) bool {
+	if len(transcriptIDs) == 0 {
+		apiErr.WriteJSONError(w, http.StatusBadRequest, "", "Missing the transcript ids")
+		return false
+	}
+	if len(transcriptIDs) > db.MaxBulkTranscripts {
+		apiErr.WriteJSONError(w, http.StatusBadRequest, "", fmt.Sprintf("At most %d transcripts can be updated at once", db.MaxBulkTranscripts))
+		return false
+	}
+	return true
+}
+
+func writeFolderError(w http.ResponseWriter, err error, msg string) {
+	switch {
+	case errors.Is(err, errs.ErrFolderNotFound):
+		apiErr.WriteJSONError(w, http.StatusNotFound, errs.ErrFolderNotFound.Error(), "Folder not found")
+	case errors.Is(err, errs.ErrTranscriptNotFound):
+		apiErr.WriteJSONError(w, http.StatusNotFound, errs.ErrTranscriptNotFound.Error(), "Transcript not found")
+	case errors.Is(err, errs.ErrFolderCycle):
+		apiErr.WriteJSONError(w, http.StatusConflict, errs.ErrFolderCycle.Error(), "A folder cannot be moved inside itself")
+	case errors.Is(err, errs.ErrInvalidFolderName):
+		apiErr.WriteJSONError(w, http.StatusBadRequest, errs.ErrInvalidFolderName.Error(), err.Error())
+	case errors.Is(err, errs.ErrInvalidTags):
+		apiErr.WriteJSONError(w, http.StatusBadRequest, errs.ErrInvalidTags.Error(), err.Error())
+	default:
+		apiErr.WriteJSONError(w, http.StatusInternalServerError, "", msg+err.Error())
+	}
+}
+
+func folderToResponse(folder *resources.Folder) responses.FolderResponse {
+	return responses.FolderResponse{
+		ID:        folder.ID,
+		Name:      folder.Name,
+		ParentID:  folder.ParentID,
+		CreatedAt: folder.CreatedAt,
+		UpdatedAt: folder.UpdatedAt,
+	}
+}
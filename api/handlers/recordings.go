@@ -0,0 +1,180 @@
+package handlers
+
+import (
+	apiErr "eavesdropper/api/error"
+	"eavesdropper/dtos/requests"
+	"eavesdropper/dtos/resources"
+	"eavesdropper/dtos/responses"
+	"eavesdropper/errs"
+	"eavesdropper/services/recordings"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+)
+
+// Starts a recording session. The client then uploads the chunks with signed URLs, registers each one
+// and finalizes the session before requesting its transcription.
+func StartRecordingSession(w http.ResponseWriter, r *http.Request) {
+	userID := r.PathValue("id")
+	if userID == "" {
+		apiErr.WriteJSONError(w, http.StatusBadRequest, "", "missing user id")
+		return
+	}
+
+	var req requests.StartRecordingSession
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apiErr.WriteJSONError(w, http.StatusBadRequest, "", "Invalid request body: "+err.Error())
+		return
+	}
+
+	session, err := recordings.StartSession(r.Context(), userID, req.MIME, req.ChunkSeconds)
+	if err != nil {
+		writeRecordingSessionError(w, err, "Failed to start recording session: ")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(recordingSessionToResponse(session))
+}
+
+func GetRecordingSession(w http.ResponseWriter, r *http.Request) {
+	userID, sessionID, ok := recordingSessionPathValues(w, r)
+	if !ok {
+		return
+	}
+
+	session, err := recordings.GetSession(r.Context(), userID, sessionID)
+	if err != nil {
+		writeRecordingSessionError(w, err, "Failed to get recording session: ")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(recordingSessionToResponse(session))
+}
+
+// Responds a signed upload URL for each of the chunk indexes in the body
+func GetChunkUploadURLs(w http.ResponseWriter, r *http.Request) {
+	userID, sessionID, ok := recordingSessionPathValues(w, r)
+	if !ok {
+		return
+	}
+
+	var req requests.ChunkUploadURLs
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apiErr.WriteJSONError(w, http.StatusBadRequest, "", "Invalid request body: "+err.Error())
+		return
+	}
+
+	urls, err := recordings.GetUploadURLs(r.Context(), userID, sessionID, req.Indexes)
+	if err != nil {
+		writeRecordingSessionError(w, err, "Failed to get upload urls: ")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(urls)
+}
+
+// Registers a chunk uploaded with its signed URL
+func RegisterRecordingChunk(w http.ResponseWriter, r *http.Request) {
+	userID, sessionID, ok := recordingSessionPathValues(w, r)
+	if !ok {
+		return
+	}
+
+	var req requests.RegisterRecordingChunk
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apiErr.WriteJSONError(w, http.StatusBadRequest, "", "Invalid request body: "+err.Error())
+		return
+	}
+	if req.Index == nil {
+		apiErr.WriteJSONError(w, http.StatusBadRequest, "", "Missing the chunk index")
+		return
+	}
+
+	session, err := recordings.RegisterChunk(r.Context(), userID, sessionID, *req.Index, req.SHA256)
+	if err != nil {
+		writeRecordingSessionError(w, err, "Failed to register chunk: ")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(recordingSessionToResponse(session))
+}
+
+// Writes the session manifest from the registered chunks. The session can then be transcribed.
+func FinalizeRecordingSession(w http.ResponseWriter, r *http.Request) {
+	userID, sessionID, ok := recordingSessionPathValues(w, r)
+	if !ok {
+		return
+	}
+
+	// The body is optional
+	var req requests.FinalizeRecordingSession
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		apiErr.WriteJSONError(w, http.StatusBadRequest, "", "Invalid request body: "+err.Error())
+		return
+	}
+
+	session, err := recordings.Finalize(r.Context(), userID, sessionID, req.Count)
+	if err != nil {
+		writeRecordingSessionError(w, err, "Failed to finalize recording session: ")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(recordingSessionToResponse(session))
+}
+
+func recordingSessionPathValues(w http.ResponseWriter, r *http.Request) (string, string, bool) {
+	userID := r.PathValue("id")
+	if userID == "" {
+		apiErr.WriteJSONError(w, http.StatusBadRequest, "", "missing user id")
+		return "", "", false
+	}
+
+	sessionID := r.PathValue("sId")
+	if sessionID == "" {
+		apiErr.WriteJSONError(w, http.StatusBadRequest, "", "missing session id")
+		return "", "", false
+	}
+
+	return userID, sessionID, true
+}
+
+func writeRecordingSessionError(w http.ResponseWriter, err error, msg string) {
+	switch {
+	case errors.Is(err, errs.ErrRecordingSessionNotFound):
+		apiErr.WriteJSONError(w, http.StatusNotFound, errs.ErrRecordingSessionNotFound.Error(), "Recording session not found")
+	case errors.Is(err, errs.ErrRecordingSessionClosed):
+		apiErr.WriteJSONError(w, http.StatusConflict, errs.ErrRecordingSessionClosed.Error(), "The recording session is finalized or expired")
+	case errors.Is(err, errs.ErrInvalidRecordingChunk):
+		apiErr.WriteJSONError(w, http.StatusBadRequest, errs.ErrInvalidRecordingChunk.Error(), err.Error())
+	case errors.Is(err, errs.ErrInvalidManifest), errors.Is(err, errs.ErrMissingRecordingChunks):
+		apiErr.WriteJSONError(w, http.StatusUnprocessableEntity, errs.ErrInvalidManifest.Error(), err.Error())
+	case errors.Is(err, errs.NoAudioChunksInManifest):
+		apiErr.WriteJSONError(w, http.StatusUnprocessableEntity, errs.NoAudioChunksInManifest.Error(), "No chunks were registered")
+	default:
+		apiErr.WriteJSONError(w, http.StatusInternalServerError, "", msg+err.Error())
+	}
+}
+
+func recordingSessionToResponse(session *resources.RecordingSession) responses.RecordingSessionResponse {
+	response := responses.RecordingSessionResponse{
+		ID:           session.ID,
+		MIME:         session.MIME,
+		Status:       string(session.Status),
+		ChunkSeconds: session.ChunkSeconds,
+		Chunks:       make([]responses.RecordingChunkResponse, len(session.Chunks)),
+		CreatedAt:    session.CreatedAt,
+		UpdatedAt:    session.UpdatedAt,
+		ExpiresAt:    session.ExpiresAt,
+	}
+	for i, chunk := range session.Chunks {
+		response.Chunks[i] = responses.RecordingChunkResponse{Index: chunk.Index, Size: chunk.Size, SHA256: chunk.SHA256}
+	}
+	return response
+}
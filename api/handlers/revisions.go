@@ -0,0 +1,336 @@
+package handlers
+
+import (
+	"context"
+	apiErr "eavesdropper/api/error"
+	"eavesdropper/dtos/requests"
+	"eavesdropper/dtos/resources"
+	"eavesdropper/dtos/responses"
+	"eavesdropper/errs"
+	"eavesdropper/services/search"
+	"eavesdropper/services/semantic"
+	"eavesdropper/services/transcripts"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Saves a manual edit of the transcript content as a new revision.
+// The version the edit is based on must be sent in the If-Match header (the ETag of the transcript)
+// or in the body. Responds 412 when the transcript changed in the meantime.
+func UpdateTranscriptContent(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userID := r.PathValue("id")
+	if userID == "" {
+		apiErr.WriteJSONError(w, http.StatusBadRequest, "", "missing user id")
+		return
+	}
+
+	transcriptID := r.PathValue("tId")
+	if transcriptID == "" {
+		apiErr.WriteJSONError(w, http.StatusBadRequest, "", "missing transcript id")
+		return
+	}
+
+	var req requests.UpdateTranscriptContent
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apiErr.WriteJSONError(w, http.StatusBadRequest, "", "Invalid request body: "+err.Error())
+		return
+	}
+	if strings.TrimSpace(req.Content) == "" {
+		apiErr.WriteJSONError(w, http.StatusBadRequest, "", "content cannot be empty")
+		return
+	}
+
+	expectedVersion, ok := parseExpectedVersion(w, r, req.Version)
+	if !ok {
+		return
+	}
+
+	transcript, err := transcripts.UpdateTranscriptContent(ctx, userID, transcriptID, userID, req.Content, expectedVersion)
+	if err != nil {
+		writeRevisionError(w, err, "Failed to update transcript content: ")
+		return
+	}
+
+	reindexTranscript(userID, transcript)
+
+	writeTranscriptETag(w, transcript)
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(transcriptToResponse(transcript))
+}
+
+// Lists the revisions of the transcript, newest first, without their content
+func GetTranscriptRevisions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userID := r.PathValue("id")
+	if userID == "" {
+		apiErr.WriteJSONError(w, http.StatusBadRequest, "", "missing user id")
+		return
+	}
+
+	transcriptID := r.PathValue("tId")
+	if transcriptID == "" {
+		apiErr.WriteJSONError(w, http.StatusBadRequest, "", "missing transcript id")
+		return
+	}
+
+	revisions, err := transcripts.GetTranscriptRevisions(ctx, userID, transcriptID)
+	if err != nil {
+		writeRevisionError(w, err, "Failed to get transcript revisions: ")
+		return
+	}
+
+	response := make([]responses.TranscriptRevisionResponse, len(revisions))
+	for i, revision := range revisions {
+		response[i] = revisionToResponse(&revision, false)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+func GetTranscriptRevision(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userID := r.PathValue("id")
+	if userID == "" {
+		apiErr.WriteJSONError(w, http.StatusBadRequest, "", "missing user id")
+		return
+	}
+
+	transcriptID := r.PathValue("tId")
+	if transcriptID == "" {
+		apiErr.WriteJSONError(w, http.StatusBadRequest, "", "missing transcript id")
+		return
+	}
+
+	version, ok := parsePathValueToInt(w, r, "version", true)
+	if !ok {
+		return
+	}
+
+	revision, err := transcripts.GetTranscriptRevision(ctx, userID, transcriptID, version)
+	if err != nil {
+		writeRevisionError(w, err, "Failed to get transcript revision: ")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(revisionToResponse(revision, true))
+}
+
+// Compares two revisions. The 'from' and 'to' query params are version numbers.
+func DiffTranscriptRevisions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userID := r.PathValue("id")
+	if userID == "" {
+		apiErr.WriteJSONError(w, http.StatusBadRequest, "", "missing user id")
+		return
+	}
+
+	transcriptID := r.PathValue("tId")
+	if transcriptID == "" {
+		apiErr.WriteJSONError(w, http.StatusBadRequest, "", "missing transcript id")
+		return
+	}
+
+	from, ok := parseQueryParamToInt(w, r, "from", true)
+	if !ok {
+		return
+	}
+	to, ok := parseQueryParamToInt(w, r, "to", true)
+	if !ok {
+		return
+	}
+
+	diff, err := transcripts.DiffTranscriptRevisions(ctx, userID, transcriptID, from, to)
+	if err != nil {
+		writeRevisionError(w, err, "Failed to diff transcript revisions: ")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(diff)
+}
+
+// Rolls the transcript content back to an earlier revision, saved as a new revision.
+// Same concurrency check as UpdateTranscriptContent.
+func RestoreTranscriptRevision(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userID := r.PathValue("id")
+	if userID == "" {
+		apiErr.WriteJSONError(w, http.StatusBadRequest, "", "missing user id")
+		return
+	}
+
+	transcriptID := r.PathValue("tId")
+	if transcriptID == "" {
+		apiErr.WriteJSONError(w, http.StatusBadRequest, "", "missing transcript id")
+		return
+	}
+
+	version, ok := parsePathValueToInt(w, r, "version", true)
+	if !ok {
+		return
+	}
+
+	// The body is optional when the If-Match header is used
+	var req requests.RestoreTranscriptRevision
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			apiErr.WriteJSONError(w, http.StatusBadRequest, "", "Invalid request body: "+err.Error())
+			return
+		}
+	}
+
+	expectedVersion, ok := parseExpectedVersion(w, r, req.Version)
+	if !ok {
+		return
+	}
+
+	transcript, err := transcripts.RestoreTranscriptRevision(ctx, userID, transcriptID, userID, version, expectedVersion)
+	if err != nil {
+		writeRevisionError(w, err, "Failed to restore transcript revision: ")
+		return
+	}
+
+	reindexTranscript(userID, transcript)
+
+	writeTranscriptETag(w, transcript)
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(transcriptToResponse(transcript))
+}
+
+// The ETag of a transcript is its content version, e.g. "3"
+func writeTranscriptETag(w http.ResponseWriter, transcript *resources.Transcript) {
+	w.Header().Set("ETag", strconv.Quote(strconv.Itoa(transcripts.Version(transcript))))
+}
+
+// Reads the version an edit is based on, from the If-Match header or else from the body
+func parseExpectedVersion(w http.ResponseWriter, r *http.Request, bodyVersion *int) (int, bool) {
+	ifMatch := strings.TrimSpace(r.Header.Get("If-Match"))
+	if ifMatch != "" {
+		ifMatch = strings.Trim(strings.TrimPrefix(ifMatch, "W/"), `"`)
+		version, err := strconv.Atoi(ifMatch)
+		if err != nil {
+			apiErr.WriteJSONError(w, http.StatusBadRequest, "", "Invalid If-Match header, expected the transcript ETag")
+			return 0, false
+		}
+		return version, true
+	}
+
+	if bodyVersion != nil {
+		return *bodyVersion, true
+	}
+
+	apiErr.WriteJSONError(
+		w,
+		http.StatusPreconditionRequired,
+		errs.ErrMissingTranscriptVersion.Error(),
+		"Send the transcript ETag in the If-Match header or its version in the body",
+	)
+	return 0, false
+}
+
+func writeRevisionError(w http.ResponseWriter, err error, msg string) {
+	switch {
+	case errors.Is(err, errs.ErrTranscriptVersionConflict):
+		apiErr.WriteJSONError(w, http.StatusPreconditionFailed, errs.ErrTranscriptVersionConflict.Error(), "The transcript was modified since the given version")
+	case errors.Is(err, errs.ErrTranscriptRevisionNotFound):
+		apiErr.WriteJSONError(w, http.StatusNotFound, errs.ErrTranscriptRevisionNotFound.Error(), "Revision not found")
+	case errors.Is(err, errs.ErrTranscriptNotFound):
+		apiErr.WriteJSONError(w, http.StatusNotFound, errs.ErrTranscriptNotFound.Error(), "Transcript not found")
+	default:
+		apiErr.WriteJSONError(w, http.StatusInternalServerError, "", msg+err.Error())
+	}
+}
+
+// Keeps the search indexes in sync after a content change. Failures are logged, the edit is already saved.
+func reindexTranscript(userID string, transcript *resources.Transcript) {
+	go func() {
+		ctx := context.Background()
+		if err := search.IndexTranscript(ctx, userID, transcript); err != nil {
+			fmt.Println("Failed to index transcript for search: ", err)
+		}
+		if err := semantic.IndexTranscript(ctx, userID, transcript); err != nil {
+			fmt.Println("Failed to embed transcript for semantic search: ", err)
+		}
+	}()
+}
+
+func revisionToResponse(revision *resources.TranscriptRevision, withContent bool) responses.TranscriptRevisionResponse {
+	response := responses.TranscriptRevisionResponse{
+		Version:             revision.Version,
+		Source:              string(revision.Source),
+		RestoredFromVersion: revision.RestoredFromVersion,
+		CreatedAt:           revision.CreatedAt,
+	}
+	if revision.AuthorRef != nil {
+		response.AuthorID = revision.AuthorRef.ID
+	}
+	if withContent {
+		response.Content = revision.Content
+	}
+	return response
+}
+
+// Transcribes the transcript again from its archived audio, with an optional model, prompt template and language.
+// The body chooses if the result is a new revision or a sibling transcript, and if it is billed
+// or covered as a quality complaint. Responds the updated or the new transcript.
+func RetranscribeTranscript(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userID := r.PathValue("id")
+	if userID == "" {
+		apiErr.WriteJSONError(w, http.StatusBadRequest, "", "missing user id")
+		return
+	}
+
+	transcriptId := r.PathValue("tId")
+	if transcriptId == "" {
+		apiErr.WriteJSONError(w, http.StatusBadRequest, "", "missing transcript id")
+		return
+	}
+
+	var req requests.RetranscribeTranscript
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apiErr.WriteJSONError(w, http.StatusBadRequest, "", "Invalid request body: "+err.Error())
+		return
+	}
+
+	transcript, err := transcripts.Retranscribe(ctx, userID, transcriptId, &req)
+	switch {
+	case errors.Is(err, errs.ErrInvalidRetranscription):
+		apiErr.WriteJSONError(w, http.StatusBadRequest, errs.ErrInvalidRetranscription.Error(), err.Error())
+		return
+	case errors.Is(err, errs.ErrTranscriptNotFound):
+		apiErr.WriteJSONError(w, http.StatusNotFound, err.Error(), "Transcript not found")
+		return
+	case errors.Is(err, errs.ErrArchivedAudioNotFound):
+		apiErr.WriteJSONError(w, http.StatusConflict, err.Error(), "The audio of this transcript is no longer stored")
+		return
+	case errors.Is(err, errs.ErrExceededSubscriptionTranscriptionLimits):
+		apiErr.WriteJSONError(w, http.StatusForbidden, err.Error(), "Subscription limits have been reached or will be with this transcription")
+		return
+	case errors.Is(err, errs.ErrComplaintRetranscriptionsExceeded):
+		apiErr.WriteJSONError(w, http.StatusForbidden, err.Error(), "This audio has no complaint re-transcriptions left, the next ones are billed")
+		return
+	case err != nil:
+		apiErr.WriteJSONError(w, http.StatusInternalServerError, "", "Failed to re-transcribe: "+err.Error())
+		return
+	}
+
+	reindexTranscript(userID, transcript)
+
+	writeTranscriptETag(w, transcript)
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(transcriptToResponse(transcript))
+}
@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	apiErr "eavesdropper/api/error"
+	"eavesdropper/services/search"
+	"eavesdropper/services/semantic"
+	"encoding/json"
+	"net/http"
+)
+
+// Full-text search over the user's transcripts.
+// Query params:
+//   - q: words to find. Text inside double quotes is matched as an exact phrase.
+//   - speaker: optional and repeatable, e.g. speaker=Speaker 1
+//   - from, to: optional creation date range, RFC3339 or YYYY-MM-DD
+//   - limit: optional max number of transcripts in the response, at most search.MaxResultsLimit
+func SearchUserTranscripts(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userID := r.PathValue("id")
+	if userID == "" {
+		apiErr.WriteJSONError(w, http.StatusBadRequest, "", "missing user id")
+		return
+	}
+
+	q := r.URL.Query().Get("q")
+	if q == "" {
+		apiErr.WriteJSONError(w, http.StatusBadRequest, "", "Missing the 'q' query param")
+		return
+	}
+
+	query := &search.Query{
+		Phrases:  search.ParsePhrases(q),
+		Speakers: r.URL.Query()["speaker"],
+	}
+
+	var ok bool
+	if query.From, ok = parseQueryParamToTime(w, r, "from", false); !ok && r.URL.Query().Get("from") != "" {
+		return
+	}
+	if query.To, ok = parseQueryParamToTime(w, r, "to", false); !ok && r.URL.Query().Get("to") != "" {
+		return
+	}
+	if query.Limit, ok = parseQueryParamToInt(w, r, "limit", false); !ok && r.URL.Query().Get("limit") != "" {
+		return
+	}
+
+	response, err := search.SearchUserTranscripts(ctx, userID, query)
+	if err == search.ErrEmptySearchQuery {
+		apiErr.WriteJSONError(w, http.StatusBadRequest, "", "The 'q' query param has no searchable words")
+		return
+	}
+	if err != nil {
+		apiErr.WriteJSONError(w, http.StatusInternalServerError, "", "Failed to search transcripts: "+err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+// Rebuilds the search index of every transcript of the user
+func ReindexUserTranscripts(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userID := r.PathValue("id")
+	if userID == "" {
+		apiErr.WriteJSONError(w, http.StatusBadRequest, "", "missing user id")
+		return
+	}
+
+	count, err := search.ReindexUserTranscripts(ctx, userID)
+	if err != nil {
+		apiErr.WriteJSONError(w, http.StatusInternalServerError, "", "Failed to reindex transcripts: "+err.Error())
+		return
+	}
+
+	response := map[string]interface{}{
+		"indexed": count,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+// Finds the transcript passages closest in meaning to the 'q' query param,
+// across the transcripts the user owns or is whitelisted on.
+func SemanticSearchTranscripts(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userID := r.PathValue("id")
+	if userID == "" {
+		apiErr.WriteJSONError(w, http.StatusBadRequest, "", "missing user id")
+		return
+	}
+
+	q := r.URL.Query().Get("q")
+	if q == "" {
+		apiErr.WriteJSONError(w, http.StatusBadRequest, "", "Missing the 'q' query param")
+		return
+	}
+
+	limit, ok := parseQueryParamToInt(w, r, "limit", false)
+	if !ok && r.URL.Query().Get("limit") != "" {
+		return
+	}
+
+	response, err := semantic.SearchSimilarPassages(ctx, userID, q, limit)
+	if err == semantic.ErrEmptySemanticQuery {
+		apiErr.WriteJSONError(w, http.StatusBadRequest, "", "The 'q' query param is empty")
+		return
+	}
+	if err != nil {
+		apiErr.WriteJSONError(w, http.StatusInternalServerError, "", "Failed to search transcripts: "+err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+// Embeds every transcript of the user again
+func ReindexUserTranscriptEmbeddings(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userID := r.PathValue("id")
+	if userID == "" {
+		apiErr.WriteJSONError(w, http.StatusBadRequest, "", "missing user id")
+		return
+	}
+
+	count, err := semantic.ReindexUserTranscripts(ctx, userID)
+	if err != nil {
+		apiErr.WriteJSONError(w, http.StatusInternalServerError, "", "Failed to embed transcripts: "+err.Error())
+		return
+	}
+
+	response := map[string]interface{}{
+		"indexed": count,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(response)
+}
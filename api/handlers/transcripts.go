@@ -1,19 +1,37 @@
 package handlers
 
 import (
+	"context"
 	apiErr "eavesdropper/api/error"
 	"eavesdropper/api/middlewares"
+	cnfgs "eavesdropper/configurations"
+	"eavesdropper/dtos/resources"
+	"eavesdropper/dtos/responses"
 	"eavesdropper/errs"
 	"eavesdropper/services/audio"
 	"eavesdropper/services/auth"
+	db "eavesdropper/services/data/firestore/operations"
+	"eavesdropper/services/export"
+	"eavesdropper/services/recordings"
+	"eavesdropper/services/search"
+	"eavesdropper/services/semantic"
 	"eavesdropper/services/transcribe"
 	"eavesdropper/services/transcripts"
 	"eavesdropper/services/users"
 	"eavesdropper/services/whitelist"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"mime/multipart"
 	"net/http"
 	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
 )
 
 // Handles a transcription request.
@@ -22,6 +40,12 @@ import (
 // Checks if the user is allowed to transcribe it. (free audio seconds + subscription's are enough to cover the full audio)
 // Stores the transcript, udates the user data. (deducts free credits fully, the rest of the audio is accounted as paid usage)
 // Responds full transcript + id
+//
+// Idempotent per session: while the session is being transcribed further requests get 202 with the job,
+// once done they get the existing transcript (with the Idempotent-Replayed header) and nothing is billed.
+// The 'force=true' query param transcribes the session again into a new transcript, which is billed again.
+// The 'integrity' query param, strict (default) or lenient, decides whether missing or corrupt chunks fail
+// the transcription or are left out and listed in the transcript.
 func Transcribe(w http.ResponseWriter, r *http.Request) {
 
 	fmt.Println("on transcribe handler")
@@ -45,25 +69,121 @@ func Transcribe(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	force := false
+	if forceParam, ok := parseQueryParamToBool(w, r, "force", false); ok {
+		force = *forceParam
+	} else if r.URL.Query().Get("force") != "" {
+		return
+	}
+
+	options, ok := parseProcessingOptions(w, r, userId)
+	if !ok {
+		return
+	}
+
+	integrity := recordings.DefaultIntegrityMode
+	if mode := r.URL.Query().Get("integrity"); mode != "" {
+		if !recordings.IsValidIntegrityMode(mode) {
+			apiErr.WriteJSONError(w, http.StatusBadRequest, "", "integrity must be strict or lenient")
+			return
+		}
+		integrity = recordings.IntegrityMode(mode)
+	}
+
+	runTranscription(w, r, userId, sessionId, force, "Failed to process audio chunks: ",
+		func(ctx context.Context, tmpDir string) (*audio.PreparedAudio, error) {
+			if err := recordings.CheckTranscribable(ctx, userId, sessionId); err != nil {
+				return nil, err
+			}
+			return audio.ProcessAudioChunks(ctx, sessionId, userId, integrity, options)
+		},
+	)
+}
+
+// Prepares the audio to transcribe: transcodes it, archives it and uploads it to gemini.
+// The temporary directory is for inputs that have to be on disk, like uploaded files.
+type audioPreparer func(ctx context.Context, tmpDir string) (*audio.PreparedAudio, error)
+
+// Transcription pipeline shared by the recorded sessions and the uploaded files:
+// claims the session, prepares the audio, checks the quota, transcribes with gemini, saves and indexes the transcript.
+func runTranscription(
+	w http.ResponseWriter,
+	r *http.Request,
+	userId, sessionId string,
+	force bool,
+	prepareErrMsg string,
+	prepareAudio audioPreparer,
+) {
+	ctx := r.Context()
+
+	claim, err := transcripts.ClaimTranscription(ctx, userId, sessionId, force)
+	if err != nil {
+		apiErr.WriteJSONError(w, http.StatusInternalServerError, "", "Failed to start the transcription: "+err.Error())
+		return
+	}
+	if claim.Existing != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Idempotent-Replayed", "true")
+		_ = json.NewEncoder(w).Encode(transcriptToResponse(claim.Existing))
+		return
+	}
+	if !claim.Claimed {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		_ = json.NewEncoder(w).Encode(transcriptionJobToResponse(claim.Job))
+		return
+	}
+
+	// Any failure from here releases the session so the client can retry,
+	// and deletes the audio uploaded to gemini, which is not transcribed
+	stopHeartbeat := transcripts.KeepTranscriptionAlive(userId, sessionId)
+	completed := false
+	var failure error
+	var prepared *audio.PreparedAudio
+	defer func() {
+		stopHeartbeat()
+		if completed {
+			return
+		}
+		if prepared != nil {
+			if err := transcribe.DeleteUploadedAudio(context.Background(), prepared.GeminiFile); err != nil {
+				fmt.Println("Failed to delete the uploaded audio: ", err)
+			}
+		}
+		if failure == nil {
+			failure = errors.New("transcription did not complete")
+		}
+		if err := transcripts.FailTranscription(context.Background(), userId, sessionId, failure); err != nil {
+			fmt.Println("Failed to release transcription job: ", err)
+		}
+	}()
+
 	tmpDir, err := os.MkdirTemp("", "finalize-*")
 	if err != nil {
+		failure = err
 		apiErr.WriteJSONError(w, http.StatusInternalServerError, "", "Failed to create temporary directory to store audio files")
 		return
 	}
 	defer os.RemoveAll(tmpDir)
 
-	audioPath, _, audioSeconds, err := audio.ProcessAudioChunks(ctx, sessionId, userId, tmpDir)
+	prepared, err = prepareAudio(ctx, tmpDir)
 	if err != nil {
-		apiErr.WriteJSONError(w, http.StatusInternalServerError, "", "Failed to process audio chunks: "+err.Error())
+		failure = err
+		writeAudioPreparationError(w, err, prepareErrMsg)
 		return
 	}
 
-	_, consumedFreeAudioSeconds, err := transcribe.TranscriptionAllowed(ctx, userId, audioSeconds)
+	_, billedAudioSeconds, consumedFreeAudioSeconds, err := transcribe.TranscriptionAllowed(ctx, userId, transcribe.AudioSeconds{
+		Total:  prepared.Seconds,
+		Speech: prepared.SpeechSeconds,
+	})
 	if err != nil && err != errs.ErrExceededSubscriptionTranscriptionLimits {
+		failure = err
 		apiErr.WriteJSONError(w, http.StatusInternalServerError, "", "Failed to check if the transcription is allowed: "+err.Error())
 		return
 	}
 	if err == errs.ErrExceededSubscriptionTranscriptionLimits {
+		failure = err
 		apiErr.WriteJSONError(
 			w,
 			http.StatusForbidden,
@@ -73,8 +193,9 @@ func Transcribe(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	transcriptionResponse, err := transcribe.TranscribeAudioFile(ctx, audioPath, "audio/wav")
+	transcriptionResponse, err := transcribe.TranscribeUploadedAudio(ctx, prepared.GeminiFile, transcribe.DefaultOptions())
 	if err != nil {
+		failure = err
 		apiErr.WriteJSONError(
 			w,
 			http.StatusInternalServerError,
@@ -94,9 +215,19 @@ func Transcribe(w http.ResponseWriter, r *http.Request) {
 		ctx,
 		transcriptionResponse,
 		userId, sessionId,
-		audioSeconds, consumedFreeAudioSeconds,
+		billedAudioSeconds, consumedFreeAudioSeconds,
+		db.TranscriptAudio{
+			DurationSeconds: prepared.Duration.Seconds(),
+			SpeechSeconds:   prepared.SpeechDuration.Seconds(),
+			NonSpeechSpans:  prepared.NonSpeechSpans,
+			SilenceTrimmed:  prepared.SilenceTrimmed,
+			Enhancement:     string(prepared.Enhancement),
+			Quality:         prepared.Quality,
+			MissingAudio:    prepared.MissingAudio,
+		},
 	)
 	if err != nil {
+		failure = err
 		apiErr.WriteJSONError(
 			w,
 			http.StatusInternalServerError,
@@ -106,6 +237,27 @@ func Transcribe(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	completed = true
+	stopHeartbeat()
+	err = transcripts.CompleteTranscription(ctx, userId, sessionId, savedTranscript.ID)
+	if err != nil {
+		// The transcript is saved, a retry finds it by its session
+		fmt.Println("Failed to complete transcription job: ", err)
+	}
+
+	err = search.IndexTranscript(ctx, userId, savedTranscript)
+	if err != nil {
+		fmt.Println("Failed to index transcript for search: ", err)
+	}
+
+	// Embedding takes a few seconds and the transcript is usable without it
+	go func() {
+		err := semantic.IndexTranscript(context.Background(), userId, savedTranscript)
+		if err != nil {
+			fmt.Println("Failed to embed transcript for semantic search: ", err)
+		}
+	}()
+
 	go users.DecrementFreeTier(userId, consumedFreeAudioSeconds)
 
 	response := transcriptToResponse(savedTranscript)
@@ -114,12 +266,24 @@ func Transcribe(w http.ResponseWriter, r *http.Request) {
 }
 
 func GetTranscript(w http.ResponseWriter, r *http.Request) {
+	transcript, _, ok := viewableTranscript(w, r, r.PathValue("id"))
+	if !ok {
+		return
+	}
+
+	response := publicTranscriptToResponse(transcript)
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+// Gets the transcript with its owner ID when the caller may view it: anyone when it is public,
+// its owner and the whitelisted users when it is private. Writes the error response otherwise.
+func viewableTranscript(w http.ResponseWriter, r *http.Request, transcriptID string) (*resources.Transcript, string, bool) {
 	ctx := r.Context()
 
-	transcriptID := r.PathValue("id")
 	if transcriptID == "" {
 		apiErr.WriteJSONError(w, http.StatusBadRequest, "", "missing transcript id")
-		return
+		return nil, "", false
 	}
 
 	userId := ""
@@ -139,42 +303,121 @@ func GetTranscript(w http.ResponseWriter, r *http.Request) {
 	transcript, transcriptOwnerID, err := transcripts.GetTranscriptByID(ctx, transcriptID)
 	if err != nil {
 		apiErr.WriteJSONError(w, http.StatusNotFound, "", "Transcript not found: "+err.Error())
-		return
+		return nil, "", false
 	}
 
 	if !transcript.IsPrivate {
-		response := transcriptToResponse(transcript)
-		w.Header().Set("Content-Type", "application/json")
-		_ = json.NewEncoder(w).Encode(response)
-		return
+		return transcript, transcriptOwnerID, true
 	}
 
 	if userId == "" {
 		apiErr.WriteJSONError(w, http.StatusUnauthorized, "", "Authentication required to access private transcript")
-		return
+		return nil, "", false
 	}
 
 	if userId == transcriptOwnerID {
-		response := transcriptToResponse(transcript)
-		w.Header().Set("Content-Type", "application/json")
-		_ = json.NewEncoder(w).Encode(response)
-		return
+		return transcript, transcriptOwnerID, true
 	}
 
 	isWhitelisted, err := whitelist.IsUserWhitelistedForTranscript(ctx, transcriptOwnerID, transcriptID, userId)
 	if err != nil {
 		apiErr.WriteJSONError(w, http.StatusInternalServerError, "", "Failed to check whitelist: "+err.Error())
-		return
+		return nil, "", false
 	}
 
 	if !isWhitelisted {
 		apiErr.WriteJSONError(w, http.StatusForbidden, "", "Access denied")
+		return nil, "", false
+	}
+
+	return transcript, transcriptOwnerID, true
+}
+
+// Serves the waveform peaks of the transcript audio, in the audiowaveform binary format (8-bit, version 1).
+// The 'zoom' query param picks the samples per peak among cnfgs.WaveformZooms, the finest by default.
+// Same access rules as GetTranscript.
+func GetTranscriptPeaks(w http.ResponseWriter, r *http.Request) {
+	zoom := cnfgs.WaveformZooms[0]
+	if r.URL.Query().Get("zoom") != "" {
+		value, ok := parseQueryParamToInt(w, r, "zoom", false)
+		if !ok {
+			return
+		}
+		if !slices.Contains(cnfgs.WaveformZooms, value) {
+			apiErr.WriteJSONError(w, http.StatusBadRequest, "", fmt.Sprintf("zoom must be one of %v", cnfgs.WaveformZooms))
+			return
+		}
+		zoom = value
+	}
+
+	transcript, ownerID, ok := viewableTranscript(w, r, r.PathValue("id"))
+	if !ok {
 		return
 	}
 
-	response := transcriptToResponse(transcript)
-	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(response)
+	peaks, err := audio.OpenWaveformPeaks(r.Context(), ownerID, db.TranscriptAudioSession(transcript), zoom)
+	if errors.Is(err, errs.ErrWaveformPeaksNotFound) {
+		apiErr.WriteJSONError(w, http.StatusNotFound, errs.ErrWaveformPeaksNotFound.Error(), "The transcript audio has no waveform peaks")
+		return
+	}
+	if err != nil {
+		apiErr.WriteJSONError(w, http.StatusInternalServerError, "", "Failed to read waveform peaks: "+err.Error())
+		return
+	}
+	defer peaks.Close()
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Length", strconv.FormatInt(peaks.Attrs.Size, 10))
+	_, _ = io.Copy(w, peaks)
+}
+
+// Streams the transcript audio, with the access rules of GetTranscript.
+// Supports Range requests (206) and revalidation with the ETag. With 'redirect=true' it redirects
+// to a short-lived signed URL instead, so the player downloads it straight from storage.
+func GetTranscriptAudio(w http.ResponseWriter, r *http.Request) {
+	redirect := false
+	if redirectParam, ok := parseQueryParamToBool(w, r, "redirect", false); ok {
+		redirect = *redirectParam
+	} else if r.URL.Query().Get("redirect") != "" {
+		return
+	}
+
+	transcript, ownerID, ok := viewableTranscript(w, r, r.PathValue("id"))
+	if !ok {
+		return
+	}
+
+	if redirect {
+		url, err := audio.ArchivedAudioURL(r.Context(), ownerID, db.TranscriptAudioSession(transcript))
+		if err != nil {
+			writeArchivedAudioError(w, err)
+			return
+		}
+		w.Header().Set("Cache-Control", "no-store") // the url expires
+		http.Redirect(w, r, url, http.StatusFound)
+		return
+	}
+
+	archived, err := audio.OpenArchivedAudio(r.Context(), ownerID, db.TranscriptAudioSession(transcript))
+	if err != nil {
+		writeArchivedAudioError(w, err)
+		return
+	}
+	defer archived.Content.Close()
+
+	w.Header().Set("Content-Type", archived.ContentType)
+	w.Header().Set("ETag", archived.ETag)
+	w.Header().Set("Cache-Control", "private, no-cache")
+	// Answers the Range, If-Range and If-None-Match headers
+	http.ServeContent(w, r, "", archived.UpdatedAt, archived.Content)
+}
+
+func writeArchivedAudioError(w http.ResponseWriter, err error) {
+	if errors.Is(err, errs.ErrArchivedAudioNotFound) {
+		apiErr.WriteJSONError(w, http.StatusNotFound, errs.ErrArchivedAudioNotFound.Error(), "The transcript audio is not stored")
+		return
+	}
+	apiErr.WriteJSONError(w, http.StatusInternalServerError, "", "Failed to read the transcript audio: "+err.Error())
 }
 
 func UpdateTranscriptVisibility(w http.ResponseWriter, r *http.Request) {
@@ -247,3 +490,327 @@ func UpdateTranscriptTitle(w http.ResponseWriter, r *http.Request) {
 
 	w.WriteHeader(http.StatusOK)
 }
+
+// Responds the transcript as a downloadable document.
+// The 'format' query param selects the output: md, txt, docx or pdf.
+func ExportTranscript(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userID := r.PathValue("id")
+	if userID == "" {
+		apiErr.WriteJSONError(w, http.StatusBadRequest, "", "missing user id")
+		return
+	}
+
+	transcriptId := r.PathValue("tId")
+	if transcriptId == "" {
+		apiErr.WriteJSONError(w, http.StatusBadRequest, "", "missing transcript id")
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if !export.IsSupportedFormat(format) {
+		apiErr.WriteJSONError(w, http.StatusBadRequest, "", "The 'format' query param must be one of md, txt, docx or pdf")
+		return
+	}
+
+	transcript, err := transcripts.GetUserTranscript(ctx, userID, transcriptId)
+	if err != nil {
+		apiErr.WriteJSONError(w, http.StatusNotFound, "", "Transcript not found: "+err.Error())
+		return
+	}
+
+	data, contentType, fileName, err := export.Export(transcript, export.Format(format))
+	if err != nil {
+		apiErr.WriteJSONError(w, http.StatusInternalServerError, "", "Failed to export transcript: "+err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fileName))
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	_, _ = w.Write(data)
+}
+
+// Moves the transcript to the trash and responds it.
+// With the 'permanent=true' query param it is deleted right away with its whitelist, revisions, indexes and audio,
+// and the cleanup report is responded. Repeating a permanent delete retries the failed cleanup steps.
+func DeleteTranscript(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userID := r.PathValue("id")
+	if userID == "" {
+		apiErr.WriteJSONError(w, http.StatusBadRequest, "", "missing user id")
+		return
+	}
+
+	transcriptId := r.PathValue("tId")
+	if transcriptId == "" {
+		apiErr.WriteJSONError(w, http.StatusBadRequest, "", "missing transcript id")
+		return
+	}
+
+	if r.URL.Query().Get("permanent") != "true" {
+		transcript, err := transcripts.TrashTranscript(ctx, userID, transcriptId)
+		if errors.Is(err, errs.ErrTranscriptNotFound) {
+			apiErr.WriteJSONError(w, http.StatusNotFound, err.Error(), "Transcript not found")
+			return
+		}
+		if err != nil {
+			apiErr.WriteJSONError(w, http.StatusInternalServerError, "", "Failed to move transcript to the trash: "+err.Error())
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(transcriptToResponse(transcript))
+		return
+	}
+
+	response, err := transcripts.DeleteTranscript(ctx, userID, transcriptId)
+	if errors.Is(err, errs.ErrTranscriptNotFound) {
+		apiErr.WriteJSONError(w, http.StatusNotFound, err.Error(), "Transcript not found")
+		return
+	}
+	if err != nil {
+		apiErr.WriteJSONError(w, http.StatusInternalServerError, "", "Failed to delete transcript: "+err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+// Takes the transcript out of the trash
+func RestoreTrashedTranscript(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userID := r.PathValue("id")
+	if userID == "" {
+		apiErr.WriteJSONError(w, http.StatusBadRequest, "", "missing user id")
+		return
+	}
+
+	transcriptId := r.PathValue("tId")
+	if transcriptId == "" {
+		apiErr.WriteJSONError(w, http.StatusBadRequest, "", "missing transcript id")
+		return
+	}
+
+	err := transcripts.RestoreTrashedTranscript(ctx, userID, transcriptId)
+	if errors.Is(err, errs.ErrTranscriptNotFound) {
+		apiErr.WriteJSONError(w, http.StatusNotFound, err.Error(), "Transcript not found, it may have been purged from the trash")
+		return
+	}
+	if err != nil {
+		apiErr.WriteJSONError(w, http.StatusInternalServerError, "", "Failed to restore transcript: "+err.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// Lists the transcripts in the trash, most recently trashed first
+func GetTrashedUserTranscripts(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userID := r.PathValue("id")
+	if userID == "" {
+		apiErr.WriteJSONError(w, http.StatusBadRequest, "", "missing user id")
+		return
+	}
+
+	trashed, err := transcripts.GetTrashedUserTranscripts(ctx, userID)
+	if err != nil {
+		apiErr.WriteJSONError(w, http.StatusInternalServerError, "", "Failed to get trashed transcripts: "+err.Error())
+		return
+	}
+
+	response := make([]responses.TranscriptionResponse, len(trashed))
+	for i, transcript := range trashed {
+		response[i] = transcriptToResponse(&transcript)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+// Status of the transcription of a recording session, polled after POST /transcripts responds 202
+func GetTranscriptionJob(w http.ResponseWriter, r *http.Request) {
+	userID := r.PathValue("id")
+	if userID == "" {
+		apiErr.WriteJSONError(w, http.StatusBadRequest, "", "missing user id")
+		return
+	}
+
+	sessionID := r.PathValue("sessionId")
+	if sessionID == "" {
+		apiErr.WriteJSONError(w, http.StatusBadRequest, "", "missing session id")
+		return
+	}
+
+	job, err := transcripts.GetTranscriptionJob(r.Context(), userID, sessionID)
+	if errors.Is(err, errs.ErrTranscriptionJobNotFound) {
+		apiErr.WriteJSONError(w, http.StatusNotFound, err.Error(), "No transcription for this session")
+		return
+	}
+	if err != nil {
+		apiErr.WriteJSONError(w, http.StatusInternalServerError, "", "Failed to get transcription job: "+err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(transcriptionJobToResponse(job))
+}
+
+func transcriptionJobToResponse(job *resources.TranscriptionJob) responses.TranscriptionJobResponse {
+	return responses.TranscriptionJobResponse{
+		SessionID:    job.SessionID,
+		Status:       string(job.Status),
+		TranscriptID: job.TranscriptID,
+		Forced:       job.Forced,
+		Error:        job.Error,
+		StartedAt:    job.StartedAt,
+		UpdatedAt:    job.UpdatedAt,
+	}
+}
+
+// Transcribes an audio or video file uploaded as the 'file' field of a multipart form,
+// for recordings made outside the app. Goes through the same pipeline as recorded sessions.
+// The optional 'uploadId' query param makes retries of the same upload idempotent, like the sessionId
+// of recorded sessions, and 'force=true' transcribes it again. Without it every upload is a new transcription.
+func UploadAndTranscribe(w http.ResponseWriter, r *http.Request) {
+	userId := r.PathValue("id")
+	if userId == "" {
+		apiErr.WriteJSONError(w, http.StatusBadRequest, "", "missing user id")
+		return
+	}
+
+	sessionId := r.URL.Query().Get("uploadId")
+	if sessionId == "" {
+		sessionId = uuid.NewString()
+	}
+
+	force := false
+	if forceParam, ok := parseQueryParamToBool(w, r, "force", false); ok {
+		force = *forceParam
+	} else if r.URL.Query().Get("force") != "" {
+		return
+	}
+
+	options, ok := parseProcessingOptions(w, r, userId)
+	if !ok {
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, cnfgs.MaxUploadBytes)
+	multipartReader, err := r.MultipartReader()
+	if err != nil {
+		apiErr.WriteJSONError(w, http.StatusBadRequest, "", "Expected a multipart form: "+err.Error())
+		return
+	}
+
+	runTranscription(w, r, userId, sessionId, force, "Failed to process uploaded file: ",
+		func(ctx context.Context, tmpDir string) (*audio.PreparedAudio, error) {
+			uploadedPath, err := saveUploadedFile(multipartReader, tmpDir)
+			if err != nil {
+				return nil, err
+			}
+			return audio.ProcessUploadedFile(ctx, uploadedPath, sessionId, userId, options)
+		},
+	)
+}
+
+// Streams the 'file' part of the form into the directory, without holding it in memory
+func saveUploadedFile(multipartReader *multipart.Reader, directory string) (string, error) {
+	for {
+		part, err := multipartReader.NextPart()
+		if err == io.EOF {
+			return "", fmt.Errorf("%w: missing the 'file' form field", errs.ErrUnsupportedAudioFile)
+		}
+		if err != nil {
+			return "", uploadReadError(err)
+		}
+		if part.FormName() != "file" {
+			part.Close()
+			continue
+		}
+		defer part.Close()
+
+		if !audio.IsSupportedUploadExtension(part.FileName()) {
+			return "", fmt.Errorf("%w: unsupported file type %s", errs.ErrUnsupportedAudioFile, filepath.Ext(part.FileName()))
+		}
+
+		uploadedPath := filepath.Join(directory, "upload"+strings.ToLower(filepath.Ext(part.FileName())))
+		file, err := os.Create(uploadedPath)
+		if err != nil {
+			return "", err
+		}
+		defer file.Close()
+
+		if _, err := io.Copy(file, part); err != nil {
+			return "", uploadReadError(err)
+		}
+		return uploadedPath, nil
+	}
+}
+
+func uploadReadError(err error) error {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return fmt.Errorf("%w: the file is larger than %d MB", errs.ErrUploadTooLarge, maxBytesErr.Limit>>20)
+	}
+	return fmt.Errorf("read upload: %w", err)
+}
+
+// Reads the audio processing query params: 'trimSilence=true' leaves the non-speech spans out of the transcribed audio
+// and 'enhancement' picks the enhancement profile, the user preference when missing.
+func parseProcessingOptions(w http.ResponseWriter, r *http.Request, userId string) (audio.ProcessingOptions, bool) {
+	options := audio.ProcessingOptions{}
+	if trimSilence, ok := parseQueryParamToBool(w, r, "trimSilence", false); ok {
+		options.TrimSilence = *trimSilence
+	} else if r.URL.Query().Get("trimSilence") != "" {
+		return options, false
+	}
+
+	if enhancement := r.URL.Query().Get("enhancement"); enhancement != "" {
+		if !cnfgs.IsAudioEnhancement(enhancement) {
+			apiErr.WriteJSONError(w, http.StatusBadRequest, errs.ErrInvalidAudioEnhancement.Error(), "Unknown enhancement profile "+enhancement)
+			return options, false
+		}
+		options.Enhancement = cnfgs.AudioEnhancement(enhancement)
+		return options, true
+	}
+	enhancement, err := users.PreferredAudioEnhancement(userId)
+	if err != nil {
+		apiErr.WriteJSONError(w, http.StatusInternalServerError, "", "Failed to get the user preferences: "+err.Error())
+		return options, false
+	}
+	options.Enhancement = enhancement
+	return options, true
+}
+
+func writeAudioPreparationError(w http.ResponseWriter, err error, msg string) {
+	switch {
+	case errors.Is(err, errs.ErrUnsupportedAudioFile):
+		apiErr.WriteJSONError(w, http.StatusBadRequest, errs.ErrUnsupportedAudioFile.Error(), err.Error())
+	case errors.Is(err, errs.ErrRecordingSessionNotFinalized):
+		apiErr.WriteJSONError(w, http.StatusConflict, errs.ErrRecordingSessionNotFinalized.Error(), "The recording session must be finalized first")
+	case errors.Is(err, errs.ErrRecordingSessionClosed):
+		apiErr.WriteJSONError(w, http.StatusConflict, errs.ErrRecordingSessionClosed.Error(), "The recording session expired")
+	case errors.Is(err, errs.ErrInvalidManifest):
+		apiErr.WriteJSONError(w, http.StatusUnprocessableEntity, errs.ErrInvalidManifest.Error(), err.Error())
+	case errors.Is(err, errs.ErrMissingRecordingChunks):
+		apiErr.WriteJSONError(w, http.StatusUnprocessableEntity, errs.ErrMissingRecordingChunks.Error(), err.Error())
+	case errors.Is(err, errs.ErrCorruptRecordingChunk):
+		apiErr.WriteJSONError(w, http.StatusUnprocessableEntity, errs.ErrCorruptRecordingChunk.Error(), err.Error())
+	case errors.Is(err, errs.ErrTranscoderBusy):
+		w.Header().Set("Retry-After", strconv.Itoa(int(cnfgs.TranscoderRetryAfter.Seconds())))
+		apiErr.WriteJSONError(w, http.StatusServiceUnavailable, errs.ErrTranscoderBusy.Error(), "Too many audio files are being processed, retry later")
+	case errors.Is(err, errs.ErrRecordingTooLarge):
+		apiErr.WriteJSONError(w, http.StatusRequestEntityTooLarge, errs.ErrRecordingTooLarge.Error(), err.Error())
+	case errors.Is(err, errs.ErrUploadTooLarge):
+		apiErr.WriteJSONError(w, http.StatusRequestEntityTooLarge, errs.ErrUploadTooLarge.Error(), err.Error())
+	default:
+		apiErr.WriteJSONError(w, http.StatusInternalServerError, "", msg+err.Error())
+	}
+}
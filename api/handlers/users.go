@@ -6,13 +6,15 @@ import (
 	"eavesdropper/api/middlewares"
 	"eavesdropper/dtos/requests"
 	"eavesdropper/dtos/responses"
+	"eavesdropper/errs"
 	"eavesdropper/services/auth"
 	"eavesdropper/services/transcripts"
 	"eavesdropper/services/users"
 	"encoding/json"
-	"fmt"
+	"errors"
 	"log"
 	"net/http"
+	"strings"
 )
 
 func AddUser(w http.ResponseWriter, r *http.Request) {
@@ -78,6 +80,10 @@ func UpdateUser(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := users.UpdateUser(userId, &updateUserReq); err != nil {
+		if errors.Is(err, errs.ErrInvalidAudioEnhancement) {
+			apiErr.WriteJSONError(w, http.StatusBadRequest, errs.ErrInvalidAudioEnhancement.Error(), err.Error())
+			return
+		}
 		apiErr.WriteJSONError(w, http.StatusInternalServerError, "", "Failed to update user: "+err.Error())
 		return
 	}
@@ -127,14 +133,31 @@ func HandleAvailable(w http.ResponseWriter, r *http.Request) {
 	_ = json.NewEncoder(w).Encode(response)
 }
 
+// Lists the user transcripts, newest first.
+// Paginated with the 'cursor' (from the previous page nextCursor) and 'limit' query params.
+// See parseTranscriptListFilters for the filtering and sorting query params.
 func GetUserTranscripts(w http.ResponseWriter, r *http.Request) {
-	pageI, _ := parseQueryParamToInt(w, r, "pageI", false)
-	pageSize, _ := parseQueryParamToInt(w, r, "pageSize", false)
 	userId := r.PathValue("id")
 
-	fmt.Printf("\nOn get user transcipts for page %v with page size %v", pageI, pageSize)
+	cursor, limit, ok := parsePageQueryParams(w, r)
+	if !ok {
+		return
+	}
+
+	filters, ok := parseTranscriptListFilters(w, r)
+	if !ok {
+		return
+	}
 
-	transcripts, err := transcripts.GetUserTranscripts(r.Context(), userId, pageI, pageSize)
+	transcripts, nextCursor, err := transcripts.GetUserTranscripts(r.Context(), userId, filters, cursor, limit)
+	if errors.Is(err, errs.ErrInvalidCursor) {
+		apiErr.WriteJSONError(w, http.StatusBadRequest, err.Error(), "The cursor belongs to a listing with another sort")
+		return
+	}
+	if errors.Is(err, errs.ErrUnsupportedTranscriptFilters) {
+		apiErr.WriteJSONError(w, http.StatusBadRequest, errs.ErrUnsupportedTranscriptFilters.Error(), err.Error())
+		return
+	}
 	if err != nil {
 		apiErr.WriteJSONError(
 			w,
@@ -146,13 +169,16 @@ func GetUserTranscripts(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Convert resource models to response models
-	responseTranscripts := make([]responses.TranscriptionResponse, len(transcripts))
+	response := responses.TranscriptListResponse{
+		Transcripts: make([]responses.TranscriptionResponse, len(transcripts)),
+		NextCursor:  nextCursor.Encode(),
+	}
 	for i, transcript := range transcripts {
-		responseTranscripts[i] = transcriptToResponse(&transcript)
+		response.Transcripts[i] = transcriptToResponse(&transcript)
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(responseTranscripts)
+	_ = json.NewEncoder(w).Encode(response)
 }
 
 func GetUserTranscript(w http.ResponseWriter, r *http.Request) {
@@ -182,6 +208,7 @@ func GetUserTranscript(w http.ResponseWriter, r *http.Request) {
 	}
 
 	response := transcriptToResponse(transcript)
+	writeTranscriptETag(w, transcript)
 	w.Header().Set("Content-Type", "application/json")
 	_ = json.NewEncoder(w).Encode(response)
 }
@@ -209,3 +236,68 @@ func GetUserBillingCycleUsage(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	_ = json.NewEncoder(w).Encode(usage)
 }
+
+// Reads the listing query params:
+//   - folderId, tag, language (ISO 639-1 code), private (true/false), shared (true/false)
+//   - createdFrom, createdTo (RFC3339 or YYYY-MM-DD), minDuration, maxDuration (seconds)
+//   - titlePrefix (case sensitive)
+//   - sort (createdAt, duration or title) and order (asc or desc). Titles default to asc, the rest to desc.
+//
+// One of folderId, tag, private, shared and language at a time, or folderId with tag.
+// The date range needs sort=createdAt, the duration range sort=duration and titlePrefix sort=title.
+func parseTranscriptListFilters(w http.ResponseWriter, r *http.Request) (*requests.TranscriptListFilters, bool) {
+	query := r.URL.Query()
+	filters := &requests.TranscriptListFilters{
+		FolderID:    query.Get("folderId"),
+		Tag:         strings.ToLower(strings.TrimSpace(query.Get("tag"))),
+		Language:    strings.ToLower(query.Get("language")),
+		TitlePrefix: query.Get("titlePrefix"),
+		SortBy:      requests.SortByCreatedAt,
+	}
+
+	var ok bool
+	if filters.IsPrivate, ok = parseQueryParamToBool(w, r, "private", false); !ok && query.Get("private") != "" {
+		return nil, false
+	}
+	if filters.Shared, ok = parseQueryParamToBool(w, r, "shared", false); !ok && query.Get("shared") != "" {
+		return nil, false
+	}
+	if filters.CreatedFrom, ok = parseQueryParamToTime(w, r, "createdFrom", false); !ok && query.Get("createdFrom") != "" {
+		return nil, false
+	}
+	if filters.CreatedTo, ok = parseQueryParamToTime(w, r, "createdTo", false); !ok && query.Get("createdTo") != "" {
+		return nil, false
+	}
+	if seconds, ok := parseQueryParamToInt(w, r, "minDuration", false); ok {
+		filters.MinDuration = &seconds
+	} else if query.Get("minDuration") != "" {
+		return nil, false
+	}
+	if seconds, ok := parseQueryParamToInt(w, r, "maxDuration", false); ok {
+		filters.MaxDuration = &seconds
+	} else if query.Get("maxDuration") != "" {
+		return nil, false
+	}
+
+	if sortBy := query.Get("sort"); sortBy != "" {
+		if !requests.IsValidTranscriptSortField(sortBy) {
+			apiErr.WriteJSONError(w, http.StatusBadRequest, "INVALID_QUERY_PARAM", "The 'sort' query param must be createdAt, duration or title")
+			return nil, false
+		}
+		filters.SortBy = requests.TranscriptSortField(sortBy)
+	}
+
+	switch query.Get("order") {
+	case "":
+		filters.Descending = filters.SortBy != requests.SortByTitle
+	case "asc":
+		filters.Descending = false
+	case "desc":
+		filters.Descending = true
+	default:
+		apiErr.WriteJSONError(w, http.StatusBadRequest, "INVALID_QUERY_PARAM", "The 'order' query param must be asc or desc")
+		return nil, false
+	}
+
+	return filters, true
+}
@@ -4,8 +4,12 @@ import (
 	apiErr "eavesdropper/api/error"
 	"eavesdropper/dtos/resources"
 	"eavesdropper/dtos/responses"
+	"eavesdropper/errs"
+	"eavesdropper/services/pagination"
+	"eavesdropper/services/transcripts"
 	"net/http"
 	"strconv"
+	"time"
 )
 
 func parsePathValueToInt(w http.ResponseWriter, r *http.Request, key string, mandatory bool) (int, bool) {
@@ -44,7 +48,64 @@ func parseQueryParamToInt(w http.ResponseWriter, r *http.Request, key string, ma
 	return intValue, true
 }
 
-// Helper function to convert Transcript resource to TranscriptionResponse
+func parseQueryParamToBool(w http.ResponseWriter, r *http.Request, key string, mandatory bool) (*bool, bool) {
+	value := r.URL.Query().Get(key)
+	if value == "" {
+		if mandatory {
+			apiErr.WriteJSONError(w, http.StatusBadRequest, "MISSING_QUERY_PARAM", "Missing required query parameter: "+key)
+		}
+		return nil, false
+	}
+
+	boolValue, err := strconv.ParseBool(value)
+	if err != nil {
+		apiErr.WriteJSONError(w, http.StatusBadRequest, "INVALID_QUERY_PARAM", "Invalid boolean value for query parameter: "+key)
+		return nil, false
+	}
+
+	return &boolValue, true
+}
+
+// Accepts RFC3339 timestamps or plain dates (YYYY-MM-DD, taken as UTC midnight)
+func parseQueryParamToTime(w http.ResponseWriter, r *http.Request, key string, mandatory bool) (*time.Time, bool) {
+	value := r.URL.Query().Get(key)
+	if value == "" {
+		if mandatory {
+			apiErr.WriteJSONError(w, http.StatusBadRequest, "MISSING_QUERY_PARAM", "Missing required query parameter: "+key)
+		}
+		return nil, false
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		t, err = time.Parse(time.DateOnly, value)
+	}
+	if err != nil {
+		apiErr.WriteJSONError(w, http.StatusBadRequest, "INVALID_QUERY_PARAM", "Invalid date value for query parameter: "+key)
+		return nil, false
+	}
+
+	return &t, true
+}
+
+// Reads the 'cursor' and 'limit' query params used by the paginated listings.
+// An absent cursor means the first page. The limit bounds are applied by the services.
+func parsePageQueryParams(w http.ResponseWriter, r *http.Request) (*pagination.Cursor, int, bool) {
+	cursor, err := pagination.Decode(r.URL.Query().Get("cursor"))
+	if err != nil {
+		apiErr.WriteJSONError(w, http.StatusBadRequest, errs.ErrInvalidCursor.Error(), "Invalid value for query parameter: cursor")
+		return nil, 0, false
+	}
+
+	limit, ok := parseQueryParamToInt(w, r, "limit", false)
+	if !ok && r.URL.Query().Get("limit") != "" {
+		return nil, 0, false
+	}
+
+	return cursor, limit, true
+}
+
+// Helper function to convert Transcript resource to TranscriptionResponse. Only for the owner routes, see publicTranscriptToResponse.
 func transcriptToResponse(transcript *resources.Transcript) responses.TranscriptionResponse {
 	return responses.TranscriptionResponse{
 		ID:                        transcript.ID,
@@ -54,5 +115,79 @@ func transcriptToResponse(transcript *resources.Transcript) responses.Transcript
 		ConsumedInputAudioSeconds: transcript.ConsumedInputAudioSeconds,
 		ConsumedFreeAudioSeconds:  transcript.ConsumedFreeAudioSeconds,
 		CreatedAt:                 transcript.CreatedAt,
+		UpdatedAt:                 transcript.UpdatedAt,
+		Version:                   transcripts.Version(transcript),
+		Language:                  transcript.Language,
+		FolderID:                  transcript.FolderID,
+		Tags:                      transcript.Tags,
+		RetranscribedFromID:       transcript.RetranscribedFromID,
+		Complimentary:             transcript.Complimentary,
+		TrashedAt:                 transcript.TrashedAt,
+		PurgeAt:                   transcripts.PurgeTime(transcript),
+		MissingAudio:              missingAudioToResponse(transcript.MissingAudio),
+		AudioDurationSeconds:      transcript.AudioDurationSeconds,
+		SpeechSeconds:             transcript.SpeechSeconds,
+		NonSpeechSpans:            audioSpansToResponse(transcript.NonSpeechSpans),
+		SilenceTrimmed:            transcript.SilenceTrimmed,
+		AudioEnhancement:          transcript.AudioEnhancement,
+		AudioQuality:              audioQualityToResponse(transcript.AudioQuality),
+	}
+}
+
+// The fields of a transcript the viewers of GET /transcripts/{id} see: the content and what its player needs
+func publicTranscriptToResponse(transcript *resources.Transcript) responses.PublicTranscriptResponse {
+	return responses.PublicTranscriptResponse{
+		ID:                        transcript.ID,
+		RecordingSessionID:        transcript.RecordingSessionID,
+		Tittle:                    transcript.Tittle,
+		Content:                   transcript.Content,
+		ConsumedInputAudioSeconds: transcript.ConsumedInputAudioSeconds,
+		ConsumedFreeAudioSeconds:  transcript.ConsumedFreeAudioSeconds,
+		CreatedAt:                 transcript.CreatedAt,
+		MissingAudio:              missingAudioToResponse(transcript.MissingAudio),
+		AudioDurationSeconds:      transcript.AudioDurationSeconds,
+		SpeechSeconds:             transcript.SpeechSeconds,
+		NonSpeechSpans:            audioSpansToResponse(transcript.NonSpeechSpans),
+	}
+}
+
+func audioQualityToResponse(quality *resources.AudioQuality) *responses.AudioQuality {
+	if quality == nil {
+		return nil
+	}
+	return &responses.AudioQuality{
+		LoudnessLUFS:    quality.LoudnessLUFS,
+		PeakDBFS:        quality.PeakDBFS,
+		ClippingPercent: quality.ClippingPercent,
+		SNRDB:           quality.SNRDB,
+		SilencePercent:  quality.SilencePercent,
+		Warnings:        quality.Warnings,
+	}
+}
+
+func audioSpansToResponse(spans []resources.AudioSpan) []responses.AudioSpan {
+	if len(spans) == 0 {
+		return nil
+	}
+	response := make([]responses.AudioSpan, len(spans))
+	for i, span := range spans {
+		response[i] = responses.AudioSpan{StartSeconds: span.StartSeconds, EndSeconds: span.EndSeconds}
+	}
+	return response
+}
+
+func missingAudioToResponse(ranges []resources.MissingAudioRange) []responses.MissingAudioRange {
+	if len(ranges) == 0 {
+		return nil
+	}
+	response := make([]responses.MissingAudioRange, len(ranges))
+	for i, missing := range ranges {
+		response[i] = responses.MissingAudioRange{
+			FromChunk:    missing.FromChunk,
+			ToChunk:      missing.ToChunk,
+			StartSeconds: missing.StartSeconds,
+			EndSeconds:   missing.EndSeconds,
+		}
 	}
+	return response
 }
@@ -94,7 +94,12 @@ func GetTranscriptWhitelist(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	whitelistResponse, err := whitelist.GetTranscriptWhitelist(ctx, userID, transcriptID)
+	cursor, limit, ok := parsePageQueryParams(w, r)
+	if !ok {
+		return
+	}
+
+	whitelistResponse, err := whitelist.GetTranscriptWhitelist(ctx, userID, transcriptID, cursor, limit)
 	if err != nil {
 		apiErr.WriteJSONError(w, http.StatusInternalServerError, "", "Failed to get transcript whitelist: "+err.Error())
 		return
@@ -141,6 +141,8 @@ func CorsMiddleware(next http.Handler) http.Handler {
 			w.Header().Set("Access-Control-Allow-Headers", reqHeaders)
 			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
 			w.Header().Set("Access-Control-Max-Age", "86400") // 24h
+			// The ETag is read by the client for transcript edits, Idempotent-Replayed on repeated transcriptions
+			w.Header().Set("Access-Control-Expose-Headers", "ETag, Idempotent-Replayed")
 		}
 
 		// Preflight: answer and stop here
@@ -24,10 +24,41 @@ func (r *Router) Start(address string) {
 	r.mux.HandleFunc("GET /users/handle/availability", m.ValidateToken(handlers.HandleAvailable))
 
 	r.mux.HandleFunc("GET /users/{id}/transcripts", m.ValidateOwnership(handlers.GetUserTranscripts))
+	r.mux.HandleFunc("GET /users/{id}/transcripts/search", m.ValidateOwnership(handlers.SearchUserTranscripts))
+	r.mux.HandleFunc("POST /users/{id}/transcripts/search/reindex", m.ValidateOwnership(handlers.ReindexUserTranscripts))
+	r.mux.HandleFunc("GET /users/{id}/transcripts/semantic-search", m.ValidateOwnership(handlers.SemanticSearchTranscripts))
+	r.mux.HandleFunc("POST /users/{id}/transcripts/semantic-search/reindex", m.ValidateOwnership(handlers.ReindexUserTranscriptEmbeddings))
+	r.mux.HandleFunc("POST /users/{id}/transcripts/upload", m.ValidateOwnership(handlers.UploadAndTranscribe))
+	r.mux.HandleFunc("POST /users/{id}/transcripts/bulk/move", m.ValidateOwnership(handlers.MoveTranscripts))
+	r.mux.HandleFunc("POST /users/{id}/transcripts/bulk/tags", m.ValidateOwnership(handlers.TagTranscripts))
 	r.mux.HandleFunc("GET /users/{id}/transcripts/{tId}", m.ValidateOwnership(handlers.GetUserTranscript))
+	r.mux.HandleFunc("DELETE /users/{id}/transcripts/{tId}", m.ValidateOwnership(handlers.DeleteTranscript))
+	r.mux.HandleFunc("POST /users/{id}/transcripts/{tId}/restore", m.ValidateOwnership(handlers.RestoreTrashedTranscript))
+	r.mux.HandleFunc("POST /users/{id}/folders", m.ValidateOwnership(handlers.CreateFolder))
+	r.mux.HandleFunc("GET /users/{id}/folders", m.ValidateOwnership(handlers.GetFolders))
+	r.mux.HandleFunc("PUT /users/{id}/folders/{fId}", m.ValidateOwnership(handlers.UpdateFolder))
+	r.mux.HandleFunc("DELETE /users/{id}/folders/{fId}", m.ValidateOwnership(handlers.DeleteFolder))
+	r.mux.HandleFunc("POST /users/{id}/recording-sessions", m.ValidateOwnership(handlers.StartRecordingSession))
+	r.mux.HandleFunc("GET /users/{id}/recording-sessions/{sId}", m.ValidateOwnership(handlers.GetRecordingSession))
+	r.mux.HandleFunc("POST /users/{id}/recording-sessions/{sId}/upload-urls", m.ValidateOwnership(handlers.GetChunkUploadURLs))
+	r.mux.HandleFunc("POST /users/{id}/recording-sessions/{sId}/chunks", m.ValidateOwnership(handlers.RegisterRecordingChunk))
+	r.mux.HandleFunc("POST /users/{id}/recording-sessions/{sId}/finalize", m.ValidateOwnership(handlers.FinalizeRecordingSession))
+	r.mux.HandleFunc("GET /users/{id}/transcription-jobs/{sessionId}", m.ValidateOwnership(handlers.GetTranscriptionJob))
+	r.mux.HandleFunc("GET /users/{id}/trash", m.ValidateOwnership(handlers.GetTrashedUserTranscripts))
+	r.mux.HandleFunc("GET /users/{id}/transcripts/{tId}/export", m.ValidateOwnership(handlers.ExportTranscript))
 	r.mux.HandleFunc("POST /users/{id}/transcript/{tId}/whitelist", m.ValidateOwnership(handlers.AddUsersToTranscriptWhitelist))
 	r.mux.HandleFunc("PUT /users/{id}/transcripts/{tId}/visibility", m.ValidateOwnership(handlers.UpdateTranscriptVisibility))
 	r.mux.HandleFunc("PUT /users/{id}/transcripts/{tId}/tittle", m.ValidateOwnership(handlers.UpdateTranscriptTitle))
+	r.mux.HandleFunc("PUT /users/{id}/transcripts/{tId}/tags", m.ValidateOwnership(handlers.UpdateTranscriptTags))
+	r.mux.HandleFunc("PUT /users/{id}/transcripts/{tId}/content", m.ValidateOwnership(handlers.UpdateTranscriptContent))
+	r.mux.HandleFunc("POST /users/{id}/transcripts/{tId}/retranscribe", m.ValidateOwnership(handlers.RetranscribeTranscript))
+	r.mux.HandleFunc("POST /users/{id}/transcripts/{tId}/clips", m.ValidateOwnership(handlers.CreateAudioClip))
+	r.mux.HandleFunc("GET /users/{id}/transcripts/{tId}/clips", m.ValidateOwnership(handlers.GetTranscriptAudioClips))
+	r.mux.HandleFunc("DELETE /users/{id}/clips/{cId}", m.ValidateOwnership(handlers.DeleteAudioClip))
+	r.mux.HandleFunc("GET /users/{id}/transcripts/{tId}/revisions", m.ValidateOwnership(handlers.GetTranscriptRevisions))
+	r.mux.HandleFunc("GET /users/{id}/transcripts/{tId}/revisions/diff", m.ValidateOwnership(handlers.DiffTranscriptRevisions))
+	r.mux.HandleFunc("GET /users/{id}/transcripts/{tId}/revisions/{version}", m.ValidateOwnership(handlers.GetTranscriptRevision))
+	r.mux.HandleFunc("POST /users/{id}/transcripts/{tId}/revisions/{version}/restore", m.ValidateOwnership(handlers.RestoreTranscriptRevision))
 	r.mux.HandleFunc("DELETE /users/{id}/transcript/{tId}/whitelist", m.ValidateOwnership(handlers.RemoveUsersFromTranscriptWhitelist))
 	r.mux.HandleFunc("GET /users/{id}/transcript/{tId}/whitelist", m.ValidateOwnership(handlers.GetTranscriptWhitelist))
 
@@ -40,6 +71,10 @@ func (r *Router) Start(address string) {
 
 	r.mux.HandleFunc("POST /transcripts", m.ValidateToken(handlers.Transcribe))
 	r.mux.HandleFunc("GET /transcripts/{id}", handlers.GetTranscript)
+	r.mux.HandleFunc("GET /transcripts/{id}/peaks", handlers.GetTranscriptPeaks)
+	r.mux.HandleFunc("GET /transcripts/{id}/audio", handlers.GetTranscriptAudio)
+	r.mux.HandleFunc("GET /clips/{id}", handlers.GetAudioClip)
+	r.mux.HandleFunc("GET /clips/{id}/audio", handlers.GetAudioClipAudio)
 
 	// Sets the global middlewares
 	handler := m.CorsMiddleware(m.LoggingMiddleware(r.mux))
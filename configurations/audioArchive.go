@@ -0,0 +1,28 @@
+package configurations
+
+import (
+	"os"
+	"time"
+)
+
+// Codec the final audio of the sessions is archived with, for playback, clips and re-transcriptions.
+// Overridden with the AUDIO_ARCHIVE_CODEC environment variable: opus, far smaller, or flac, lossless.
+var ArchiveCodec = getArchiveCodec()
+
+func getArchiveCodec() AudioCodec {
+	switch codec := AudioCodec(os.Getenv("AUDIO_ARCHIVE_CODEC")); codec {
+	case CodecOpus, CodecFLAC:
+		return codec
+	}
+	return CodecOpus
+}
+
+// How often the final audio archived as wav, before the archives were compressed, is migrated to ArchiveCodec
+const ArchiveMigrationInterval = 15 * time.Minute
+
+// Archives migrated per run, one at a time, the rest wait for the next run
+const ArchiveMigrationBatchSize = 20
+
+// A wav archive is migrated by the instance holding its lease. One older than this was left by an instance
+// that died while encoding, and is taken over.
+const ArchiveMigrationLeaseTTL = 2 * TranscodeTimeout
@@ -0,0 +1,14 @@
+package configurations
+
+// Thresholds of the audio quality warnings returned with the transcripts
+const (
+	// Share of the audio quieter than SpeechThresholdDBFS
+	MostlySilentPercent = 80.0
+	// Share of the samples at full scale
+	HeavyClippingPercent = 1.0
+	SomeClippingPercent  = 0.1
+	// Integrated loudness, speech is usually mastered around -16 LUFS
+	QuietLoudnessLUFS = -35.0
+	// Estimated signal to noise ratio
+	NoisySNRDB = 10.0
+)
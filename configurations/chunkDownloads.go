@@ -0,0 +1,30 @@
+package configurations
+
+import (
+	"os"
+	"strconv"
+	"time"
+)
+
+const defaultChunkDownloadWorkers = 8
+
+// Chunks of a recording downloaded at the same time.
+// Overridden with the CHUNK_DOWNLOAD_WORKERS environment variable.
+var ChunkDownloadWorkers = getChunkDownloadWorkers()
+
+// Tries of a chunk download before the transcription fails
+const ChunkDownloadAttempts = 4
+
+// Wait before the first retry of a chunk download, doubled on every retry
+const ChunkDownloadBackoff = 500 * time.Millisecond
+
+// Most bytes downloaded for the chunks of one recording
+const MaxRecordingBytes = 2 << 30 // 2 GiB
+
+func getChunkDownloadWorkers() int {
+	workers, err := strconv.Atoi(os.Getenv("CHUNK_DOWNLOAD_WORKERS"))
+	if err != nil || workers <= 0 {
+		workers = defaultChunkDownloadWorkers
+	}
+	return workers
+}
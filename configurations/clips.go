@@ -0,0 +1,32 @@
+package configurations
+
+import "time"
+
+// Compressed formats the transcoder encodes audio into
+type AudioCodec string
+
+const (
+	CodecOpus AudioCodec = "opus" // in an ogg container, made for speech at low bitrates
+	CodecFLAC AudioCodec = "flac" // lossless, around half the size of the PCM
+)
+
+// Format the audio clips are encoded in
+const ClipCodec = CodecOpus
+
+// Shortest and longest audio clips
+const (
+	MinClipDuration = time.Second
+	MaxClipDuration = 5 * time.Minute
+)
+
+// Audio kept on each side of a clip cut from a segment, whose times are estimates
+const SegmentClipPadding = time.Second
+
+// How long the audio clips are shared when their creator does not say, and at most
+const (
+	DefaultClipLifetime = 7 * 24 * time.Hour
+	MaxClipLifetime     = 90 * 24 * time.Hour
+)
+
+// How often the expired audio clips are deleted
+const ClipExpiryInterval = time.Hour
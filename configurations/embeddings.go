@@ -0,0 +1,17 @@
+package configurations
+
+// Provider used to embed transcript passages for the semantic search.
+// Vectors of different providers are not comparable, every stored vector records the provider that produced it.
+type EmbeddingProvider string
+
+const (
+	GeminiEmbeddings EmbeddingProvider = "gemini"
+	LocalEmbeddings  EmbeddingProvider = "local" // deterministic, no external calls. Meant for tests and local development.
+)
+
+var SelectedEmbeddingProvider = GeminiEmbeddings
+
+const GeminiEmbeddingModel = "gemini-embedding-001"
+
+// Every provider outputs vectors of this size. Must match the vector index in firestore.indexes.json.
+const EmbeddingDimensions = 768
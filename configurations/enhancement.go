@@ -0,0 +1,25 @@
+package configurations
+
+// Processing applied to the audio while it is transcoded, before it is transcribed. The archived audio is not processed.
+type AudioEnhancement string
+
+const (
+	EnhancementNone AudioEnhancement = "none"
+	// High-pass filtering and loudness normalization, for close microphones
+	EnhancementLight AudioEnhancement = "light"
+	// Adds noise reduction and dynamic range compression, for quiet and noisy conference rooms
+	EnhancementConference AudioEnhancement = "conference"
+	// Stronger noise reduction and compression, for street and crowd recordings
+	EnhancementNoisy AudioEnhancement = "noisy"
+)
+
+// Used when neither the request nor the user preference picks a profile
+const DefaultAudioEnhancement = EnhancementNone
+
+func IsAudioEnhancement(profile string) bool {
+	switch AudioEnhancement(profile) {
+	case EnhancementNone, EnhancementLight, EnhancementConference, EnhancementNoisy:
+		return true
+	}
+	return false
+}
@@ -6,3 +6,9 @@ var GenAIApiKey = os.Getenv("GENAI_API_KEY")
 
 var GeminiAudioInputSecondsToTokenRate = 32 // one second costs 32 tokens
 var GeminiAudioInputMaxSeconds = 9.5 * 60 * 60
+
+// Gemini model used for transcriptions
+const DefaultTranscriptionModel = "gemini-2.5-flash"
+
+// Models a re-transcription can be requested with
+var TranscriptionModels = []string{"gemini-2.5-flash", "gemini-2.5-pro"}
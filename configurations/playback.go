@@ -0,0 +1,6 @@
+package configurations
+
+import "time"
+
+// Validity of the signed URLs the transcript audio is played from, when the client asks for a redirect
+const AudioPlaybackURLExpiry = 10 * time.Minute
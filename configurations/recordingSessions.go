@@ -0,0 +1,22 @@
+package configurations
+
+import "time"
+
+// A recording session without uploads for this long is abandoned, its chunks are deleted
+const RecordingSessionIdleTimeout = 6 * time.Hour
+
+// How often abandoned recording sessions are expired
+const RecordingSessionExpiryInterval = 30 * time.Minute
+
+// Validity of the signed URLs the client uploads the chunks with
+const ChunkUploadURLExpiry = 15 * time.Minute
+
+// Largest chunk accepted when registered
+const MaxChunkBytes = 50 << 20 // 50 MiB
+
+// Recording formats accepted for sessions, with the extension of their chunks.
+// Only formats whose chunks can be joined by appending their bytes.
+var RecordingMIMETypes = map[string]string{
+	"audio/webm": ".webm",
+	"audio/ogg":  ".ogg",
+}
@@ -21,6 +21,7 @@ type PlanConfig struct {
 	PriceIDTest         string
 	PriceIDProd         string
 	MonthlyAudioSeconds int
+	BillSpeechOnly      bool // bills the speech seconds of a recording instead of its total seconds, off on every plan by default
 }
 
 // planConfigs is the single source of truth for all plan configurations
@@ -89,7 +90,7 @@ func GetPriceID(tier SubscriptionTier) (string, error) {
 	case Production:
 		return config.PriceIDProd, nil
 	default:
-		return "", fmt.Errorf("unknown environment: %d", SelectedBackendMode)
+		return "", fmt.Errorf("unknown environment: %s", SelectedBackendMode)
 	}
 }
 
@@ -103,7 +104,7 @@ func GetSubscriptionTier(priceID string) (SubscriptionTier, error) {
 	case Production:
 		lookupMap = priceToTierProd
 	default:
-		return FreeTrial, fmt.Errorf("unknown environment: %d", SelectedBackendMode)
+		return FreeTrial, fmt.Errorf("unknown environment: %s", SelectedBackendMode)
 	}
 
 	tier, exists := lookupMap[priceID]
@@ -141,6 +142,15 @@ func GetMonthlyAudioSeconds(tier SubscriptionTier) (int, error) {
 	return config.MonthlyAudioSeconds, nil
 }
 
+// BillsSpeechOnly returns if the tier is billed on the speech seconds of recordings, silences excluded
+func BillsSpeechOnly(tier SubscriptionTier) (bool, error) {
+	config, exists := planConfigs[tier]
+	if !exists {
+		return false, fmt.Errorf("unknown subscription tier: %d", tier)
+	}
+	return config.BillSpeechOnly, nil
+}
+
 // GetMonthlyAudioSecondsByPriceID returns the monthly limit for a price ID
 func GetMonthlyAudioSecondsByPriceID(priceID string) (int, error) {
 	tier, err := GetSubscriptionTier(priceID)
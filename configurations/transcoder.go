@@ -0,0 +1,33 @@
+package configurations
+
+import (
+	"os"
+	"strconv"
+	"time"
+)
+
+const defaultMaxTranscoderProcesses = 4
+
+// ffmpeg and ffprobe processes running at the same time, across every request.
+// Overridden with the FFMPEG_MAX_PROCESSES environment variable.
+var MaxTranscoderProcesses = getMaxTranscoderProcesses()
+
+// How long a request waits for a free process before it is turned away with 503
+const TranscoderQueueWait = 5 * time.Second
+
+// Retry-After of the 503 responses sent while every process is busy
+const TranscoderRetryAfter = 30 * time.Second
+
+// Longest an ffmpeg conversion may run before it is killed, above the longest audio gemini accepts
+const TranscodeTimeout = 30 * time.Minute
+
+// Longest an ffprobe run may take before it is killed
+const ProbeTimeout = 30 * time.Second
+
+func getMaxTranscoderProcesses() int {
+	processes, err := strconv.Atoi(os.Getenv("FFMPEG_MAX_PROCESSES"))
+	if err != nil || processes <= 0 {
+		processes = defaultMaxTranscoderProcesses
+	}
+	return processes
+}
@@ -0,0 +1,49 @@
+package configurations
+
+import (
+	"os"
+	"time"
+)
+
+// A transcription job still processing after this long is considered abandoned (the instance died)
+// and a new request for the same session takes it over
+const TranscriptionJobStaleAfter = 15 * time.Minute
+
+// How often a running transcription refreshes its job, well under TranscriptionJobStaleAfter
+// so a long transcription is never taken over while its instance is alive
+const TranscriptionJobHeartbeatInterval = time.Minute
+
+// Re-transcriptions covered as quality complaints allowed per recorded audio, the next ones have to be billed
+const MaxComplaintRetranscriptions = 2
+
+// How the exact audio duration is rounded into the billed seconds
+type AudioBillingRounding string
+
+const (
+	RoundUp      AudioBillingRounding = "up" // any started second is billed
+	RoundNearest AudioBillingRounding = "nearest"
+	RoundDown    AudioBillingRounding = "down"
+)
+
+// Overridden with the AUDIO_BILLING_ROUNDING environment variable. Non empty audio is always billed at least a second.
+var AudioBillingRoundingPolicy = getAudioBillingRounding()
+
+func getAudioBillingRounding() AudioBillingRounding {
+	switch rounding := AudioBillingRounding(os.Getenv("AUDIO_BILLING_ROUNDING")); rounding {
+	case RoundUp, RoundNearest, RoundDown:
+		return rounding
+	}
+	return RoundUp
+}
+
+// Frames of audio louder than this are speech
+const SpeechThresholdDBFS = -45.0
+
+// Quieter spans shorter than this are pauses of the speech, longer ones are non-speech
+const MinNonSpeech = 1500 * time.Millisecond
+
+// Audio kept on each side of a trimmed non-speech span, so words are not cut. Under half of MinNonSpeech.
+const NonSpeechPadding = 300 * time.Millisecond
+
+// Non-speech spans recorded on a transcript, the longest recordings have more
+const MaxRecordedNonSpeechSpans = 500
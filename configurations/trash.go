@@ -0,0 +1,24 @@
+package configurations
+
+import (
+	"os"
+	"strconv"
+	"time"
+)
+
+const defaultTrashRetentionDays = 30
+
+// How long a transcript stays in the trash before it is permanently deleted.
+// Overridden with the TRASH_RETENTION_DAYS environment variable.
+var TrashRetention = getTrashRetention()
+
+// How often the trash purge runs
+const TrashPurgeInterval = time.Hour
+
+func getTrashRetention() time.Duration {
+	days, err := strconv.Atoi(os.Getenv("TRASH_RETENTION_DAYS"))
+	if err != nil || days <= 0 {
+		days = defaultTrashRetentionDays
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
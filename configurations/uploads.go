@@ -0,0 +1,4 @@
+package configurations
+
+// Largest audio or video file accepted by the upload endpoint
+const MaxUploadBytes = 2 << 30 // 2 GiB
@@ -0,0 +1,5 @@
+package configurations
+
+// Zoom levels of the waveform peaks, in audio samples per peak. Each one is a multiple of the previous one.
+// At 16kHz the finest has 62.5 peaks per second and the coarsest about 4.
+var WaveformZooms = []int{256, 1024, 4096}
@@ -0,0 +1,10 @@
+package requests
+
+// Either the offsets or the segment index of the clip
+type CreateAudioClip struct {
+	StartSeconds   *float64 `json:"startSeconds"`
+	EndSeconds     *float64 `json:"endSeconds"`
+	SegmentIndex   *int     `json:"segmentIndex"`   // speaker turn of the transcript content, from 0
+	IsPrivate      *bool    `json:"isPrivate"`      // true when missing, cannot be false on a private transcript
+	ExpiresInHours *int     `json:"expiresInHours"` // a week when missing
+}
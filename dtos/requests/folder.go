@@ -0,0 +1,26 @@
+package requests
+
+type CreateFolder struct {
+	Name     string `json:"name"`
+	ParentID string `json:"parentId,omitempty"` // empty for a top level folder
+}
+
+type UpdateFolder struct {
+	Name     *string `json:"name,omitempty"`
+	ParentID *string `json:"parentId,omitempty"` // empty string moves it to the top level
+}
+
+type MoveTranscripts struct {
+	TranscriptIDs []string `json:"transcriptIds"`
+	FolderID      string   `json:"folderId"` // empty moves them out of any folder
+}
+
+type TagTranscripts struct {
+	TranscriptIDs []string `json:"transcriptIds"`
+	Add           []string `json:"add,omitempty"`
+	Remove        []string `json:"remove,omitempty"`
+}
+
+type UpdateTranscriptTags struct {
+	Tags []string `json:"tags"`
+}
@@ -0,0 +1,19 @@
+package requests
+
+type StartRecordingSession struct {
+	MIME         string  `json:"mime"`         // audio/webm or audio/ogg, codecs parameters are accepted
+	ChunkSeconds float64 `json:"chunkSeconds"` // optional recording time of each chunk (the MediaRecorder timeslice)
+}
+
+type ChunkUploadURLs struct {
+	Indexes []int `json:"indexes"`
+}
+
+type RegisterRecordingChunk struct {
+	Index  *int   `json:"index"`
+	SHA256 string `json:"sha256"` // hex encoded SHA-256 of the uploaded chunk
+}
+
+type FinalizeRecordingSession struct {
+	Count *int `json:"count"` // optional number of chunks recorded, detects chunks never registered
+}
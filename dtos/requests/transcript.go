@@ -0,0 +1,26 @@
+package requests
+
+type UpdateTranscriptContent struct {
+	Content string `json:"content"`
+	Version *int   `json:"version,omitempty"` // version the edit is based on. Alternative to the If-Match header.
+}
+
+type RestoreTranscriptRevision struct {
+	Version *int `json:"version,omitempty"` // current version of the transcript. Alternative to the If-Match header.
+}
+
+const (
+	RetranscribeAsRevision = "revision" // replaces the content, the previous one stays in the revisions
+	RetranscribeAsSibling  = "sibling"  // saves a new transcript next to the original
+
+	RetranscriptionBilled    = "billed"
+	RetranscriptionComplaint = "complaint" // not billed, the original transcription had quality issues
+)
+
+type RetranscribeTranscript struct {
+	Model          string `json:"model,omitempty"`          // defaults to the model of new transcriptions
+	PromptTemplate string `json:"promptTemplate,omitempty"` // default, verbatim or clean
+	Language       string `json:"language,omitempty"`       // spoken language hint, detected by the model when empty
+	SaveAs         string `json:"saveAs,omitempty"`         // revision (default) or sibling
+	Billing        string `json:"billing"`                  // billed or complaint
+}
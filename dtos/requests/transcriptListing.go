@@ -0,0 +1,41 @@
+package requests
+
+import "time"
+
+type TranscriptSortField string
+
+const (
+	SortByCreatedAt TranscriptSortField = "createdAt"
+	SortByDuration  TranscriptSortField = "duration"
+	SortByTitle     TranscriptSortField = "title"
+)
+
+func IsValidTranscriptSortField(field string) bool {
+	switch TranscriptSortField(field) {
+	case SortByCreatedAt, SortByDuration, SortByTitle:
+		return true
+	}
+	return false
+}
+
+// Filters and sort of the transcript listing, from its query params. Empty values do not filter.
+type TranscriptListFilters struct {
+	FolderID    string
+	Tag         string
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+	MinDuration *int // in seconds of input audio
+	MaxDuration *int
+	IsPrivate   *bool
+	TitlePrefix string // case sensitive
+	Shared      *bool  // shared with at least one whitelisted user
+	Language    string // ISO 639-1 code
+
+	SortBy     TranscriptSortField // defaults to createdAt
+	Descending bool
+}
+
+// Newest first, the order of the listing when no sort is given
+func DefaultTranscriptListFilters() *TranscriptListFilters {
+	return &TranscriptListFilters{SortBy: SortByCreatedAt, Descending: true}
+}
@@ -6,8 +6,9 @@ type NewUser struct {
 }
 
 type UpdateUser struct {
-	Handle          *string `json:"handle,omitempty"`
-	FirstName       *string `json:"firstName,omitempty"`
-	LastName        *string `json:"lastName,omitempty"`
-	PrefersDarkMode *bool   `json:"prefersDarkMode,omitempty"`
+	Handle           *string `json:"handle,omitempty"`
+	FirstName        *string `json:"firstName,omitempty"`
+	LastName         *string `json:"lastName,omitempty"`
+	PrefersDarkMode  *bool   `json:"prefersDarkMode,omitempty"`
+	AudioEnhancement *string `json:"audioEnhancement,omitempty"` // a profile name, or "none"
 }
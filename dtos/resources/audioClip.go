@@ -0,0 +1,23 @@
+package resources
+
+import "time"
+
+// Piece of the audio of a transcript, encoded on its own to be shared.
+// Public clips can be played by anyone until they expire, private ones by whoever can view the transcript.
+// Clips of a private transcript are played as private ones, whatever their visibility.
+// Stored with the clip ID as the document ID.
+type AudioClip struct {
+	ID                 string
+	TranscriptID       string
+	RecordingSessionID string // of the transcript, its final audio is the source of the clip
+	StartSeconds       float64
+	EndSeconds         float64
+	SegmentIndex       *int // set when the clip was cut from a segment of the transcript
+	Estimated          bool // the times of the segment were estimated from its share of the content
+	StoragePath        string
+	ContentType        string
+	Size               int64
+	IsPrivate          bool
+	ExpiresAt          time.Time
+	CreatedAt          time.Time
+}
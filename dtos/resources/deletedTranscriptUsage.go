@@ -0,0 +1,18 @@
+package resources
+
+import "time"
+
+// Usage of a deleted transcript. Billing usage is computed from the transcripts,
+// this record keeps the billed seconds and tokens counted once the transcript is gone.
+// Stored with the transcript ID as the document ID.
+type DeletedTranscriptUsage struct {
+	TranscriptID              string
+	RecordingSessionID        string // of the audio the transcript used, which is deleted with its last transcript
+	Complimentary             bool   // re-transcribed as a quality complaint, the usage is empty
+	ConsumedInputAudioSeconds int
+	ConsumedFreeAudioSeconds  int
+	ConsumedInputTokens       int
+	ConsumedOutputTokens      int
+	CreatedAt                 time.Time // of the transcript, places the usage in its billing cycle
+	DeletedAt                 time.Time
+}
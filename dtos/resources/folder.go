@@ -0,0 +1,12 @@
+package resources
+
+import "time"
+
+// User defined folder to organize transcripts. Folders nest through ParentID, empty for top level folders.
+type Folder struct {
+	ID        string
+	Name      string
+	ParentID  string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
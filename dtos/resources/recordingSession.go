@@ -1,12 +1,57 @@
 package resources
 
+import "time"
+
+// Version 2 adds the size and checksum of each chunk. Version 1 manifests have no version field.
+const RecordingManifestV2 = 2
+
 // Represents a chunked audio file stored in storage.
 // Stored by UI upon uploading audio to the storage.
 // TODO Add duration in seconds (measure in UI and write there)
 type RecordingManifest struct {
-	UID       string   `json:"uid"`
-	SessionID string   `json:"sessionId"`
-	Chunks    []string `json:"chunks"`
-	Count     int      `json:"count"`
-	MIME      string   `json:"mime"`
+	UID          string          `json:"uid"`
+	SessionID    string          `json:"sessionId"`
+	Chunks       []string        `json:"chunks"` // kept in v2 for the readers of v1
+	Count        int             `json:"count"`  // chunks recorded, indexes from 0 to count-1 in v2
+	MIME         string          `json:"mime"`
+	Version      int             `json:"version,omitempty"`
+	ChunkSeconds float64         `json:"chunkSeconds,omitempty"` // recording time of each chunk, 0 when unknown
+	ChunkDetails []ManifestChunk `json:"chunkDetails,omitempty"` // v2
+}
+
+type ManifestChunk struct {
+	Index  int    `json:"index"`
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"` // hex encoded
+}
+
+type RecordingSessionStatus string
+
+const (
+	RecordingSessionRecording RecordingSessionStatus = "recording" // accepting chunks
+	RecordingSessionFinalized RecordingSessionStatus = "finalized" // manifest written, ready to transcribe
+	RecordingSessionExpired   RecordingSessionStatus = "expired"   // abandoned, its chunks were deleted
+)
+
+// Recording session managed by the server. The client uploads the chunks with signed URLs and registers them,
+// on finalize the server writes the manifest from the registered chunks.
+// Stored with the session ID as the document ID.
+type RecordingSession struct {
+	ID           string
+	MIME         string
+	Status       RecordingSessionStatus
+	ChunkSeconds float64          // recording time of each chunk, 0 when the client did not tell
+	Chunks       []RecordingChunk // sorted by index
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	ExpiresAt    *time.Time // while recording, pushed back by every upload. Nil once finalized or expired.
+}
+
+type RecordingChunk struct {
+	Index        int
+	Path         string // in storage
+	Size         int64
+	SHA256       string // hex encoded, as computed by the client
+	RegisteredAt time.Time
 }
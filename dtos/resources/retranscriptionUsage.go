@@ -0,0 +1,22 @@
+package resources
+
+import "time"
+
+// Usage of a re-transcription. Saved as a new revision, the transcript keeps the usage
+// of its first transcription and this record holds the usage of the extra run.
+// Saved as a sibling, the sibling transcript holds the usage and this record only counts the run.
+// Re-transcriptions covered as quality complaints are recorded too, but not billed.
+type RetranscriptionUsage struct {
+	ID                        string
+	TranscriptID              string
+	AudioSessionID            string // of the re-transcribed audio, complaints are limited per audio
+	Version                   int    // revision created by the run, 0 when saved as a sibling
+	SiblingID                 string // transcript created by the run when saved as a sibling
+	Model                     string
+	Billed                    bool
+	ConsumedInputAudioSeconds int
+	ConsumedFreeAudioSeconds  int
+	ConsumedInputTokens       int
+	ConsumedOutputTokens      int
+	CreatedAt                 time.Time
+}
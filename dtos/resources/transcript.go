@@ -10,12 +10,55 @@ type Transcript struct {
 	ID                        string
 	UserRef                   *firestore.DocumentRef
 	RecordingSessionID        string // mathces the manifest in storage
+	AudioSessionID            string // session of the archived audio on siblings, which have no session of their own
 	Tittle                    string
 	Content                   string
-	ConsumedInputAudioSeconds int // total paid + free
+	ConsumedInputAudioSeconds int // total paid + free, AudioDurationSeconds rounded with the billing policy
 	ConsumedFreeAudioSeconds  int
 	ConsumedInputTokens       int // All input tokens including audio and insctructions
 	ConsumedOutputTokens      int
 	CreatedAt                 time.Time
+	UpdatedAt                 time.Time
+	Version                   int // incremented on every content change. 0 on transcripts saved before revisions existed, read as 1
 	IsPrivate                 bool
+	WhitelistedUsersCount     int
+	IsShared                  bool   // has whitelisted users, filtered on by the listing
+	Language                  string // ISO 639-1 code detected from the content, empty when unknown
+	FolderID                  string // empty when not in a folder
+	Tags                      []string
+	RetranscribedFromID       string              // set on transcripts re-transcribed from the audio of another one
+	Complimentary             bool                // not billed, re-transcribed as a quality complaint
+	TrashedAt                 *time.Time          // set while the transcript is in the trash, purged after the retention period
+	MissingAudio              []MissingAudioRange `firestore:"MissingAudio,omitempty"` // recording chunks left out of a lenient transcription
+	AudioDurationSeconds      float64             // exact duration of the audio, 0 on transcripts saved before it was measured
+	SpeechSeconds             float64             // the duration without the non-speech spans, 0 on transcripts saved before it was measured
+	NonSpeechSpans            []AudioSpan         `firestore:"NonSpeechSpans,omitempty"` // the first ones of the recording, up to a limit
+	SilenceTrimmed            bool                // the non-speech spans were trimmed from the audio sent to gemini
+	AudioEnhancement          string              // profile applied before transcription, empty on transcripts saved before the profiles
+	AudioQuality              *AudioQuality       // measured on the audio as recorded, before the enhancement, nil on transcripts saved before it was measured
+}
+
+// Measures of the audio as it was recorded, before the enhancement
+type AudioQuality struct {
+	LoudnessLUFS    float64 // integrated loudness (ITU-R BS.1770), -70 for silent audio
+	PeakDBFS        float64 // -100 for silent audio
+	ClippingPercent float64 // share of the samples at full scale
+	SNRDB           float64 // estimated from the levels of the loud and the quiet frames
+	SilencePercent  float64 // share of the audio quieter than the speech threshold
+	Warnings        []string
+}
+
+// Span of the audio, in seconds from its start
+type AudioSpan struct {
+	StartSeconds float64
+	EndSeconds   float64
+}
+
+// Recording chunks, from FromChunk to ToChunk, missing or corrupt when the session was transcribed.
+// The seconds are estimated from the chunk duration and nil when the manifest does not have it.
+type MissingAudioRange struct {
+	FromChunk    int
+	ToChunk      int
+	StartSeconds *float64
+	EndSeconds   *float64
 }
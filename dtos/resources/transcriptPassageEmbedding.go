@@ -0,0 +1,22 @@
+package resources
+
+import (
+	"time"
+
+	"cloud.google.com/go/firestore"
+)
+
+// Embedding of a passage (a speaker turn or part of a long one) of a transcript, used by the semantic search.
+// Stored under the transcript owner with the ID "<transcriptID>_<passageIndex>".
+type TranscriptPassageEmbedding struct {
+	ID             string
+	TranscriptID   string
+	PassageIndex   int
+	Speaker        string
+	Text           string
+	Offset         int // byte position of Text in the transcript content
+	Provider       string
+	Vector         firestore.Vector32
+	CreatedAt      time.Time
+	VectorDistance float64 `firestore:"VectorDistance,omitempty"` // only set on search results
+}
@@ -0,0 +1,27 @@
+package resources
+
+import (
+	"time"
+
+	"cloud.google.com/go/firestore"
+)
+
+type RevisionSource string
+
+const (
+	RevisionFromTranscription RevisionSource = "transcription" // content generated by gemini
+	RevisionFromEdit          RevisionSource = "edit"
+	RevisionFromRestore       RevisionSource = "restore"
+	RevisionFromRetranscribe  RevisionSource = "retranscription" // content generated again from the archived audio
+)
+
+// Snapshot of the transcript content at a given version. Subcollection of the transcript,
+// the document ID is the version number. Every content change adds one, they are never modified.
+type TranscriptRevision struct {
+	Version             int
+	Content             string
+	Source              RevisionSource
+	RestoredFromVersion int `firestore:"RestoredFromVersion,omitempty"` // only for restores
+	AuthorRef           *firestore.DocumentRef
+	CreatedAt           time.Time
+}
@@ -0,0 +1,14 @@
+package resources
+
+import "time"
+
+// Inverted index entry of a transcript, used by the full-text search.
+// Stored with the transcript ID as the document ID. Terms holds every distinct normalized word of the content,
+// so a Firestore array-contains query on it returns the transcripts containing a word.
+type TranscriptSearchIndex struct {
+	TranscriptID string
+	Terms        []string
+	Speakers     []string
+	CreatedAt    time.Time // copied from the transcript to filter by date without reading it
+	IndexedAt    time.Time
+}
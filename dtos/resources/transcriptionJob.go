@@ -0,0 +1,23 @@
+package resources
+
+import "time"
+
+type TranscriptionJobStatus string
+
+const (
+	TranscriptionJobProcessing TranscriptionJobStatus = "processing"
+	TranscriptionJobCompleted  TranscriptionJobStatus = "completed"
+	TranscriptionJobFailed     TranscriptionJobStatus = "failed"
+)
+
+// Transcription of a recording session. Stored with the session ID as the document ID,
+// so a session has at most one job per user and repeated requests find it.
+type TranscriptionJob struct {
+	SessionID    string
+	Status       TranscriptionJobStatus
+	TranscriptID string // of the last completed run
+	Forced       bool   // the run re-transcribes a session that already had a transcript
+	Error        string // of the last failed run
+	StartedAt    time.Time
+	UpdatedAt    time.Time
+}
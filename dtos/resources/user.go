@@ -10,6 +10,7 @@ type User struct {
 	LastName                 string
 	StripeCustomerID         string
 	PrefersDarkMode          bool
+	AudioEnhancement         string // profile applied to the audio of the transcriptions that do not pick one, empty for none
 	FreeTranscriptionSeconds int
 	CreatedAt                time.Time
 }
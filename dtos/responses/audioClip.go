@@ -0,0 +1,18 @@
+package responses
+
+import "time"
+
+type AudioClipResponse struct {
+	ID           string    `json:"id"`
+	TranscriptID string    `json:"transcriptId"`
+	StartSeconds float64   `json:"startSeconds"`
+	EndSeconds   float64   `json:"endSeconds"`
+	SegmentIndex *int      `json:"segmentIndex,omitempty"`
+	Estimated    bool      `json:"estimated,omitempty"` // the times of the segment are estimates
+	ContentType  string    `json:"contentType"`
+	Size         int64     `json:"size"`
+	IsPrivate    bool      `json:"isPrivate"`
+	AudioURL     string    `json:"audioUrl"` // path of the endpoint playing it
+	ExpiresAt    time.Time `json:"expiresAt"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
@@ -0,0 +1,12 @@
+package responses
+
+type DeleteTranscriptResponse struct {
+	TranscriptID string            `json:"transcriptId"`
+	Complete     bool              `json:"complete"` // false when some cleanup failed, deleting again retries it
+	Failures     []DeletionFailure `json:"failures"`
+}
+
+type DeletionFailure struct {
+	Step  string `json:"step"`
+	Error string `json:"error"`
+}
@@ -0,0 +1,21 @@
+package responses
+
+import "time"
+
+type FolderResponse struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	ParentID  string    `json:"parentId,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+type DeleteFolderResponse struct {
+	MovedTranscripts   int `json:"movedTranscripts"`
+	TrashedTranscripts int `json:"trashedTranscripts"`
+	MovedFolders       int `json:"movedFolders"`
+	DeletedFolders     int `json:"deletedFolders"`
+	// What a folder that was not deleted holds, when no contents action was given
+	ContainedTranscripts int `json:"containedTranscripts,omitempty"`
+	ContainedFolders     int `json:"containedFolders,omitempty"`
+}
@@ -0,0 +1,28 @@
+package responses
+
+import "time"
+
+type RecordingSessionResponse struct {
+	ID           string                   `json:"id"`
+	MIME         string                   `json:"mime"`
+	Status       string                   `json:"status"` // recording, finalized or expired
+	ChunkSeconds float64                  `json:"chunkSeconds,omitempty"`
+	Chunks       []RecordingChunkResponse `json:"chunks"`
+	CreatedAt    time.Time                `json:"createdAt"`
+	UpdatedAt    time.Time                `json:"updatedAt"`
+	ExpiresAt    *time.Time               `json:"expiresAt,omitempty"` // while recording
+}
+
+type RecordingChunkResponse struct {
+	Index  int    `json:"index"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+type ChunkUploadURL struct {
+	Index       int       `json:"index"`
+	URL         string    `json:"url"`
+	Method      string    `json:"method"`
+	ContentType string    `json:"contentType"` // must be sent as the Content-Type header of the upload
+	ExpiresAt   time.Time `json:"expiresAt"`
+}
@@ -0,0 +1,28 @@
+package responses
+
+import "time"
+
+type SearchResponse struct {
+	Results []TranscriptSearchResult `json:"results"`
+}
+
+type TranscriptSearchResult struct {
+	TranscriptID string        `json:"transcriptId"`
+	Tittle       string        `json:"tittle"`
+	CreatedAt    time.Time     `json:"createdAt"`
+	MatchCount   int           `json:"matchCount"` // total matches, Matches may be truncated
+	Matches      []SearchMatch `json:"matches"`
+}
+
+type SearchMatch struct {
+	Start      int            `json:"start"` // byte offsets of the match in the transcript content
+	End        int            `json:"end"`
+	Speaker    string         `json:"speaker,omitempty"`
+	Snippet    string         `json:"snippet"`
+	Highlights []SnippetRange `json:"highlights"` // byte offsets of the matched words inside the snippet
+}
+
+type SnippetRange struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
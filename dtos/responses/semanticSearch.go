@@ -0,0 +1,15 @@
+package responses
+
+type SemanticSearchResponse struct {
+	Results []SimilarPassage `json:"results"`
+}
+
+type SimilarPassage struct {
+	TranscriptID string  `json:"transcriptId"`
+	OwnerID      string  `json:"ownerId"`
+	Tittle       string  `json:"tittle"`
+	Speaker      string  `json:"speaker,omitempty"`
+	Text         string  `json:"text"`
+	Offset       int     `json:"offset"` // byte position of the passage in the transcript content
+	Score        float64 `json:"score"`  // cosine similarity, higher is closer
+}
@@ -6,4 +6,5 @@ type SubscriptionPlan struct {
 	Name                        string
 	TranscriptionMonthlySeconds int
 	FreeTranscriptionSeconds    int
+	BillsSpeechOnly             bool // silences of the recordings are not billed
 }
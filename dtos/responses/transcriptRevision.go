@@ -0,0 +1,25 @@
+package responses
+
+import "time"
+
+type TranscriptRevisionResponse struct {
+	Version             int       `json:"version"`
+	Source              string    `json:"source"` // transcription, edit or restore
+	RestoredFromVersion int       `json:"restoredFromVersion,omitempty"`
+	AuthorID            string    `json:"authorId"`
+	CreatedAt           time.Time `json:"createdAt"`
+	Content             string    `json:"content,omitempty"` // omitted in listings
+}
+
+type TranscriptRevisionDiff struct {
+	FromVersion int      `json:"fromVersion"`
+	ToVersion   int      `json:"toVersion"`
+	Insertions  int      `json:"insertions"` // lines
+	Deletions   int      `json:"deletions"`
+	Ops         []DiffOp `json:"ops"`
+}
+
+type DiffOp struct {
+	Op    string   `json:"op"` // equal, insert or delete
+	Lines []string `json:"lines"`
+}
@@ -3,11 +3,71 @@ package responses
 import "time"
 
 type TranscriptionResponse struct {
-	ID                        string    `json:"id"`
-	RecordingSessionID        string    `json:"recordingSessionID"`
-	Tittle                    string    `json:"tittle"`
-	Content                   string    `json:"content"`
-	ConsumedInputAudioSeconds int       `json:"consumedInputAudioSeconds"`
-	ConsumedFreeAudioSeconds  int       `json:"consumedFreeAudioSeconds"`
-	CreatedAt                 time.Time `json:"createdAt"`
+	ID                        string              `json:"id"`
+	RecordingSessionID        string              `json:"recordingSessionID"`
+	Tittle                    string              `json:"tittle"`
+	Content                   string              `json:"content"`
+	ConsumedInputAudioSeconds int                 `json:"consumedInputAudioSeconds"`
+	ConsumedFreeAudioSeconds  int                 `json:"consumedFreeAudioSeconds"`
+	CreatedAt                 time.Time           `json:"createdAt"`
+	UpdatedAt                 time.Time           `json:"updatedAt"`
+	Version                   int                 `json:"version"`
+	Language                  string              `json:"language,omitempty"`
+	FolderID                  string              `json:"folderId,omitempty"`
+	Tags                      []string            `json:"tags,omitempty"`
+	RetranscribedFromID       string              `json:"retranscribedFromId,omitempty"`
+	Complimentary             bool                `json:"complimentary,omitempty"` // not billed
+	TrashedAt                 *time.Time          `json:"trashedAt,omitempty"`
+	PurgeAt                   *time.Time          `json:"purgeAt,omitempty"` // when a trashed transcript is permanently deleted
+	MissingAudio              []MissingAudioRange `json:"missingAudio,omitempty"`
+	AudioDurationSeconds      float64             `json:"audioDurationSeconds,omitempty"` // exact, consumedInputAudioSeconds is the billed rounding
+	SpeechSeconds             float64             `json:"speechSeconds,omitempty"`        // without the non-speech spans
+	NonSpeechSpans            []AudioSpan         `json:"nonSpeechSpans,omitempty"`
+	SilenceTrimmed            bool                `json:"silenceTrimmed,omitempty"`
+	AudioEnhancement          string              `json:"audioEnhancement,omitempty"`
+	AudioQuality              *AudioQuality       `json:"audioQuality,omitempty"`
+}
+
+// Transcript as served by GET /transcripts/{id} to anyone who can view it, without the fields only its owner sees
+// (organization, trash, billing of re-transcriptions, audio processing)
+type PublicTranscriptResponse struct {
+	ID                        string              `json:"id"`
+	RecordingSessionID        string              `json:"recordingSessionID"`
+	Tittle                    string              `json:"tittle"`
+	Content                   string              `json:"content"`
+	ConsumedInputAudioSeconds int                 `json:"consumedInputAudioSeconds"`
+	ConsumedFreeAudioSeconds  int                 `json:"consumedFreeAudioSeconds"`
+	CreatedAt                 time.Time           `json:"createdAt"`
+	MissingAudio              []MissingAudioRange `json:"missingAudio,omitempty"`
+	AudioDurationSeconds      float64             `json:"audioDurationSeconds,omitempty"`
+	SpeechSeconds             float64             `json:"speechSeconds,omitempty"`
+	NonSpeechSpans            []AudioSpan         `json:"nonSpeechSpans,omitempty"`
+}
+
+// Measures of the recorded audio, before the enhancement, with warnings like "heavy clipping detected" when it may explain a bad transcript
+type AudioQuality struct {
+	LoudnessLUFS    float64  `json:"loudnessLufs"`
+	PeakDBFS        float64  `json:"peakDbfs"`
+	ClippingPercent float64  `json:"clippingPercent"`
+	SNRDB           float64  `json:"snrDb"`
+	SilencePercent  float64  `json:"silencePercent"`
+	Warnings        []string `json:"warnings"`
+}
+
+type AudioSpan struct {
+	StartSeconds float64 `json:"startSeconds"`
+	EndSeconds   float64 `json:"endSeconds"`
+}
+
+// Audio of the recording left out of the transcript, the seconds are estimates
+type MissingAudioRange struct {
+	FromChunk    int      `json:"fromChunk"`
+	ToChunk      int      `json:"toChunk"`
+	StartSeconds *float64 `json:"startSeconds,omitempty"`
+	EndSeconds   *float64 `json:"endSeconds,omitempty"`
+}
+
+type TranscriptListResponse struct {
+	Transcripts []TranscriptionResponse `json:"transcripts"`
+	NextCursor  string                  `json:"nextCursor,omitempty"` // empty on the last page
 }
@@ -0,0 +1,13 @@
+package responses
+
+import "time"
+
+type TranscriptionJobResponse struct {
+	SessionID    string    `json:"sessionId"`
+	Status       string    `json:"status"` // processing, completed or failed
+	TranscriptID string    `json:"transcriptId,omitempty"`
+	Forced       bool      `json:"forced"`
+	Error        string    `json:"error,omitempty"`
+	StartedAt    time.Time `json:"startedAt"`
+	UpdatedAt    time.Time `json:"updatedAt"`
+}
@@ -3,7 +3,8 @@ package responses
 import "time"
 
 type WhitelistResponse struct {
-	Users []WhitelistedUserResponse `json:"users"`
+	Users      []WhitelistedUserResponse `json:"users"`
+	NextCursor string                    `json:"nextCursor,omitempty"` // empty on the last page
 }
 
 type WhitelistedUserResponse struct {
@@ -13,3 +13,33 @@ var ErrExceededSubscriptionTranscriptionLimits = errors.New("ErrExceededSubscrip
 var ErrUnverifiedEmailAccount = errors.New("ErrUnverifiedEmailAccount")
 var ErrInvalidAuthToken = errors.New("ErrInvalidAuthToken")
 var ErrAuthTokenDoesNotMatchAcessedUser = errors.New("ErrAuthTokenDoesNotMatchAcessedUser")
+var ErrInvalidCursor = errors.New("ErrInvalidCursor")
+var ErrUnsupportedTranscriptFilters = errors.New("ErrUnsupportedTranscriptFilters")
+var ErrTranscriptVersionConflict = errors.New("ErrTranscriptVersionConflict")
+var ErrMissingTranscriptVersion = errors.New("ErrMissingTranscriptVersion")
+var ErrTranscriptRevisionNotFound = errors.New("ErrTranscriptRevisionNotFound")
+var ErrTranscriptNotFound = errors.New("ErrTranscriptNotFound")
+var ErrFolderNotFound = errors.New("ErrFolderNotFound")
+var ErrFolderCycle = errors.New("ErrFolderCycle")
+var ErrFolderNotEmpty = errors.New("ErrFolderNotEmpty")
+var ErrInvalidTags = errors.New("ErrInvalidTags")
+var ErrInvalidFolderName = errors.New("ErrInvalidFolderName")
+var ErrTranscriptionJobNotFound = errors.New("ErrTranscriptionJobNotFound")
+var ErrArchivedAudioNotFound = errors.New("ErrArchivedAudioNotFound")
+var ErrInvalidRetranscription = errors.New("ErrInvalidRetranscription")
+var ErrComplaintRetranscriptionsExceeded = errors.New("ErrComplaintRetranscriptionsExceeded")
+var ErrUnsupportedAudioFile = errors.New("ErrUnsupportedAudioFile")
+var ErrUploadTooLarge = errors.New("ErrUploadTooLarge")
+var ErrRecordingSessionNotFound = errors.New("ErrRecordingSessionNotFound")
+var ErrRecordingSessionClosed = errors.New("ErrRecordingSessionClosed")
+var ErrRecordingSessionNotFinalized = errors.New("ErrRecordingSessionNotFinalized")
+var ErrInvalidRecordingChunk = errors.New("ErrInvalidRecordingChunk")
+var ErrInvalidManifest = errors.New("ErrInvalidManifest")
+var ErrMissingRecordingChunks = errors.New("ErrMissingRecordingChunks")
+var ErrCorruptRecordingChunk = errors.New("ErrCorruptRecordingChunk")
+var ErrRecordingTooLarge = errors.New("ErrRecordingTooLarge")
+var ErrTranscoderBusy = errors.New("ErrTranscoderBusy")
+var ErrInvalidAudioEnhancement = errors.New("ErrInvalidAudioEnhancement")
+var ErrWaveformPeaksNotFound = errors.New("ErrWaveformPeaksNotFound")
+var ErrAudioClipNotFound = errors.New("ErrAudioClipNotFound")
+var ErrInvalidAudioClip = errors.New("ErrInvalidAudioClip")
@@ -5,6 +5,7 @@ go 1.24.0
 require (
 	cloud.google.com/go/firestore v1.18.0
 	github.com/google/uuid v1.6.0
+	golang.org/x/sync v0.16.0
 	google.golang.org/api v0.243.0
 	google.golang.org/genai v1.19.0
 )
@@ -43,7 +44,6 @@ require (
 	go.opentelemetry.io/otel/sdk/metric v1.36.0 // indirect
 	go.opentelemetry.io/otel/trace v1.36.0 // indirect
 	golang.org/x/oauth2 v0.30.0 // indirect
-	golang.org/x/sync v0.16.0 // indirect
 	golang.org/x/time v0.12.0 // indirect
 	google.golang.org/appengine/v2 v2.0.6 // indirect
 	google.golang.org/genproto v0.0.0-20250603155806-513f23925822 // indirect
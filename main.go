@@ -1,9 +1,14 @@
 package main
 
 import (
+	"context"
 	"eavesdropper/api"
 	config "eavesdropper/configurations"
+	"eavesdropper/services/audio"
+	"eavesdropper/services/clips"
+	"eavesdropper/services/recordings"
 	"eavesdropper/services/stripe"
+	"eavesdropper/services/transcripts"
 	"log"
 	"os"
 )
@@ -12,6 +17,12 @@ func main() {
 
 	stripe.InitStripe(config.GetStripeKey())
 
+	go transcripts.RunTrashPurge(context.Background())
+	go transcripts.BackfillTranscriptSharing(context.Background())
+	go recordings.RunSessionExpiry(context.Background())
+	go clips.RunClipExpiry(context.Background())
+	go audio.RunArchiveMigration(context.Background())
+
 	port := os.Getenv("PORT")
 	if port == "" {
 		port = "8080" // local default
@@ -0,0 +1,78 @@
+package audio
+
+import (
+	"context"
+	cnfgs "eavesdropper/configurations"
+	"eavesdropper/errs"
+	cloudStorage "eavesdropper/services/data/storage"
+	"fmt"
+	"time"
+
+	"cloud.google.com/go/storage"
+)
+
+// Audio stored in storage, like the final audio of a session, for playback
+type ArchivedAudio struct {
+	Content     *cloudStorage.ObjectReadSeeker // closed by the caller
+	ContentType string
+	ETag        string // quoted, changes when the archive is replaced
+	UpdatedAt   time.Time
+}
+
+// Opens the final audio of the session for reading ranges of it.
+// Sessions without one, like the ones purged from the trash, fail with errs.ErrArchivedAudioNotFound.
+func OpenArchivedAudio(ctx context.Context, userID, sessionID string) (*ArchivedAudio, error) {
+	attrs, err := archivedAudioAttrs(ctx, userID, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	return archivedAudioFromAttrs(ctx, attrs), nil
+}
+
+func archivedAudioFromAttrs(ctx context.Context, attrs *storage.ObjectAttrs) *ArchivedAudio {
+	return &ArchivedAudio{
+		Content:     cloudStorage.NewObjectReadSeeker(ctx, attrs),
+		ContentType: attrs.ContentType,
+		ETag:        `"` + attrs.Etag + `"`,
+		UpdatedAt:   attrs.Updated,
+	}
+}
+
+// Short-lived signed URL of the final audio of the session
+func ArchivedAudioURL(ctx context.Context, userID, sessionID string) (string, error) {
+	attrs, err := archivedAudioAttrs(ctx, userID, sessionID)
+	if err != nil {
+		return "", err
+	}
+	url, err := cloudStorage.SignedDownloadURL(attrs.Name, time.Now().Add(cnfgs.AudioPlaybackURLExpiry))
+	if err != nil {
+		return "", fmt.Errorf("failed to sign download url: %w", err)
+	}
+	return url, nil
+}
+
+// Finds the final audio of the session in whichever format it was archived with.
+// A compressed archive wins over the wav an interrupted migration left behind.
+func archivedAudioAttrs(ctx context.Context, userID, sessionID string) (*storage.ObjectAttrs, error) {
+	objects, err := cloudStorage.ListObjects(ctx, &storage.Query{Prefix: cloudStorage.FinalAudioPath(userID, sessionID, ".")}, 10)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the final audio: %w", err)
+	}
+
+	var wav *storage.ObjectAttrs
+	for _, attrs := range objects {
+		if attrs.Name == cloudStorage.FinalAudioPath(userID, sessionID, ".wav") {
+			wav = attrs
+			continue
+		}
+		for _, format := range codecFormats {
+			if attrs.Name == cloudStorage.FinalAudioPath(userID, sessionID, format.extension) {
+				return attrs, nil
+			}
+		}
+	}
+	if wav == nil {
+		return nil, errs.ErrArchivedAudioNotFound
+	}
+	return wav, nil
+}
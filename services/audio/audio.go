@@ -2,138 +2,223 @@ package audio
 
 import (
 	"context"
+	cnfgs "eavesdropper/configurations"
+	"eavesdropper/dtos/resources"
 	"eavesdropper/errs"
 	cloudStorage "eavesdropper/services/data/storage"
+	"eavesdropper/services/recordings"
+	"eavesdropper/services/transcribe"
 	"errors"
 	"fmt"
 	"io"
-	"math"
-	"os"
-	"os/exec"
-	"path/filepath"
-	"sort"
-	"strconv"
-	"strings"
+	"time"
+
+	"google.golang.org/genai"
 )
 
-// TODO Double check if it's not possible to pass storage audio file path to gemini instead of uploading the local to gemini
+// Stops the chunks stream once the transcoding is over, failed or not
+var errTranscodingStopped = errors.New("transcoding stopped")
+
+// How the audio is processed before it is transcribed
+type ProcessingOptions struct {
+	TrimSilence bool // leaves the non-speech spans out of the audio sent to gemini, the archived audio keeps them
+	// Applied by the transcoder to the audio sent to gemini. The archived audio is kept as recorded,
+	// its playback and re-transcriptions are not enhanced.
+	Enhancement cnfgs.AudioEnhancement
+}
+
+// Audio of a session transcoded, archived in storage and uploaded to gemini as a wav
+type PreparedAudio struct {
+	StoragePath    string
+	Duration       time.Duration // exact, from the decoded samples
+	Seconds        int           // the duration rounded with the billing policy
+	SpeechDuration time.Duration // the duration without the non-speech spans
+	SpeechSeconds  int           // the speech duration rounded with the billing policy
+	NonSpeechSpans []resources.AudioSpan
+	SilenceTrimmed bool
+	Enhancement    cnfgs.AudioEnhancement
+	Quality        *resources.AudioQuality
+	GeminiFile     *genai.File
+	MissingAudio   []resources.MissingAudioRange // recorded audio left out in lenient mode
+}
+
+// Streams the verified chunks of the session manifest through ffmpeg into the final audio.
+// Nothing is written to disk: the chunks go from storage into ffmpeg and its output into storage and gemini.
+// In lenient mode missing and corrupt chunks are left out and listed in MissingAudio, in strict mode they fail.
 func ProcessAudioChunks(
 	ctx context.Context,
 	sessionID string,
 	userID string,
-	directory string,
-) (
-	finalAudioLocalPath string,
-	finalAudioStoragePath string,
-	audioDurationSeconds int,
-	err error,
-) {
+	mode recordings.IntegrityMode,
+	options ProcessingOptions,
+) (*PreparedAudio, error) {
 
 	// Gets the recording session manifest json
 	manifest, err := cloudStorage.LoadManifest(ctx, sessionID, userID)
 	if err != nil {
-		return "", "", 0, err
+		return nil, err
 	}
 	if manifest.Count == 0 || len(manifest.Chunks) == 0 {
-		return "", "", 0, errs.NoAudioChunksInManifest
-	}
-
-	// Downloads the audio chunks locally in sorted order
-	chunkNames := make([]string, len(manifest.Chunks))
-	copy(chunkNames, manifest.Chunks)
-	sort.Slice(chunkNames, func(i, j int) bool {
-		return chunkIndex(chunkNames[i]) < chunkIndex(chunkNames[j])
-	})
-
-	localFiles := []string{}
-	for _, storagePath := range chunkNames {
-		local := filepath.Join(directory, filepath.Base(storagePath))
-		err = cloudStorage.Download(ctx, storagePath, local)
-		if err != nil {
-			return "", "", 0, errors.New("download " + storagePath + ": " + err.Error())
-		}
-		localFiles = append(localFiles, local)
+		return nil, errs.NoAudioChunksInManifest
 	}
-	fmt.Printf("\ndownloaded %d chunks", len(localFiles))
-
-	// Byte-append all chunks into a single webm file
-	joined := filepath.Join(directory, "joined.webm")
-	out, err := os.Create(joined)
-	if err != nil {
-		return "", "", 0, errors.New("create joined: " + err.Error())
+	// Clients wrote their own manifests before the server managed the sessions, never trust them blindly
+	if err := recordings.ValidateManifest(manifest, userID, sessionID); err != nil {
+		return nil, err
 	}
-	for _, local := range localFiles {
-		f, _err := os.Open(local)
-		if _err != nil {
-			out.Close()
-			return "", "", 0, errors.New("open chunk: " + _err.Error())
-		}
-		_, err = io.Copy(out, f)
-		if err != nil {
-			f.Close()
-			out.Close()
-			return "", "", 0, errors.New("append chunk: " + err.Error())
-		}
-		f.Close()
-	}
-	_err := out.Close()
-	if _err != nil {
-		return "", "", 0, errors.New("close joined: " + err.Error())
-	}
-
-	// Transcode once: joined.webm -> final.wav (mono, 16kHz, 16-bit PCM)
-	finalAudioLocalPath = filepath.Join(directory, "final.wav")
-	cmd := exec.Command("ffmpeg", "-y", "-hide_banner", "-nostdin", // TODO change to exec.CommandContext(ctx, ...)
-		"-fflags", "+genpts",
-		"-i", joined,
-		"-ac", "1", "-ar", "16000", "-c:a", "pcm_s16le",
-		finalAudioLocalPath,
-	)
-	outb, err := cmd.CombinedOutput()
+	plan, err := recordings.PlanChunks(manifest, mode)
 	if err != nil {
-		return "", "", 0, errors.New("ffmpeg transcode: " + string(outb) + " err: " + err.Error())
+		return nil, err
 	}
 
-	// Measure audio duration using ffprobe
-	audioDurationSeconds, err = getAudioDuration(finalAudioLocalPath)
-	if err != nil {
-		return "", "", 0, errors.New("get audio duration: " + err.Error())
+	// The chunks are appended as they are written into ffmpeg, which reads them as one webm
+	type streamResult struct {
+		missing []int
+		err     error
+	}
+	chunksReader, chunksWriter := io.Pipe()
+	streamed := make(chan streamResult, 1)
+	go func() {
+		missing, err := streamChunks(ctx, plan, mode, chunksWriter)
+		chunksWriter.CloseWithError(err)
+		streamed <- streamResult{missing: missing, err: err}
+	}()
+
+	prepared, err := transcodeAndArchive(ctx, "", chunksReader, manifest.UID, manifest.SessionID, options)
+	// Unblocks the chunks stream when ffmpeg stopped reading early
+	chunksReader.CloseWithError(errTranscodingStopped)
+	result := <-streamed
+	if result.err != nil && !errors.Is(result.err, errTranscodingStopped) {
+		return nil, result.err
 	}
-
-	// Uploads the complete audio in wav to storage
-	finalAudioStoragePath = cloudStorage.FinalAudioUploadPath(manifest.UID, manifest.SessionID)
-	err = cloudStorage.Upload(ctx, finalAudioStoragePath, finalAudioLocalPath, "audio/wav")
 	if err != nil {
-		return "", "", 0, errors.New("upload final: " + err.Error())
+		return nil, err
 	}
 
-	return finalAudioLocalPath, finalAudioStoragePath, audioDurationSeconds, nil
+	prepared.MissingAudio = recordings.MissingRanges(plan, result.missing)
+	return prepared, nil
 }
 
-func getAudioDuration(audioPath string) (int, error) {
-	cmd := exec.Command("ffprobe", "-v", "quiet", "-show_entries", "format=duration", "-of", "csv=p=0", audioPath)
-	output, err := cmd.Output()
-	if err != nil {
-		return 0, err
+// Transcodes the input into mono 16kHz 16-bit PCM, streamed as a wav to gemini, and at the same time
+// into the archived final audio, encoded with cnfgs.ArchiveCodec, where re-transcriptions find it.
+// Video streams of the input are dropped. The input is read from inputPath, or from input when inputPath is empty.
+// The enhancement profile is applied by the transcoder to the PCM only, before the speech is detected.
+// The quality is measured and the waveform peaks are computed on the audio as recorded, before the enhancement,
+// from a second PCM output of the same pass when enhancing. The peaks are uploaded next to the archive.
+// The speech is detected on the way to gemini, which gets the audio without its non-speech spans when trimming.
+//
+// The wav only exists as the gemini upload, with a streamed header of unknown sizes. Storage keeps the compressed archive.
+func transcodeAndArchive(
+	ctx context.Context,
+	inputPath string,
+	input io.Reader,
+	userID, sessionID string,
+	options ProcessingOptions,
+) (*PreparedAudio, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel() // aborts the uploads left unfinished by a failure
+
+	// Gemini reads the wav as it is produced
+	type geminiUpload struct {
+		file *genai.File
+		err  error
+	}
+	geminiReader, geminiWriter := io.Pipe()
+	uploaded := make(chan geminiUpload, 1)
+	go func() {
+		file, err := transcribe.UploadAudio(ctx, geminiReader, "audio/wav")
+		geminiReader.CloseWithError(err) // fails the writes when the upload stops early
+		uploaded <- geminiUpload{file: file, err: err}
+	}()
+
+	enhancement := options.Enhancement
+	if enhancement == "" {
+		enhancement = cnfgs.DefaultAudioEnhancement
+	}
+
+	format := codecFormats[cnfgs.ArchiveCodec]
+	archivePath := cloudStorage.FinalAudioPath(userID, sessionID, format.extension)
+	archiveWriter := cloudStorage.NewWriter(ctx, archivePath, format.contentType)
+	archive := &EncodedOutput{Codec: cnfgs.ArchiveCodec, Writer: archiveWriter}
+	speech := newSpeechDetector(geminiWriter, options.TrimSilence)
+	quality := newQualityMeter()
+	peaks := &peaksWriter{}
+	recorded := io.MultiWriter(quality, peaks)
+	pcm := &countingWriter{w: io.MultiWriter(speech, recorded)}
+	var original io.Writer
+	if enhancement != cnfgs.EnhancementNone {
+		pcm = &countingWriter{w: speech}
+		original = recorded
 	}
 
-	durationStr := strings.TrimSpace(string(output))
-	duration, err := strconv.ParseFloat(durationStr, 64)
+	_, err := geminiWriter.Write(wavHeader(unknownWAVSize))
+	if err == nil {
+		err = DefaultTranscoder.TranscodeToPCM(ctx, inputPath, input, enhancement, pcm, original, archive)
+	}
+	if err == nil {
+		err = speech.Close()
+	}
+	if err == nil {
+		err = peaks.Close()
+	}
+	if err == nil && pcm.n == 0 {
+		err = fmt.Errorf("%w: no audio was decoded", errs.ErrUnsupportedAudioFile)
+	}
 	if err != nil {
-		return 0, err
+		geminiWriter.CloseWithError(err)
+		<-uploaded
+		var toolErr *ToolError
+		if errors.As(err, &toolErr) {
+			fmt.Printf("\n%s diagnostics: %+v", toolErr.Tool, toolErr.Diagnostics)
+		}
+		return nil, fmt.Errorf("transcode: %w", err)
 	}
 
-	return int(math.Ceil(duration)), nil
+	geminiWriter.Close()
+	upload := <-uploaded
+	if upload.err != nil {
+		return nil, errors.New("upload to gemini: " + upload.err.Error())
+	}
+	if err := archiveWriter.Close(); err != nil {
+		// Without its archive the audio is not transcribed, gemini would keep the upload until it expires
+		if err := transcribe.DeleteUploadedAudio(context.Background(), upload.file); err != nil {
+			fmt.Println("Failed to delete the uploaded audio: ", err)
+		}
+		return nil, errors.New("upload final audio: " + err.Error())
+	}
+	pcmBytes := pcm.n
+
+	// The player draws a flat waveform without them, not worth failing the transcription
+	if err := uploadPeaks(ctx, userID, sessionID, peaks); err != nil {
+		fmt.Println("Failed to upload waveform peaks: ", err)
+	}
+
+	duration := pcmDuration(pcmBytes)
+	speechDuration := max(duration-speech.nonSpeech(), 0)
+	fmt.Printf("\n%s of audio, %s of speech in %d non-speech spans", duration, speechDuration, speech.spansCount)
+
+	return &PreparedAudio{
+		StoragePath:    archivePath,
+		Duration:       duration,
+		Seconds:        transcribe.BilledSeconds(duration),
+		SpeechDuration: speechDuration,
+		SpeechSeconds:  transcribe.BilledSeconds(speechDuration),
+		NonSpeechSpans: speech.spans,
+		SilenceTrimmed: options.TrimSilence,
+		Enhancement:    enhancement,
+		Quality:        quality.quality(),
+		GeminiFile:     upload.file,
+	}, nil
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
 }
 
-func chunkIndex(path string) int {
-	base := filepath.Base(path)
-	// chunk-000123.webm
-	s := strings.TrimPrefix(base, "chunk-")
-	s = strings.TrimSuffix(s, ".webm")
-	var n int
-	fmt.Sscanf(s, "%d", &n)
-	return n
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
 }
 
 // // TODO MOVE TO STORAGE SERVICE
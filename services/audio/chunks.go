@@ -0,0 +1,188 @@
+package audio
+
+import (
+	"bytes"
+	"context"
+	cnfgs "eavesdropper/configurations"
+	"eavesdropper/errs"
+	cloudStorage "eavesdropper/services/data/storage"
+	"eavesdropper/services/recordings"
+	"errors"
+	"fmt"
+	"io"
+	"sync/atomic"
+	"time"
+
+	"cloud.google.com/go/storage"
+)
+
+// A chunk downloaded into memory, or why it could not be
+type fetchedChunk struct {
+	content []byte
+	err     error
+}
+
+// Writes the verified chunks into w in index order, whatever order their downloads finish in.
+// Up to cnfgs.ChunkDownloadWorkers chunks are downloaded ahead of the one being written,
+// so the memory used depends on the workers and the chunk size, not on the recording length.
+// In lenient mode corrupt chunks and chunks missing from storage are returned as missing instead of failing.
+// A failure cancels the pending downloads.
+func streamChunks(
+	ctx context.Context,
+	plan *recordings.ChunkPlan,
+	mode recordings.IntegrityMode,
+	w io.Writer,
+) (missing []int, err error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	budget := &byteBudget{}
+	budget.remaining.Store(cnfgs.MaxRecordingBytes)
+
+	// A slot is taken when a download starts and given back once the chunk is written
+	window := make(chan struct{}, cnfgs.ChunkDownloadWorkers)
+	fetched := make([]chan fetchedChunk, len(plan.Chunks))
+	for i := range fetched {
+		fetched[i] = make(chan fetchedChunk, 1)
+	}
+	go func() {
+		for i, planned := range plan.Chunks {
+			select {
+			case window <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			go func() {
+				content, err := fetchVerifiedChunk(ctx, planned, budget)
+				fetched[i] <- fetchedChunk{content: content, err: err}
+			}()
+		}
+	}()
+
+	missing = append([]int{}, plan.Missing...)
+	written := 0
+	for i, planned := range plan.Chunks {
+		var chunk fetchedChunk
+		select {
+		case chunk = <-fetched[i]:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		<-window
+
+		if mode == recordings.IntegrityLenient &&
+			(errors.Is(chunk.err, errs.ErrCorruptRecordingChunk) || errors.Is(chunk.err, errs.ErrMissingRecordingChunks)) {
+			fmt.Println("Leaving out chunk: ", chunk.err)
+			missing = append(missing, planned.Index)
+			continue
+		}
+		if chunk.err != nil {
+			return nil, chunk.err
+		}
+		if _, err := w.Write(chunk.content); err != nil {
+			return nil, fmt.Errorf("write chunk %d: %w", planned.Index, err)
+		}
+		written++
+	}
+	if written == 0 {
+		return nil, fmt.Errorf("%w: every chunk is corrupt or missing", errs.ErrCorruptRecordingChunk)
+	}
+	fmt.Printf("\nstreamed %d chunks", written)
+
+	return missing, nil
+}
+
+// Downloads the first manifest entry of the chunk that matches its size and checksum.
+// Fails with errs.ErrCorruptRecordingChunk when none does, and with errs.ErrMissingRecordingChunks
+// when the chunk is not in storage.
+func fetchVerifiedChunk(ctx context.Context, planned recordings.PlannedChunk, budget *byteBudget) ([]byte, error) {
+	var verifyErr error
+	for _, candidate := range planned.Candidates {
+		content, err := downloadWithRetry(ctx, candidate.Path, budget)
+		if errors.Is(err, storage.ErrObjectNotExist) {
+			return nil, fmt.Errorf("%w: chunk %d is not in storage", errs.ErrMissingRecordingChunks, planned.Index)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("download %s: %w", candidate.Path, err)
+		}
+		verifyErr = recordings.VerifyChunk(bytes.NewReader(content), candidate)
+		if verifyErr == nil {
+			return content, nil
+		}
+	}
+	return nil, verifyErr
+}
+
+// Retries failed downloads with exponential backoff, except when the object does not exist,
+// the byte budget is spent or the context is done
+func downloadWithRetry(ctx context.Context, storagePath string, budget *byteBudget) ([]byte, error) {
+	backoff := cnfgs.ChunkDownloadBackoff
+	for attempt := 1; ; attempt++ {
+		content, err := download(ctx, storagePath, budget)
+		if err == nil || attempt == cnfgs.ChunkDownloadAttempts || !isRetryableDownloadError(ctx, err) {
+			return content, err
+		}
+		fmt.Printf("\ndownload %s failed (attempt %d), retrying in %s: %v", storagePath, attempt, backoff, err)
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// Downloads the object into memory after reserving its size in the budget.
+// A failed download gives its reservation back.
+func download(ctx context.Context, storagePath string, budget *byteBudget) (content []byte, err error) {
+	reader, err := cloudStorage.OpenReader(ctx, storagePath)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	size := reader.Attrs.Size
+	if size > cnfgs.MaxChunkBytes {
+		return nil, fmt.Errorf("%w: chunk %s has %d bytes, at most %d are accepted", errs.ErrRecordingTooLarge, storagePath, size, int64(cnfgs.MaxChunkBytes))
+	}
+	if !budget.reserve(size) {
+		return nil, fmt.Errorf("%w: the chunks exceed %d bytes", errs.ErrRecordingTooLarge, int64(cnfgs.MaxRecordingBytes))
+	}
+	defer func() {
+		if err != nil {
+			budget.release(size)
+		}
+	}()
+
+	content = make([]byte, size)
+	if _, err := io.ReadFull(reader, content); err != nil {
+		return nil, err
+	}
+	return content, nil
+}
+
+func isRetryableDownloadError(ctx context.Context, err error) bool {
+	return ctx.Err() == nil &&
+		!errors.Is(err, storage.ErrObjectNotExist) &&
+		!errors.Is(err, errs.ErrRecordingTooLarge) &&
+		!errors.Is(err, context.Canceled) &&
+		!errors.Is(err, context.DeadlineExceeded)
+}
+
+// Bytes the downloads of one recording may still read, shared by its workers
+type byteBudget struct {
+	remaining atomic.Int64
+}
+
+func (b *byteBudget) reserve(n int64) bool {
+	if b.remaining.Add(-n) < 0 {
+		b.remaining.Add(n)
+		return false
+	}
+	return true
+}
+
+func (b *byteBudget) release(n int64) {
+	b.remaining.Add(n)
+}
@@ -0,0 +1,67 @@
+package audio
+
+import (
+	"context"
+	cnfgs "eavesdropper/configurations"
+	"eavesdropper/errs"
+	cloudStorage "eavesdropper/services/data/storage"
+	"errors"
+	"fmt"
+	"time"
+
+	"cloud.google.com/go/storage"
+)
+
+// Content type and file extension of the audio encoded with each codec
+var codecFormats = map[cnfgs.AudioCodec]struct{ contentType, extension string }{
+	cnfgs.CodecOpus: {contentType: "audio/ogg", extension: ".ogg"},
+	cnfgs.CodecFLAC: {contentType: "audio/ogg", extension: ".oga"}, // Ogg FLAC
+}
+
+// Audio clip encoded and stored
+type EncodedClip struct {
+	StoragePath string
+	ContentType string
+	Size        int64
+}
+
+// Cuts the audio from start to end out of the final audio of the session, encodes it with cnfgs.ClipCodec
+// and stores it. The transcoder reads the final audio from a signed URL, with range requests around the clip.
+func ExtractClip(ctx context.Context, userID, sessionID, clipID string, start, end time.Duration) (*EncodedClip, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel() // aborts the upload left unfinished by a failure
+
+	archived, err := archivedAudioAttrs(ctx, userID, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	url, err := cloudStorage.SignedDownloadURL(archived.Name, time.Now().Add(cnfgs.TranscodeTimeout))
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign the final audio url: %w", err)
+	}
+
+	format := codecFormats[cnfgs.ClipCodec]
+	clipPath := cloudStorage.ClipPath(userID, sessionID, clipID, format.extension)
+	writer := cloudStorage.NewWriter(ctx, clipPath, format.contentType)
+	encoded := &countingWriter{w: writer}
+	if err := DefaultTranscoder.EncodeFile(ctx, url, start, end, cnfgs.ClipCodec, encoded); err != nil {
+		return nil, fmt.Errorf("encode clip: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return nil, fmt.Errorf("upload clip: %w", err)
+	}
+
+	return &EncodedClip{StoragePath: clipPath, ContentType: format.contentType, Size: encoded.n}, nil
+}
+
+// Opens a stored clip for reading ranges of it. Fails with errs.ErrAudioClipNotFound when it is not stored.
+func OpenClip(ctx context.Context, storagePath string) (*ArchivedAudio, error) {
+	attrs, err := cloudStorage.GetObjectAttrs(ctx, storagePath)
+	if errors.Is(err, storage.ErrObjectNotExist) {
+		return nil, errs.ErrAudioClipNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the clip: %w", err)
+	}
+	return archivedAudioFromAttrs(ctx, attrs), nil
+}
@@ -0,0 +1,415 @@
+package audio
+
+import (
+	"bytes"
+	"context"
+	cnfgs "eavesdropper/configurations"
+	"eavesdropper/errs"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// stderr kept from a run, the last bytes are the ones that explain a failure
+	maxStderrBytes = 64 << 10
+	// How long a killed process may keep its pipes open before they are closed
+	killWaitDelay = 5 * time.Second
+)
+
+// Transcoder running ffmpeg and ffprobe, at most maxProcesses at the same time.
+// Processes are killed with their whole process group when the context is done or they time out.
+type FFmpegTranscoder struct {
+	slots chan struct{}
+}
+
+func NewFFmpegTranscoder(maxProcesses int) *FFmpegTranscoder {
+	return &FFmpegTranscoder{slots: make(chan struct{}, maxProcesses)}
+}
+
+// ffmpeg filter chain of each enhancement profile: high-pass filtering, noise reduction,
+// dynamic range compression and loudness normalization, in that order
+var enhancementFilters = map[cnfgs.AudioEnhancement]string{
+	cnfgs.EnhancementLight: "highpass=f=80,loudnorm=I=-16:TP=-1.5:LRA=11",
+	cnfgs.EnhancementConference: "highpass=f=100,afftdn=nr=12:nf=-40," +
+		"acompressor=threshold=-24dB:ratio=3:attack=10:release=200:makeup=2," +
+		"loudnorm=I=-16:TP=-1.5:LRA=9",
+	cnfgs.EnhancementNoisy: "highpass=f=150,lowpass=f=7000,afftdn=nr=24:nf=-30:tn=1," +
+		"acompressor=threshold=-28dB:ratio=4:attack=5:release=150:makeup=3," +
+		"loudnorm=I=-16:TP=-1.5:LRA=7",
+}
+
+func (t *FFmpegTranscoder) TranscodeToPCM(
+	ctx context.Context,
+	inputPath string,
+	input io.Reader,
+	enhancement cnfgs.AudioEnhancement,
+	output io.Writer,
+	original io.Writer,
+	archive *EncodedOutput,
+) error {
+	args := []string{"-y", "-hide_banner", "-loglevel", "level+warning", "-fflags", "+genpts"}
+	if inputPath != "" {
+		args = append(args, "-nostdin", "-i", inputPath)
+		input = nil
+	} else {
+		args = append(args, "-i", "pipe:0")
+	}
+	// The options of an output come before it. The archive is resampled like the PCM, only the PCM is enhanced.
+	outputArgs := []string{"-vn", "-ac", strconv.Itoa(Channels), "-ar", strconv.Itoa(SampleRate)}
+	args = append(args, outputArgs...)
+	if filters, ok := enhancementFilters[enhancement]; ok {
+		args = append(args, "-af", filters)
+	}
+	pcmArgs := []string{"-c:a", "pcm_s16le", "-f", "s16le"}
+	args = append(args, pcmArgs...)
+	args = append(args, "pipe:1")
+
+	// The extra outputs are the files 3 and on, in the order they are added
+	extraOutputs := []io.Writer{}
+	extraOutput := func(encoder []string, w io.Writer) {
+		args = append(args, outputArgs...)
+		args = append(args, encoder...)
+		args = append(args, "pipe:"+strconv.Itoa(3+len(extraOutputs)))
+		extraOutputs = append(extraOutputs, w)
+	}
+	if original != nil {
+		extraOutput(pcmArgs, original)
+	}
+	if archive != nil {
+		encoder, ok := codecArgs[archive.Codec]
+		if !ok {
+			return fmt.Errorf("no encoder for codec %q", archive.Codec)
+		}
+		extraOutput(encoder, archive.Writer)
+	}
+	return t.run(ctx, cnfgs.TranscodeTimeout, "ffmpeg", args, input, output, extraOutputs...)
+}
+
+// ffmpeg encoder and muxer of each codec, with container formats that can be written to a pipe.
+// FLAC goes in Ogg too: written to a pipe the flac muxer cannot go back to write the total samples
+// and a seektable, while Ogg pages carry their position, so players and ffmpeg seek in them with range requests.
+var codecArgs = map[cnfgs.AudioCodec][]string{
+	cnfgs.CodecOpus: {"-c:a", "libopus", "-b:a", "24k", "-application", "voip", "-f", "ogg"},
+	cnfgs.CodecFLAC: {"-c:a", "flac", "-compression_level", "8", "-f", "ogg"},
+}
+
+func (t *FFmpegTranscoder) EncodeFile(
+	ctx context.Context,
+	inputPath string,
+	start, end time.Duration,
+	codec cnfgs.AudioCodec,
+	output io.Writer,
+) error {
+	encoder, ok := codecArgs[codec]
+	if !ok {
+		return fmt.Errorf("no encoder for codec %q", codec)
+	}
+	args := []string{"-y", "-hide_banner", "-loglevel", "level+warning", "-nostdin"}
+	// Seeking before the input skips to the start without decoding the audio before it
+	if start > 0 {
+		args = append(args, "-ss", ffmpegTime(start))
+	}
+	args = append(args, "-i", inputPath)
+	if end > 0 {
+		args = append(args, "-t", ffmpegTime(end-start))
+	}
+	args = append(args, "-vn", "-ac", strconv.Itoa(Channels), "-ar", strconv.Itoa(SampleRate))
+	args = append(args, encoder...)
+	args = append(args, "pipe:1")
+
+	return t.run(ctx, cnfgs.TranscodeTimeout, "ffmpeg", args, nil, output)
+}
+
+// Seconds with millisecond precision
+func ffmpegTime(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', 3, 64)
+}
+
+func (t *FFmpegTranscoder) Probe(ctx context.Context, path string) (*Probe, error) {
+	var output bytes.Buffer
+	err := t.run(ctx, cnfgs.ProbeTimeout, "ffprobe", []string{
+		"-v", "error",
+		"-show_entries", "format=format_name,duration:stream=codec_type",
+		"-of", "json", path,
+	}, nil, &output)
+
+	var toolErr *ToolError
+	if errors.As(err, &toolErr) && toolErr.ExitCode > 0 {
+		return nil, fmt.Errorf("%w: the file could not be read as audio or video", errs.ErrUnsupportedAudioFile)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Format struct {
+			FormatName string `json:"format_name"`
+			Duration   string `json:"duration"`
+		} `json:"format"`
+		Streams []struct {
+			CodecType string `json:"codec_type"`
+		} `json:"streams"`
+	}
+	if err := json.Unmarshal(output.Bytes(), &result); err != nil {
+		return nil, fmt.Errorf("decode ffprobe output: %w", err)
+	}
+
+	probe := &Probe{FormatName: result.Format.FormatName}
+	probe.DurationSeconds, _ = strconv.ParseFloat(result.Format.Duration, 64)
+	for _, stream := range result.Streams {
+		if stream.CodecType == "audio" {
+			probe.HasAudio = true
+		}
+	}
+	return probe, nil
+}
+
+// Runs the tool once a process slot is free. A failed write into stdout kills the process,
+// otherwise it would block on its full output pipe forever.
+// The extra outputs are given to the process as the files 3, 4 and on, like stdout.
+func (t *FFmpegTranscoder) run(
+	ctx context.Context,
+	timeout time.Duration,
+	name string,
+	args []string,
+	stdin io.Reader,
+	stdout io.Writer,
+	extraOutputs ...io.Writer,
+) error {
+	if err := t.acquire(ctx); err != nil {
+		return err
+	}
+	defer func() { <-t.slots }()
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	output := &cancelOnErrorWriter{w: stdout, cancel: cancel}
+	stderr := &tailBuffer{max: maxStderrBytes}
+	cmd := exec.CommandContext(ctx, name, args...)
+	if stdin != nil {
+		cmd.Stdin = stdin
+	}
+	cmd.Stdout = output
+	cmd.Stderr = stderr
+	cmd.WaitDelay = killWaitDelay
+	killProcessGroupOnCancel(cmd)
+
+	extras, err := pipeExtraOutputs(cmd, extraOutputs, cancel)
+	if err != nil {
+		return err
+	}
+	err = cmd.Start()
+	extras.closeChildEnds()
+	if err == nil {
+		err = cmd.Wait()
+	}
+	extras.wait() // the process is gone, its ends of the pipes are closed
+	if output.err != nil {
+		return output.err
+	}
+	if extraErr := extras.err(); extraErr != nil {
+		return extraErr
+	}
+	if err == nil {
+		return nil
+	}
+
+	toolErr := &ToolError{
+		Tool:        name,
+		ExitCode:    -1,
+		TimedOut:    errors.Is(ctx.Err(), context.DeadlineExceeded),
+		Diagnostics: parseDiagnostics(stderr.String()),
+		Err:         err,
+	}
+	if cmd.ProcessState != nil {
+		toolErr.ExitCode = cmd.ProcessState.ExitCode()
+	}
+	if toolErr.TimedOut {
+		toolErr.Err = fmt.Errorf("timed out after %s", timeout)
+	}
+	return toolErr
+}
+
+// Waits up to cnfgs.TranscoderQueueWait for a process slot
+func (t *FFmpegTranscoder) acquire(ctx context.Context) error {
+	timer := time.NewTimer(cnfgs.TranscoderQueueWait)
+	defer timer.Stop()
+
+	select {
+	case t.slots <- struct{}{}:
+		return nil
+	case <-timer.C:
+		return errs.ErrTranscoderBusy
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Failed run of ffmpeg or ffprobe, with what it wrote to stderr
+type ToolError struct {
+	Tool        string
+	ExitCode    int // -1 when it did not exit on its own, like when it was killed
+	TimedOut    bool
+	Diagnostics []Diagnostic
+	Err         error
+}
+
+func (e *ToolError) Error() string {
+	message := fmt.Sprintf("%s failed (exit code %d): %v", e.Tool, e.ExitCode, e.Err)
+	for i := len(e.Diagnostics) - 1; i >= 0; i-- {
+		if e.Diagnostics[i].Level != "warning" {
+			return message + ": " + e.Diagnostics[i].Message
+		}
+	}
+	return message
+}
+
+func (e *ToolError) Unwrap() error {
+	return e.Err
+}
+
+// A line ffmpeg or ffprobe wrote to stderr, like "[error] Invalid data found when processing input"
+type Diagnostic struct {
+	Level     string // warning, error, fatal or panic. Empty for lines without a level
+	Component string // like "matroska,webm @ 0x5581", empty when the line has none
+	Message   string
+}
+
+var diagnosticLevels = []string{"warning", "error", "fatal", "panic"}
+
+// Parses the lines written with "-loglevel level+...": "[component @ 0x1] [error] message"
+func parseDiagnostics(stderr string) []Diagnostic {
+	diagnostics := []Diagnostic{}
+	for _, line := range strings.Split(stderr, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		diagnostic := Diagnostic{Message: line}
+		if strings.HasPrefix(line, "[") && !hasLevelPrefix(line) {
+			if end := strings.Index(line, "] "); end > 0 {
+				diagnostic.Component = line[1:end]
+				line = line[end+2:]
+				diagnostic.Message = line
+			}
+		}
+		for _, level := range diagnosticLevels {
+			if prefix := "[" + level + "] "; strings.HasPrefix(line, prefix) {
+				diagnostic.Level = level
+				diagnostic.Message = strings.TrimPrefix(line, prefix)
+				break
+			}
+		}
+		diagnostics = append(diagnostics, diagnostic)
+	}
+	return diagnostics
+}
+
+func hasLevelPrefix(line string) bool {
+	for _, level := range diagnosticLevels {
+		if strings.HasPrefix(line, "["+level+"] ") {
+			return true
+		}
+	}
+	return false
+}
+
+// Pipes of the extra outputs of a process, copied into their writers while it runs
+type extraOutputPipes struct {
+	childEnds []*os.File
+	writers   []*cancelOnErrorWriter
+	copies    sync.WaitGroup
+	copyErrs  []error
+}
+
+// Gives the process a pipe per output, whose content is copied into it. Failing writes cancel the run.
+func pipeExtraOutputs(cmd *exec.Cmd, outputs []io.Writer, cancel context.CancelFunc) (*extraOutputPipes, error) {
+	pipes := &extraOutputPipes{copyErrs: make([]error, len(outputs))}
+	for i, output := range outputs {
+		reader, writer, err := os.Pipe()
+		if err != nil {
+			pipes.closeChildEnds()
+			pipes.wait()
+			return nil, fmt.Errorf("extra output pipe: %w", err)
+		}
+		pipes.childEnds = append(pipes.childEnds, writer)
+		cmd.ExtraFiles = append(cmd.ExtraFiles, writer)
+
+		copied := &cancelOnErrorWriter{w: output, cancel: cancel}
+		pipes.writers = append(pipes.writers, copied)
+		pipes.copies.Add(1)
+		go func() {
+			defer pipes.copies.Done()
+			_, pipes.copyErrs[i] = io.Copy(copied, reader)
+			reader.Close() // a process still writing gets a broken pipe
+		}()
+	}
+	return pipes, nil
+}
+
+// Closes the ends handed to the process once it started with its own copies, so the reads end with it
+func (p *extraOutputPipes) closeChildEnds() {
+	for _, end := range p.childEnds {
+		_ = end.Close()
+	}
+}
+
+func (p *extraOutputPipes) wait() {
+	p.copies.Wait()
+}
+
+// The first failed write into an output, or the first failed read of a pipe
+func (p *extraOutputPipes) err() error {
+	for i, writer := range p.writers {
+		if writer.err != nil {
+			return writer.err
+		}
+		if p.copyErrs[i] != nil {
+			return p.copyErrs[i]
+		}
+	}
+	return nil
+}
+
+// Writer that cancels the run on its first failed write, and remembers the failure
+type cancelOnErrorWriter struct {
+	w      io.Writer
+	cancel context.CancelFunc
+	err    error
+}
+
+func (c *cancelOnErrorWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	if err != nil && c.err == nil {
+		c.err = err
+		c.cancel()
+	}
+	return n, err
+}
+
+// Keeps the last max bytes written into it
+type tailBuffer struct {
+	buf []byte
+	max int
+}
+
+func (t *tailBuffer) Write(p []byte) (int, error) {
+	t.buf = append(t.buf, p...)
+	if len(t.buf) > t.max {
+		t.buf = t.buf[len(t.buf)-t.max:]
+	}
+	return len(p), nil
+}
+
+func (t *tailBuffer) String() string {
+	return string(t.buf)
+}
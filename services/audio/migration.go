@@ -0,0 +1,214 @@
+package audio
+
+import (
+	"context"
+	cnfgs "eavesdropper/configurations"
+	"eavesdropper/errs"
+	cloudStorage "eavesdropper/services/data/storage"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/storage"
+)
+
+// Another instance holds the lease of the migration of the wav archive
+var errArchiveMigrationLeased = errors.New("the wav archive is migrated by another instance")
+
+// What a run of the migration of the wav archives did
+type ArchiveMigrationReport struct {
+	Migrated    int
+	Failed      int
+	Skipped     int   // migrated by another instance meanwhile
+	BytesBefore int64 // of the migrated wav archives
+	BytesAfter  int64 // of the compressed archives replacing them
+}
+
+func (r *ArchiveMigrationReport) BytesSaved() int64 {
+	return r.BytesBefore - r.BytesAfter
+}
+
+func (r *ArchiveMigrationReport) add(other *ArchiveMigrationReport) {
+	r.Migrated += other.Migrated
+	r.Failed += other.Failed
+	r.Skipped += other.Skipped
+	r.BytesBefore += other.BytesBefore
+	r.BytesAfter += other.BytesAfter
+}
+
+// Encodes a batch of the final audio archived as wav, before the archives were compressed, with cnfgs.ArchiveCodec
+// and deletes the wav. The batch starts at the object name after, and the name the next batch starts at is returned,
+// empty once every wav archive was listed. Stops early while the transcoder is busy, the requests go first.
+// Every instance runs it, each wav is migrated by the instance that takes its lease and skipped by the others.
+func MigrateWAVArchives(ctx context.Context, after string) (*ArchiveMigrationReport, string, error) {
+	wavs, err := cloudStorage.ListObjects(ctx, &storage.Query{
+		MatchGlob:   cloudStorage.WAVFinalAudioGlob(),
+		StartOffset: after,
+	}, cnfgs.ArchiveMigrationBatchSize)
+	if err != nil {
+		return nil, after, err
+	}
+
+	report := &ArchiveMigrationReport{}
+	for _, wav := range wavs {
+		size, err := migrateWAVArchive(ctx, wav)
+		if errors.Is(err, errs.ErrTranscoderBusy) {
+			return report, wav.Name, nil
+		}
+		if errors.Is(err, errArchiveMigrationLeased) {
+			report.Skipped++
+			continue
+		}
+		if err != nil {
+			log.Printf("[Archive] failed to migrate %s: %s", wav.Name, err)
+			report.Failed++
+			continue
+		}
+		report.Migrated++
+		report.BytesBefore += wav.Size
+		report.BytesAfter += size
+	}
+
+	if len(wavs) < cnfgs.ArchiveMigrationBatchSize {
+		return report, "", nil
+	}
+	// Past the last one, the failed ones are retried once the listing starts over
+	return report, wavs[len(wavs)-1].Name + "\x00", nil
+}
+
+// Encodes the wav archive into the archive of cnfgs.ArchiveCodec next to it, then deletes the wav.
+// Returns the size of the compressed archive. Fails with errArchiveMigrationLeased while another instance migrates it.
+func migrateWAVArchive(ctx context.Context, wav *storage.ObjectAttrs) (int64, error) {
+	leasePath := cloudStorage.ArchiveMigrationLeasePath(wav.Name)
+	if err := takeArchiveMigrationLease(ctx, leasePath); err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err := cloudStorage.Delete(context.Background(), leasePath); err != nil {
+			log.Printf("[Archive] failed to release the lease %s: %s", leasePath, err)
+		}
+	}()
+
+	format := codecFormats[cnfgs.ArchiveCodec]
+	archivePath := strings.TrimSuffix(wav.Name, ".wav") + format.extension
+
+	archive, err := cloudStorage.GetObjectAttrs(ctx, archivePath)
+	if errors.Is(err, storage.ErrObjectNotExist) {
+		archive, err = encodeWAVArchive(ctx, wav, archivePath)
+	}
+	// Otherwise a run stopped between the encoding and the deletion of the wav
+	if err != nil {
+		return 0, err
+	}
+
+	err = cloudStorage.DeleteGeneration(ctx, wav.Name, wav.Generation)
+	if errors.Is(err, storage.ErrObjectNotExist) {
+		// The session was purged meanwhile, the new archive must go with it
+		if err := cloudStorage.Delete(ctx, archivePath); err != nil {
+			log.Printf("[Archive] failed to delete %s: %s", archivePath, err)
+		}
+		return 0, errors.New("the wav archive was deleted while it was migrated")
+	}
+	if err != nil {
+		return 0, fmt.Errorf("delete the wav archive: %w", err)
+	}
+	return archive.Size, nil
+}
+
+// Takes the lease of the migration of a wav archive, so instances never encode the same one.
+// Fails with errArchiveMigrationLeased while another instance holds it. A lease older than
+// cnfgs.ArchiveMigrationLeaseTTL was left by an instance that died and is taken over.
+func takeArchiveMigrationLease(ctx context.Context, leasePath string) error {
+	created, err := cloudStorage.CreateIfNotExists(ctx, leasePath)
+	if err != nil {
+		return fmt.Errorf("take the migration lease: %w", err)
+	}
+	if created {
+		return nil
+	}
+
+	lease, err := cloudStorage.GetObjectAttrs(ctx, leasePath)
+	if errors.Is(err, storage.ErrObjectNotExist) {
+		return errArchiveMigrationLeased // released meanwhile, the next run migrates it
+	}
+	if err != nil {
+		return fmt.Errorf("read the migration lease: %w", err)
+	}
+	if time.Since(lease.Created) < cnfgs.ArchiveMigrationLeaseTTL {
+		return errArchiveMigrationLeased
+	}
+
+	// Only the instance deleting this generation of the stale lease takes it over
+	err = cloudStorage.DeleteGeneration(ctx, leasePath, lease.Generation)
+	if errors.Is(err, storage.ErrObjectNotExist) || cloudStorage.IsPreconditionFailed(err) {
+		return errArchiveMigrationLeased
+	}
+	if err != nil {
+		return fmt.Errorf("delete the stale migration lease: %w", err)
+	}
+	created, err = cloudStorage.CreateIfNotExists(ctx, leasePath)
+	if err != nil {
+		return fmt.Errorf("take the migration lease: %w", err)
+	}
+	if !created {
+		return errArchiveMigrationLeased
+	}
+	return nil
+}
+
+// The transcoder reads the wav from a signed URL and its output is uploaded as it is produced.
+// The archive is never overwritten, even if a stale lease was taken over while its instance was still uploading.
+func encodeWAVArchive(ctx context.Context, wav *storage.ObjectAttrs, archivePath string) (*storage.ObjectAttrs, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel() // aborts the upload left unfinished by a failure
+
+	url, err := cloudStorage.SignedDownloadURL(wav.Name, time.Now().Add(cnfgs.TranscodeTimeout))
+	if err != nil {
+		return nil, fmt.Errorf("sign the wav archive url: %w", err)
+	}
+
+	writer := cloudStorage.NewExclusiveWriter(ctx, archivePath, codecFormats[cnfgs.ArchiveCodec].contentType)
+	if err := DefaultTranscoder.EncodeFile(ctx, url, 0, 0, cnfgs.ArchiveCodec, writer); err != nil {
+		return nil, fmt.Errorf("encode: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return nil, fmt.Errorf("upload: %w", err)
+	}
+	return writer.Attrs(), nil
+}
+
+// Runs MigrateWAVArchives every cnfgs.ArchiveMigrationInterval until the context is cancelled,
+// logging the bytes saved by each run and since the start
+func RunArchiveMigration(ctx context.Context) {
+	ticker := time.NewTicker(cnfgs.ArchiveMigrationInterval)
+	defer ticker.Stop()
+
+	total := &ArchiveMigrationReport{}
+	after := ""
+	for {
+		report, next, err := MigrateWAVArchives(ctx, after)
+		if err != nil {
+			log.Printf("[Archive] migration failed: %s", err)
+		} else if report.Migrated > 0 || report.Failed > 0 {
+			total.add(report)
+			log.Printf(
+				"[Archive] migrated %d wav archives to %s (%d failed), saved %.1f MB, %.1f MB in %d archives since the start",
+				report.Migrated, cnfgs.ArchiveCodec, report.Failed,
+				megabytes(report.BytesSaved()), megabytes(total.BytesSaved()), total.Migrated,
+			)
+		}
+		after = next
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func megabytes(bytes int64) float64 {
+	return float64(bytes) / 1e6
+}
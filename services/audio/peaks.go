@@ -0,0 +1,116 @@
+package audio
+
+import (
+	"context"
+	cnfgs "eavesdropper/configurations"
+	"eavesdropper/errs"
+	cloudStorage "eavesdropper/services/data/storage"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"cloud.google.com/go/storage"
+)
+
+// Header of the audiowaveform binary format (version 1), read by the usual waveform players
+const (
+	peaksFormatVersion = 1
+	peaksFlag8Bit      = 1
+	peaksHeaderSize    = 20
+)
+
+// Min and max of each bucket of samples of the PCM written into it, in the final audio format.
+// The finest zoom of cnfgs.WaveformZooms is computed from the samples and the others from it.
+// The peaks are 8-bit, plenty for drawing: an hour takes under 1MB at the finest zoom.
+type peaksWriter struct {
+	partial  []byte // first byte of a sample split between writes
+	samples  int    // in the current bucket
+	min, max int8
+	peaks    []int8 // min and max pairs of the finest zoom
+}
+
+func (p *peaksWriter) Write(b []byte) (int, error) {
+	written := len(b)
+	if len(p.partial) > 0 && len(b) > 0 {
+		p.sample(int16(binary.LittleEndian.Uint16([]byte{p.partial[0], b[0]})))
+		p.partial = p.partial[:0]
+		b = b[1:]
+	}
+	for ; len(b) >= 2; b = b[2:] {
+		p.sample(int16(binary.LittleEndian.Uint16(b)))
+	}
+	p.partial = append(p.partial, b...)
+	return written, nil
+}
+
+func (p *peaksWriter) sample(sample int16) {
+	value := int8(sample >> 8)
+	if p.samples == 0 {
+		p.min, p.max = value, value
+	}
+	p.min, p.max = min(p.min, value), max(p.max, value)
+	p.samples++
+	if p.samples == cnfgs.WaveformZooms[0] {
+		p.endBucket()
+	}
+}
+
+func (p *peaksWriter) endBucket() {
+	p.peaks = append(p.peaks, p.min, p.max)
+	p.samples = 0
+}
+
+// Ends the last bucket, even when it is not full, once the audio is over
+func (p *peaksWriter) Close() error {
+	if p.samples > 0 {
+		p.endBucket()
+	}
+	return nil
+}
+
+// The peaks at the zoom in the audiowaveform binary format, merging the buckets of the finest zoom
+func (p *peaksWriter) encode(zoom int) []byte {
+	merged := zoom / cnfgs.WaveformZooms[0]
+	buckets := (len(p.peaks)/2 + merged - 1) / merged
+
+	data := make([]byte, 0, peaksHeaderSize+2*buckets)
+	data = binary.LittleEndian.AppendUint32(data, peaksFormatVersion)
+	data = binary.LittleEndian.AppendUint32(data, peaksFlag8Bit)
+	data = binary.LittleEndian.AppendUint32(data, SampleRate)
+	data = binary.LittleEndian.AppendUint32(data, uint32(zoom))
+	data = binary.LittleEndian.AppendUint32(data, uint32(buckets))
+	for start := 0; start < len(p.peaks); start += 2 * merged {
+		end := min(start+2*merged, len(p.peaks))
+		low, high := p.peaks[start], p.peaks[start+1]
+		for i := start + 2; i < end; i += 2 {
+			low, high = min(low, p.peaks[i]), max(high, p.peaks[i+1])
+		}
+		data = append(data, byte(low), byte(high))
+	}
+	return data
+}
+
+// Uploads the peaks of every zoom next to the final audio
+func uploadPeaks(ctx context.Context, userID, sessionID string, peaks *peaksWriter) error {
+	for _, zoom := range cnfgs.WaveformZooms {
+		writer := cloudStorage.NewWriter(ctx, cloudStorage.WaveformPeaksPath(userID, sessionID, zoom), "application/octet-stream")
+		if _, err := writer.Write(peaks.encode(zoom)); err != nil {
+			_ = writer.Close()
+			return fmt.Errorf("upload waveform peaks at zoom %d: %w", zoom, err)
+		}
+		if err := writer.Close(); err != nil {
+			return fmt.Errorf("upload waveform peaks at zoom %d: %w", zoom, err)
+		}
+	}
+	return nil
+}
+
+// Opens the waveform peaks of the session at the zoom. Sessions processed before the peaks were generated
+// fail with errs.ErrWaveformPeaksNotFound.
+func OpenWaveformPeaks(ctx context.Context, userID, sessionID string, zoom int) (*storage.Reader, error) {
+	reader, err := cloudStorage.OpenReader(ctx, cloudStorage.WaveformPeaksPath(userID, sessionID, zoom))
+	if errors.Is(err, storage.ErrObjectNotExist) {
+		return nil, errs.ErrWaveformPeaksNotFound
+	}
+	return reader, err
+}
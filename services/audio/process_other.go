@@ -0,0 +1,8 @@
+//go:build !unix
+
+package audio
+
+import "os/exec"
+
+// Process groups are unix only, the process alone is killed on cancel
+func killProcessGroupOnCancel(cmd *exec.Cmd) {}
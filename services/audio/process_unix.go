@@ -0,0 +1,17 @@
+//go:build unix
+
+package audio
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// Runs the process in its own process group and kills the whole group on cancel,
+// so the children ffmpeg may start die with it
+func killProcessGroupOnCancel(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+}
@@ -0,0 +1,275 @@
+package audio
+
+import (
+	cnfgs "eavesdropper/configurations"
+	"eavesdropper/dtos/resources"
+	"encoding/binary"
+	"math"
+)
+
+const (
+	// The 400ms loudness blocks are made of 4 sub-blocks of 100ms, so they overlap by 75%
+	loudnessSubBlockSamples = SampleRate / 10
+	loudnessBlockSubBlocks  = 4
+	absoluteGateLUFS        = -70.0
+	relativeGateLU          = 10.0
+	// Gated blocks are kept in a histogram, so long recordings take no more memory
+	loudnessBinLU   = 0.1
+	loudnessBins    = int((10 - absoluteGateLUFS) / loudnessBinLU)
+	silentDBFS      = -100.0
+	levelBins       = int(-silentDBFS) // 1 dB bins of the frame levels, from -100 dBFS to 0
+	noisePercentile = 10.0
+	// Speech is not loud all the time, the loudest frames give its level
+	signalPercentile    = 95.0
+	qualityFrameSamples = speechFrameBytes / (BitsPerSample / 8)
+)
+
+// Measures the quality of the PCM written into it, in the final audio format: the integrated loudness,
+// the peak, the clipping, an estimated signal to noise ratio and the share of silence.
+// It holds no audio, the levels go into histograms.
+type qualityMeter struct {
+	partial []byte // first byte of a sample split between writes
+
+	samples int64
+	peak    float64 // of the absolute samples, 1 is full scale
+	clipped int64
+
+	shelf, highPass biquad // K-weighting of the loudness measure
+	subBlockPower   float64
+	subBlockSamples int
+	subBlocks       [loudnessBlockSubBlocks]float64
+	subBlocksCount  int64
+	loudnessPower   [loudnessBins]float64 // power of the gated blocks, by loudness
+	loudnessBlocks  [loudnessBins]int64
+	framePower      float64
+	frameSamples    int
+	frameLevels     [levelBins]int64 // frames by level, digital silence left out
+	frames          int64
+	silentFrames    int64
+}
+
+func newQualityMeter() *qualityMeter {
+	m := &qualityMeter{}
+	m.shelf, m.highPass = kWeighting(SampleRate)
+	return m
+}
+
+func (m *qualityMeter) Write(p []byte) (int, error) {
+	written := len(p)
+	if len(m.partial) > 0 && len(p) > 0 {
+		m.sample(int16(binary.LittleEndian.Uint16([]byte{m.partial[0], p[0]})))
+		m.partial = m.partial[:0]
+		p = p[1:]
+	}
+	for ; len(p) >= 2; p = p[2:] {
+		m.sample(int16(binary.LittleEndian.Uint16(p)))
+	}
+	m.partial = append(m.partial, p...)
+	return written, nil
+}
+
+func (m *qualityMeter) sample(sample int16) {
+	m.samples++
+	x := float64(sample) / -math.MinInt16
+	if sample >= math.MaxInt16 || sample == math.MinInt16 {
+		m.clipped++
+	}
+	m.peak = max(m.peak, math.Abs(x))
+
+	weighted := m.highPass.process(m.shelf.process(x))
+	m.subBlockPower += weighted * weighted
+	m.subBlockSamples++
+	if m.subBlockSamples == loudnessSubBlockSamples {
+		m.endSubBlock()
+	}
+
+	m.framePower += x * x
+	m.frameSamples++
+	if m.frameSamples == qualityFrameSamples {
+		m.endFrame()
+	}
+}
+
+// Adds the block of the last 4 sub-blocks, once there are 4, to the loudness histogram when it passes the absolute gate
+func (m *qualityMeter) endSubBlock() {
+	m.subBlocks[m.subBlocksCount%loudnessBlockSubBlocks] = m.subBlockPower / float64(m.subBlockSamples)
+	m.subBlocksCount++
+	m.subBlockPower, m.subBlockSamples = 0, 0
+	if m.subBlocksCount < loudnessBlockSubBlocks {
+		return
+	}
+
+	var power float64
+	for _, subBlock := range m.subBlocks {
+		power += subBlock
+	}
+	power /= loudnessBlockSubBlocks
+	loudness := blockLoudness(power)
+	if loudness <= absoluteGateLUFS {
+		return
+	}
+	bin := min(int((loudness-absoluteGateLUFS)/loudnessBinLU), loudnessBins-1)
+	m.loudnessPower[bin] += power
+	m.loudnessBlocks[bin]++
+}
+
+func (m *qualityMeter) endFrame() {
+	rms := math.Sqrt(m.framePower / float64(m.frameSamples))
+	m.framePower, m.frameSamples = 0, 0
+	m.frames++
+
+	level := silentDBFS
+	if rms > 0 {
+		level = max(20*math.Log10(rms), silentDBFS)
+		m.frameLevels[min(int(level-silentDBFS), levelBins-1)]++
+	}
+	if level <= cnfgs.SpeechThresholdDBFS {
+		m.silentFrames++
+	}
+}
+
+// The measures of the audio written so far, with the warnings they call for
+func (m *qualityMeter) quality() *resources.AudioQuality {
+	if m.frameSamples > 0 {
+		m.endFrame()
+	}
+	quality := &resources.AudioQuality{
+		LoudnessLUFS: round2(m.integratedLoudness()),
+		PeakDBFS:     silentDBFS,
+		SNRDB:        round2(m.snr()),
+	}
+	if m.peak > 0 {
+		quality.PeakDBFS = round2(max(20*math.Log10(m.peak), silentDBFS))
+	}
+	if m.samples > 0 {
+		quality.ClippingPercent = round2(100 * float64(m.clipped) / float64(m.samples))
+	}
+	if m.frames > 0 {
+		quality.SilencePercent = round2(100 * float64(m.silentFrames) / float64(m.frames))
+	}
+	quality.Warnings = qualityWarnings(quality)
+	return quality
+}
+
+// Loudness of the blocks above the relative gate, 10 LU under the loudness of the blocks above the absolute gate
+func (m *qualityMeter) integratedLoudness() float64 {
+	var power float64
+	var blocks int64
+	for bin := range loudnessBins {
+		power += m.loudnessPower[bin]
+		blocks += m.loudnessBlocks[bin]
+	}
+	if blocks == 0 {
+		return absoluteGateLUFS
+	}
+
+	gate := blockLoudness(power/float64(blocks)) - relativeGateLU
+	power, blocks = 0, 0
+	for bin := range loudnessBins {
+		if absoluteGateLUFS+(float64(bin)+0.5)*loudnessBinLU >= gate {
+			power += m.loudnessPower[bin]
+			blocks += m.loudnessBlocks[bin]
+		}
+	}
+	if blocks == 0 {
+		return absoluteGateLUFS
+	}
+	return blockLoudness(power / float64(blocks))
+}
+
+// Level of the loud frames over the level of the quiet ones, the noise floor
+func (m *qualityMeter) snr() float64 {
+	var frames int64
+	for _, count := range m.frameLevels {
+		frames += count
+	}
+	if frames == 0 {
+		return 0
+	}
+	return max(m.levelPercentile(frames, signalPercentile)-m.levelPercentile(frames, noisePercentile), 0)
+}
+
+func (m *qualityMeter) levelPercentile(frames int64, percentile float64) float64 {
+	target := int64(math.Ceil(float64(frames) * percentile / 100))
+	var seen int64
+	for bin, count := range m.frameLevels {
+		seen += count
+		if seen >= max(target, 1) {
+			return silentDBFS + float64(bin) + 0.5
+		}
+	}
+	return 0
+}
+
+func qualityWarnings(quality *resources.AudioQuality) []string {
+	warnings := []string{}
+	mostlySilent := quality.SilencePercent >= cnfgs.MostlySilentPercent
+	if mostlySilent {
+		warnings = append(warnings, "recording was mostly silent")
+	}
+	switch {
+	case quality.ClippingPercent >= cnfgs.HeavyClippingPercent:
+		warnings = append(warnings, "heavy clipping detected")
+	case quality.ClippingPercent >= cnfgs.SomeClippingPercent:
+		warnings = append(warnings, "some clipping detected")
+	}
+	// Quiet and noisy is expected of mostly silent audio
+	if !mostlySilent && quality.LoudnessLUFS < cnfgs.QuietLoudnessLUFS {
+		warnings = append(warnings, "recording is very quiet")
+	}
+	if !mostlySilent && quality.SNRDB < cnfgs.NoisySNRDB {
+		warnings = append(warnings, "background noise is loud compared to the speech")
+	}
+	return warnings
+}
+
+func blockLoudness(power float64) float64 {
+	return -0.691 + 10*math.Log10(power)
+}
+
+func round2(value float64) float64 {
+	return math.Round(value*100) / 100
+}
+
+// Second order IIR filter
+type biquad struct {
+	b0, b1, b2, a1, a2 float64
+	x1, x2, y1, y2     float64
+}
+
+func (f *biquad) process(x float64) float64 {
+	y := f.b0*x + f.b1*f.x1 + f.b2*f.x2 - f.a1*f.y1 - f.a2*f.y2
+	f.x2, f.x1 = f.x1, x
+	f.y2, f.y1 = f.y1, y
+	return y
+}
+
+// The two stages of the BS.1770 K-weighting at the sample rate:
+// a +4 dB high shelf modelling the head and a high-pass at 38 Hz
+func kWeighting(sampleRate float64) (shelf, highPass biquad) {
+	w0 := 2 * math.Pi * 1500 / sampleRate
+	a := math.Pow(10, 4.0/40)
+	alpha := math.Sin(w0) / (2 / math.Sqrt2)
+	cos := math.Cos(w0)
+	a0 := (a + 1) - (a-1)*cos + 2*math.Sqrt(a)*alpha
+	shelf = biquad{
+		b0: a * ((a + 1) + (a-1)*cos + 2*math.Sqrt(a)*alpha) / a0,
+		b1: -2 * a * ((a - 1) + (a+1)*cos) / a0,
+		b2: a * ((a + 1) + (a-1)*cos - 2*math.Sqrt(a)*alpha) / a0,
+		a1: 2 * ((a - 1) - (a+1)*cos) / a0,
+		a2: ((a + 1) - (a-1)*cos - 2*math.Sqrt(a)*alpha) / a0,
+	}
+
+	w0 = 2 * math.Pi * 38 / sampleRate
+	alpha = math.Sin(w0) / (2 * 0.5)
+	cos = math.Cos(w0)
+	a0 = 1 + alpha
+	highPass = biquad{
+		b0: (1 + cos) / 2 / a0,
+		b1: -(1 + cos) / a0,
+		b2: (1 + cos) / 2 / a0,
+		a1: -2 * cos / a0,
+		a2: (1 - alpha) / a0,
+	}
+	return shelf, highPass
+}
@@ -0,0 +1,180 @@
+package audio
+
+import (
+	cnfgs "eavesdropper/configurations"
+	"eavesdropper/dtos/resources"
+	"encoding/binary"
+	"io"
+	"math"
+	"time"
+)
+
+const (
+	speechFrameDuration = 30 * time.Millisecond
+	speechFrameBytes    = int(SampleRate*speechFrameDuration/time.Second) * Channels * BitsPerSample / 8
+)
+
+// Voice activity pass over the PCM written into it, in the final audio format.
+// Frames louder than cnfgs.SpeechThresholdDBFS are speech, quieter spans longer than cnfgs.MinNonSpeech are non-speech.
+// Everything is written to out, except the non-speech spans when trimming, of which only
+// cnfgs.NonSpeechPadding on each side is kept. It holds at most cnfgs.MinNonSpeech of audio.
+type speechDetector struct {
+	out  io.Writer
+	trim bool
+
+	partial     []byte
+	frames      int64
+	runStart    int64    // first frame of the current quiet run
+	runFrames   int64    // frames of the current quiet run, 0 while speaking
+	held        [][]byte // quiet frames not written yet when trimming
+	spans       []resources.AudioSpan
+	spansCount  int
+	quietFrames int64 // frames of the non-speech spans
+
+	minRunFrames  int64
+	paddingFrames int
+}
+
+func newSpeechDetector(out io.Writer, trim bool) *speechDetector {
+	return &speechDetector{
+		out:           out,
+		trim:          trim,
+		minRunFrames:  int64(cnfgs.MinNonSpeech / speechFrameDuration),
+		paddingFrames: int(cnfgs.NonSpeechPadding / speechFrameDuration),
+	}
+}
+
+func (d *speechDetector) Write(p []byte) (int, error) {
+	written := len(p)
+	if len(d.partial) > 0 {
+		missing := speechFrameBytes - len(d.partial)
+		if len(p) < missing {
+			d.partial = append(d.partial, p...)
+			return written, nil
+		}
+		d.partial = append(d.partial, p[:missing]...)
+		p = p[missing:]
+		if err := d.frame(d.partial); err != nil {
+			return 0, err
+		}
+		d.partial = d.partial[:0]
+	}
+	for len(p) >= speechFrameBytes {
+		if err := d.frame(p[:speechFrameBytes]); err != nil {
+			return 0, err
+		}
+		p = p[speechFrameBytes:]
+	}
+	d.partial = append(d.partial, p...)
+	return written, nil
+}
+
+// Writes what is left once the audio is over
+func (d *speechDetector) Close() error {
+	if len(d.partial) > 0 {
+		if err := d.frame(d.partial); err != nil {
+			return err
+		}
+		d.partial = nil
+	}
+	if d.runFrames == 0 {
+		return nil
+	}
+	long := d.endRun()
+	if long || !d.trim {
+		return nil // the padding after the last speech is already written
+	}
+	return d.writeHeld()
+}
+
+func (d *speechDetector) frame(frame []byte) error {
+	defer func() { d.frames++ }()
+
+	if isSpeech(frame) {
+		if d.runFrames > 0 {
+			d.endRun()
+			if err := d.writeHeld(); err != nil {
+				return err
+			}
+		}
+		_, err := d.out.Write(frame)
+		return err
+	}
+
+	if d.runFrames == 0 {
+		d.runStart = d.frames
+	}
+	d.runFrames++
+	if !d.trim {
+		_, err := d.out.Write(frame)
+		return err
+	}
+
+	d.held = append(d.held, append([]byte(nil), frame...))
+	if d.runFrames == d.minRunFrames+1 {
+		// The run is non-speech: the padding after the speech is written, the padding before the next speech is held
+		for _, padding := range d.held[:d.paddingFrames] {
+			if _, err := d.out.Write(padding); err != nil {
+				return err
+			}
+		}
+	}
+	if d.runFrames > d.minRunFrames && len(d.held) > d.paddingFrames {
+		d.held = d.held[len(d.held)-d.paddingFrames:]
+	}
+	return nil
+}
+
+// Closes the quiet run, recording it when it is long enough to be non-speech
+func (d *speechDetector) endRun() (long bool) {
+	long = d.runFrames > d.minRunFrames
+	if long {
+		d.quietFrames += d.runFrames
+		d.spansCount++
+		if len(d.spans) < cnfgs.MaxRecordedNonSpeechSpans {
+			d.spans = append(d.spans, resources.AudioSpan{
+				StartSeconds: frameSeconds(d.runStart),
+				EndSeconds:   frameSeconds(d.runStart + d.runFrames),
+			})
+		}
+	}
+	d.runFrames = 0
+	return long
+}
+
+func (d *speechDetector) writeHeld() error {
+	for _, frame := range d.held {
+		if _, err := d.out.Write(frame); err != nil {
+			return err
+		}
+	}
+	d.held = d.held[:0]
+	return nil
+}
+
+// Duration of the non-speech spans
+func (d *speechDetector) nonSpeech() time.Duration {
+	return time.Duration(d.quietFrames) * speechFrameDuration
+}
+
+// A frame is speech when its RMS level is above the threshold
+func isSpeech(frame []byte) bool {
+	samples := len(frame) / 2
+	if samples == 0 {
+		return false
+	}
+	var sum float64
+	for i := 0; i+1 < len(frame); i += 2 {
+		sample := float64(int16(binary.LittleEndian.Uint16(frame[i:])))
+		sum += sample * sample
+	}
+	rms := math.Sqrt(sum / float64(samples))
+	if rms == 0 {
+		return false
+	}
+	return 20*math.Log10(rms/math.MaxInt16) > cnfgs.SpeechThresholdDBFS
+}
+
+func frameSeconds(frame int64) float64 {
+	return (time.Duration(frame) * speechFrameDuration).Seconds()
+}
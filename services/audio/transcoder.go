@@ -0,0 +1,40 @@
+package audio
+
+import (
+	"context"
+	cnfgs "eavesdropper/configurations"
+	"io"
+	"time"
+)
+
+// Runs the external tools that read and convert audio
+type Transcoder interface {
+	// Decodes the input into mono 16kHz 16-bit little endian PCM, written into output.
+	// The input is read from inputPath, or from input when inputPath is empty. Video streams are dropped.
+	// The enhancement profile is applied on the way, EnhancementNone leaves the audio as it is.
+	// When original is not nil the same PCM without the enhancement is written into it too.
+	// When archive is not nil the audio is encoded into it too, in the same pass, as recorded without the enhancement.
+	TranscodeToPCM(
+		ctx context.Context,
+		inputPath string,
+		input io.Reader,
+		enhancement cnfgs.AudioEnhancement,
+		output io.Writer,
+		original io.Writer,
+		archive *EncodedOutput,
+	) error
+	// Encodes the audio of the file from start to end, in the final audio format, with the codec.
+	// A zero end encodes up to the end of the file. inputPath can be a URL, read with range requests.
+	EncodeFile(ctx context.Context, inputPath string, start, end time.Duration, codec cnfgs.AudioCodec, output io.Writer) error
+	// Reads the container, the streams and the duration of the file
+	Probe(ctx context.Context, path string) (*Probe, error)
+}
+
+// Audio encoded with a codec while it is transcoded
+type EncodedOutput struct {
+	Codec  cnfgs.AudioCodec
+	Writer io.Writer
+}
+
+// Transcoder of the audio processing. Fails with errs.ErrTranscoderBusy while every process slot is taken.
+var DefaultTranscoder Transcoder = NewFFmpegTranscoder(cnfgs.MaxTranscoderProcesses)
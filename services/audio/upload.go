@@ -0,0 +1,108 @@
+package audio
+
+import (
+	"context"
+	cnfgs "eavesdropper/configurations"
+	"eavesdropper/errs"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Extensions of the files accepted for upload: audio files and the video containers phones and
+// meeting tools record to. The probe decides, the extension only rejects obviously wrong files early.
+var uploadExtensions = map[string]bool{
+	".mp3": true, ".m4a": true, ".aac": true, ".ogg": true, ".oga": true, ".opus": true,
+	".wav": true, ".flac": true, ".webm": true,
+	".mp4": true, ".mov": true, ".mkv": true, ".avi": true,
+}
+
+// ffprobe format names of the accepted containers
+var uploadFormats = map[string]bool{
+	"mp3": true, "mov": true, "mp4": true, "m4a": true, "3gp": true, "aac": true, "ogg": true,
+	"wav": true, "flac": true, "matroska": true, "webm": true, "avi": true,
+}
+
+func IsSupportedUploadExtension(fileName string) bool {
+	return uploadExtensions[strings.ToLower(filepath.Ext(fileName))]
+}
+
+// What is read from an uploaded file
+type Probe struct {
+	FormatName      string // comma separated aliases, like "mov,mp4,m4a,3gp,3g2,mj2"
+	DurationSeconds float64
+	HasAudio        bool
+	WAV             *WAVInfo // exact format of wav files, nil for other containers
+}
+
+// Wav files are read natively for a sample accurate duration, ffprobe reads the other containers
+func ProbeFile(ctx context.Context, path string) (*Probe, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var magic [4]byte
+	if _, err := io.ReadFull(file, magic[:]); err == nil && (string(magic[:]) == "RIFF" || string(magic[:]) == "RF64") {
+		stat, err := file.Stat()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			return nil, err
+		}
+		info, err := ReadWAVInfo(file, stat.Size())
+		if err != nil {
+			return nil, err
+		}
+		return &Probe{FormatName: "wav", DurationSeconds: info.Seconds(), HasAudio: info.Frames > 0, WAV: info}, nil
+	}
+
+	return DefaultTranscoder.Probe(ctx, path)
+}
+
+// Checks the probed file is an accepted container with an audio stream of a duration gemini accepts
+func (p *Probe) Validate() error {
+	supported := false
+	for _, name := range strings.Split(p.FormatName, ",") {
+		if uploadFormats[name] {
+			supported = true
+		}
+	}
+	if !supported {
+		return fmt.Errorf("%w: unsupported format %s", errs.ErrUnsupportedAudioFile, p.FormatName)
+	}
+	if !p.HasAudio {
+		return fmt.Errorf("%w: the file has no audio", errs.ErrUnsupportedAudioFile)
+	}
+	if p.DurationSeconds <= 0 {
+		return fmt.Errorf("%w: the audio is empty", errs.ErrUnsupportedAudioFile)
+	}
+	if p.DurationSeconds > cnfgs.GeminiAudioInputMaxSeconds {
+		return fmt.Errorf("%w: the audio is longer than %.1f hours", errs.ErrUnsupportedAudioFile, cnfgs.GeminiAudioInputMaxSeconds/3600)
+	}
+	return nil
+}
+
+// Validates an uploaded file and turns it into the final audio of the session, like ProcessAudioChunks
+// does with the recorded chunks. No manifest is involved.
+func ProcessUploadedFile(
+	ctx context.Context,
+	uploadedPath string,
+	sessionID string,
+	userID string,
+	options ProcessingOptions,
+) (*PreparedAudio, error) {
+	probe, err := ProbeFile(ctx, uploadedPath)
+	if err != nil {
+		return nil, err
+	}
+	if err := probe.Validate(); err != nil {
+		return nil, err
+	}
+
+	return transcodeAndArchive(ctx, uploadedPath, nil, userID, sessionID, options)
+}
@@ -0,0 +1,173 @@
+package audio
+
+import (
+	"eavesdropper/errs"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+// Format of the final audio: mono 16kHz 16-bit PCM
+const (
+	SampleRate     = 16000
+	Channels       = 1
+	BitsPerSample  = 16
+	BytesPerSecond = SampleRate * Channels * BitsPerSample / 8
+
+	wavHeaderSize = 44
+	// Chunk size of the streamed wav headers, read as "until the end of the file"
+	unknownWAVSize = math.MaxUint32
+)
+
+// Canonical 44 bytes header of a wav file with dataSize bytes of PCM in the final audio format.
+// Headers streamed before the audio is complete use unknownWAVSize.
+func wavHeader(dataSize uint32) []byte {
+	riffSize := uint32(unknownWAVSize)
+	if dataSize != unknownWAVSize {
+		riffSize = dataSize + wavHeaderSize - 8
+	}
+
+	header := make([]byte, 0, wavHeaderSize)
+	header = append(header, "RIFF"...)
+	header = binary.LittleEndian.AppendUint32(header, riffSize)
+	header = append(header, "WAVEfmt "...)
+	header = binary.LittleEndian.AppendUint32(header, 16) // fmt chunk size
+	header = binary.LittleEndian.AppendUint16(header, 1)  // PCM
+	header = binary.LittleEndian.AppendUint16(header, Channels)
+	header = binary.LittleEndian.AppendUint32(header, SampleRate)
+	header = binary.LittleEndian.AppendUint32(header, BytesPerSecond)
+	header = binary.LittleEndian.AppendUint16(header, Channels*BitsPerSample/8) // block align
+	header = binary.LittleEndian.AppendUint16(header, BitsPerSample)
+	header = append(header, "data"...)
+	header = binary.LittleEndian.AppendUint32(header, dataSize)
+	return header
+}
+
+// What the header of a wav file says about its audio
+type WAVInfo struct {
+	AudioFormat   uint16 // 1 for PCM, 3 for IEEE float, 0xFFFE for extensible
+	Channels      int
+	SampleRate    int
+	BitsPerSample int
+	DataOffset    int64 // where the samples start
+	DataSize      int64 // bytes of samples
+	Frames        int64 // samples per channel
+	Duration      time.Duration
+}
+
+func (i *WAVInfo) Seconds() float64 {
+	return float64(i.Frames) / float64(i.SampleRate)
+}
+
+// Reads the RIFF chunks of a wav (or RF64) file up to its data chunk. Nothing after the header is read.
+// fileSize is the size of the whole file, used when the header was streamed with unknown sizes, -1 when unknown.
+// Fails with errs.ErrUnsupportedAudioFile when the file is not a wav file or its header is broken.
+func ReadWAVInfo(r io.Reader, fileSize int64) (*WAVInfo, error) {
+	var riff [12]byte
+	if _, err := io.ReadFull(r, riff[:]); err != nil {
+		return nil, fmt.Errorf("%w: wav header too short", errs.ErrUnsupportedAudioFile)
+	}
+	kind := string(riff[0:4])
+	if (kind != "RIFF" && kind != "RF64") || string(riff[8:12]) != "WAVE" {
+		return nil, fmt.Errorf("%w: not a wav file", errs.ErrUnsupportedAudioFile)
+	}
+
+	info := &WAVInfo{}
+	offset := int64(len(riff))
+	rf64DataSize := int64(-1)
+	hasFormat := false
+	for {
+		var chunkHeader [8]byte
+		if _, err := io.ReadFull(r, chunkHeader[:]); err != nil {
+			return nil, fmt.Errorf("%w: wav file without a data chunk", errs.ErrUnsupportedAudioFile)
+		}
+		offset += int64(len(chunkHeader))
+		id := string(chunkHeader[0:4])
+		size := int64(binary.LittleEndian.Uint32(chunkHeader[4:8]))
+
+		switch id {
+		case "ds64": // sizes of RF64 files, which do not fit in 32 bits
+			var ds64 [16]byte
+			if size < int64(len(ds64)) {
+				return nil, fmt.Errorf("%w: short ds64 chunk", errs.ErrUnsupportedAudioFile)
+			}
+			if _, err := io.ReadFull(r, ds64[:]); err != nil {
+				return nil, fmt.Errorf("%w: short ds64 chunk", errs.ErrUnsupportedAudioFile)
+			}
+			rf64DataSize = int64(binary.LittleEndian.Uint64(ds64[8:16]))
+			if err := skip(r, size-int64(len(ds64))); err != nil {
+				return nil, err
+			}
+		case "fmt ":
+			var format [16]byte
+			if size < int64(len(format)) {
+				return nil, fmt.Errorf("%w: short fmt chunk", errs.ErrUnsupportedAudioFile)
+			}
+			if _, err := io.ReadFull(r, format[:]); err != nil {
+				return nil, fmt.Errorf("%w: short fmt chunk", errs.ErrUnsupportedAudioFile)
+			}
+			info.AudioFormat = binary.LittleEndian.Uint16(format[0:2])
+			info.Channels = int(binary.LittleEndian.Uint16(format[2:4]))
+			info.SampleRate = int(binary.LittleEndian.Uint32(format[4:8]))
+			info.BitsPerSample = int(binary.LittleEndian.Uint16(format[14:16]))
+			hasFormat = true
+			if err := skip(r, size-int64(len(format))+size%2); err != nil {
+				return nil, err
+			}
+		case "data":
+			if !hasFormat {
+				return nil, fmt.Errorf("%w: data chunk before the fmt chunk", errs.ErrUnsupportedAudioFile)
+			}
+			info.DataOffset = offset
+			info.DataSize = size
+			if kind == "RF64" && size == unknownWAVSize && rf64DataSize >= 0 {
+				info.DataSize = rf64DataSize
+			}
+			// Streamed headers do not know the size, the samples run to the end of the file
+			if size == unknownWAVSize && (kind == "RIFF" || rf64DataSize < 0) {
+				if fileSize < 0 {
+					return nil, fmt.Errorf("%w: wav data size unknown", errs.ErrUnsupportedAudioFile)
+				}
+				info.DataSize = fileSize - offset
+			}
+			if fileSize >= 0 && info.DataOffset+info.DataSize > fileSize {
+				info.DataSize = fileSize - info.DataOffset // truncated file
+			}
+			return info, info.computeFrames()
+		default: // LIST, fact, cue and others
+			if err := skip(r, size+size%2); err != nil {
+				return nil, err
+			}
+		}
+		offset += size + size%2
+	}
+}
+
+func (i *WAVInfo) computeFrames() error {
+	if i.Channels <= 0 || i.SampleRate <= 0 || i.BitsPerSample <= 0 || i.BitsPerSample%8 != 0 {
+		return fmt.Errorf("%w: invalid wav format", errs.ErrUnsupportedAudioFile)
+	}
+	frameSize := int64(i.Channels * i.BitsPerSample / 8)
+	i.Frames = i.DataSize / frameSize
+	rate := int64(i.SampleRate)
+	i.Duration = time.Duration(i.Frames/rate)*time.Second + time.Duration(i.Frames%rate)*time.Second/time.Duration(rate)
+	return nil
+}
+
+// Duration of PCM bytes in the final audio format
+func pcmDuration(bytes int64) time.Duration {
+	frames := bytes / (Channels * BitsPerSample / 8)
+	return time.Duration(frames/SampleRate)*time.Second + time.Duration(frames%SampleRate)*time.Second/SampleRate
+}
+
+func skip(r io.Reader, n int64) error {
+	if n <= 0 {
+		return nil
+	}
+	if _, err := io.CopyN(io.Discard, r, n); err != nil {
+		return fmt.Errorf("%w: truncated wav header", errs.ErrUnsupportedAudioFile)
+	}
+	return nil
+}
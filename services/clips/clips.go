@@ -0,0 +1,219 @@
+package clips
+
+import (
+	"context"
+	cnfgs "eavesdropper/configurations"
+	"eavesdropper/dtos/requests"
+	"eavesdropper/dtos/resources"
+	"eavesdropper/errs"
+	"eavesdropper/services/audio"
+	db "eavesdropper/services/data/firestore/operations"
+	cloudStorage "eavesdropper/services/data/storage"
+	"eavesdropper/services/transcripts"
+	"fmt"
+	"log"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+// Cuts a clip out of the audio of the user's transcript, between the offsets or around a segment, and stores it
+func CreateClip(ctx context.Context, userID, transcriptID string, req *requests.CreateAudioClip) (*resources.AudioClip, error) {
+	lifetime := cnfgs.DefaultClipLifetime
+	if req.ExpiresInHours != nil {
+		lifetime = time.Duration(*req.ExpiresInHours) * time.Hour
+		if lifetime <= 0 || lifetime > cnfgs.MaxClipLifetime {
+			return nil, fmt.Errorf("%w: expiresInHours must be between 1 and %d", errs.ErrInvalidAudioClip, int(cnfgs.MaxClipLifetime.Hours()))
+		}
+	}
+
+	transcript, err := db.GetUserTranscript(ctx, userID, transcriptID)
+	if err != nil {
+		return nil, err
+	}
+	if transcript.TrashedAt != nil {
+		return nil, errs.ErrTranscriptNotFound
+	}
+	if db.TranscriptAudioSession(transcript) == "" {
+		return nil, errs.ErrArchivedAudioNotFound
+	}
+	if transcript.IsPrivate && req.IsPrivate != nil && !*req.IsPrivate {
+		return nil, fmt.Errorf("%w: clips of a private transcript are private", errs.ErrInvalidAudioClip)
+	}
+
+	now := time.Now()
+	clip := &resources.AudioClip{
+		ID:                 uuid.NewString(),
+		TranscriptID:       transcript.ID,
+		RecordingSessionID: db.TranscriptAudioSession(transcript),
+		IsPrivate:          req.IsPrivate == nil || *req.IsPrivate,
+		ExpiresAt:          now.Add(lifetime),
+		CreatedAt:          now,
+	}
+	switch {
+	case req.SegmentIndex != nil && (req.StartSeconds != nil || req.EndSeconds != nil):
+		return nil, fmt.Errorf("%w: give either the offsets or the segment", errs.ErrInvalidAudioClip)
+	case req.SegmentIndex != nil:
+		clip.StartSeconds, clip.EndSeconds, err = segmentRange(transcript, *req.SegmentIndex)
+		if err != nil {
+			return nil, err
+		}
+		clip.SegmentIndex = req.SegmentIndex
+		clip.Estimated = true
+	case req.StartSeconds != nil && req.EndSeconds != nil:
+		clip.StartSeconds, clip.EndSeconds = *req.StartSeconds, *req.EndSeconds
+	default:
+		return nil, fmt.Errorf("%w: startSeconds and endSeconds, or segmentIndex, are required", errs.ErrInvalidAudioClip)
+	}
+	clip.EndSeconds, err = validateRange(clip.StartSeconds, clip.EndSeconds, audioDuration(transcript))
+	if err != nil {
+		return nil, err
+	}
+
+	encoded, err := audio.ExtractClip(ctx, userID, clip.RecordingSessionID, clip.ID, seconds(clip.StartSeconds), seconds(clip.EndSeconds))
+	if err != nil {
+		return nil, err
+	}
+	clip.StoragePath = encoded.StoragePath
+	clip.ContentType = encoded.ContentType
+	clip.Size = encoded.Size
+
+	if err := db.CreateAudioClip(ctx, userID, clip); err != nil {
+		if err := cloudStorage.Delete(context.Background(), clip.StoragePath); err != nil {
+			log.Printf("[Clips] failed to delete the audio of clip %s: %s", clip.ID, err)
+		}
+		return nil, err
+	}
+	return clip, nil
+}
+
+// Clips of the user's transcript that did not expire, newest first
+func GetTranscriptClips(ctx context.Context, userID, transcriptID string) ([]resources.AudioClip, error) {
+	transcript, err := db.GetUserTranscript(ctx, userID, transcriptID)
+	if err != nil {
+		return nil, err
+	}
+	if transcript.TrashedAt != nil {
+		return nil, errs.ErrTranscriptNotFound
+	}
+
+	clips, err := db.GetTranscriptAudioClips(ctx, userID, transcriptID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	live := []resources.AudioClip{}
+	for _, clip := range clips {
+		if clip.ExpiresAt.After(now) {
+			live = append(live, clip)
+		}
+	}
+	return live, nil
+}
+
+// Gets the clip with the ID of its owner. Expired clips not deleted yet are not found either.
+// Whether the caller may play it is up to the visibility of the clip and the transcript.
+func GetClip(ctx context.Context, clipID string) (*resources.AudioClip, string, error) {
+	clip, ownerID, err := db.GetAudioClipByID(ctx, clipID)
+	if err != nil {
+		return nil, "", err
+	}
+	if !clip.ExpiresAt.After(time.Now()) {
+		return nil, "", errs.ErrAudioClipNotFound
+	}
+	return clip, ownerID, nil
+}
+
+// Deletes the clip of the user and its audio
+func DeleteClip(ctx context.Context, userID, clipID string) error {
+	clip, err := db.GetUserAudioClip(ctx, userID, clipID)
+	if err != nil {
+		return err
+	}
+	if err := cloudStorage.Delete(ctx, clip.StoragePath); err != nil {
+		return fmt.Errorf("failed to delete the clip audio: %w", err)
+	}
+	return db.DeleteAudioClip(ctx, userID, clipID)
+}
+
+// Checks the range is long enough, short enough and inside the audio. Returns the end, cut at the end of the audio.
+// duration is 0 when it is unknown.
+func validateRange(start, end, duration float64) (float64, error) {
+	if start < 0 || end <= start {
+		return 0, fmt.Errorf("%w: the clip must start at 0 or later and end after it starts", errs.ErrInvalidAudioClip)
+	}
+	if duration > 0 {
+		if start >= duration {
+			return 0, fmt.Errorf("%w: the clip starts after the end of the audio", errs.ErrInvalidAudioClip)
+		}
+		end = min(end, duration)
+	}
+	length := seconds(end - start)
+	if length < cnfgs.MinClipDuration || length > cnfgs.MaxClipDuration {
+		return 0, fmt.Errorf("%w: clips last between %s and %s", errs.ErrInvalidAudioClip, cnfgs.MinClipDuration, cnfgs.MaxClipDuration)
+	}
+	return end, nil
+}
+
+// Time range of the segment. The transcript has no timestamps, so it is estimated from the share of the content
+// before and in the segment, spread over the speech: the non-speech spans are skipped, nothing is said in them.
+// Padded with cnfgs.SegmentClipPadding on each side.
+func segmentRange(transcript *resources.Transcript, index int) (float64, float64, error) {
+	segments := transcripts.ParseSegments(transcript.Content)
+	if index < 0 || index >= len(segments) {
+		return 0, 0, fmt.Errorf("%w: the transcript has no segment %d", errs.ErrInvalidAudioClip, index)
+	}
+	duration := audioDuration(transcript)
+	if duration <= 0 {
+		return 0, 0, fmt.Errorf("%w: the audio duration of the transcript is unknown, give the offsets", errs.ErrInvalidAudioClip)
+	}
+
+	var before, length, total int
+	for i, segment := range segments {
+		characters := utf8.RuneCountInString(segment.Text)
+		if i < index {
+			before += characters
+		}
+		if i == index {
+			length = characters
+		}
+		total += characters
+	}
+	if total == 0 {
+		return 0, 0, fmt.Errorf("%w: the transcript has no text", errs.ErrInvalidAudioClip)
+	}
+
+	speech := transcript.SpeechSeconds
+	if speech <= 0 {
+		speech = duration
+	}
+	padding := cnfgs.SegmentClipPadding.Seconds()
+	start := audioTime(speech*float64(before)/float64(total), transcript.NonSpeechSpans) - padding
+	end := audioTime(speech*float64(before+length)/float64(total), transcript.NonSpeechSpans) + padding
+	return max(start, 0), min(end, duration), nil
+}
+
+// Time in the audio of a time in the speech, adding the non-speech spans before it
+func audioTime(speechSeconds float64, nonSpeechSpans []resources.AudioSpan) float64 {
+	at := speechSeconds
+	for _, span := range nonSpeechSpans {
+		if span.StartSeconds > at {
+			break
+		}
+		at += span.EndSeconds - span.StartSeconds
+	}
+	return at
+}
+
+// Exact duration of the transcript audio, the billed one on transcripts saved before it was measured
+func audioDuration(transcript *resources.Transcript) float64 {
+	if transcript.AudioDurationSeconds > 0 {
+		return transcript.AudioDurationSeconds
+	}
+	return float64(transcript.ConsumedInputAudioSeconds)
+}
+
+func seconds(value float64) time.Duration {
+	return time.Duration(value * float64(time.Second))
+}
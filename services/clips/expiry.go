@@ -0,0 +1,57 @@
+package clips
+
+import (
+	"context"
+	cnfgs "eavesdropper/configurations"
+	db "eavesdropper/services/data/firestore/operations"
+	cloudStorage "eavesdropper/services/data/storage"
+	"log"
+	"time"
+)
+
+// Clips deleted per run, the rest wait for the next run
+const expiryBatchSize = 100
+
+// Deletes the clips whose expiration passed and their audio. Returns how many were deleted.
+func DeleteExpiredClips(ctx context.Context) (int, error) {
+	expired, err := db.GetExpiredAudioClips(ctx, time.Now(), expiryBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	deleted := 0
+	for _, owned := range expired {
+		if err := cloudStorage.Delete(ctx, owned.Clip.StoragePath); err != nil {
+			log.Printf("[Clips] failed to delete the audio of clip %s: %s", owned.Clip.ID, err)
+			continue
+		}
+		if err := db.DeleteAudioClip(ctx, owned.OwnerID, owned.Clip.ID); err != nil {
+			log.Printf("[Clips] failed to delete clip %s: %s", owned.Clip.ID, err)
+			continue
+		}
+		deleted++
+	}
+
+	return deleted, nil
+}
+
+// Runs DeleteExpiredClips every cnfgs.ClipExpiryInterval until the context is cancelled
+func RunClipExpiry(ctx context.Context) {
+	ticker := time.NewTicker(cnfgs.ClipExpiryInterval)
+	defer ticker.Stop()
+
+	for {
+		deleted, err := DeleteExpiredClips(ctx)
+		if err != nil {
+			log.Printf("[Clips] expiry failed: %s", err)
+		} else if deleted > 0 {
+			log.Printf("[Clips] deleted %d expired clips", deleted)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
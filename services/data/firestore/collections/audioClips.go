@@ -0,0 +1,29 @@
+package collections
+
+import (
+	cnfgs "eavesdropper/configurations"
+
+	"cloud.google.com/go/firestore"
+)
+
+// // Audio clips (Subcollection of Users) ////
+const audioClipsCollectionID = "audioClips"
+const audioClipsTestingCollectionID = "audioClipsTest"
+
+var AllAudioClips = getAllAudioClipsCollections()
+
+func AudioClips(userID string) *firestore.CollectionRef {
+	collectionID := audioClipsTestingCollectionID
+	if cnfgs.SelectedBackendMode == cnfgs.Production {
+		collectionID = audioClipsCollectionID
+	}
+	return Users.Doc(userID).Collection(collectionID)
+}
+
+func getAllAudioClipsCollections() *firestore.CollectionGroupRef {
+	collectionID := audioClipsTestingCollectionID
+	if cnfgs.SelectedBackendMode == cnfgs.Production {
+		collectionID = audioClipsCollectionID
+	}
+	return db.CollectionGroup(collectionID)
+}
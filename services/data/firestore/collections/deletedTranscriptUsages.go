@@ -0,0 +1,19 @@
+package collections
+
+import (
+	cnfgs "eavesdropper/configurations"
+
+	"cloud.google.com/go/firestore"
+)
+
+// // Deleted transcript usages (Subcollection of Users) ////
+const deletedTranscriptUsagesCollectionID = "deletedTranscriptUsages"
+const deletedTranscriptUsagesTestingCollectionID = "deletedTranscriptUsagesTest"
+
+func DeletedTranscriptUsages(userID string) *firestore.CollectionRef {
+	collectionID := deletedTranscriptUsagesTestingCollectionID
+	if cnfgs.SelectedBackendMode == cnfgs.Production {
+		collectionID = deletedTranscriptUsagesCollectionID
+	}
+	return Users.Doc(userID).Collection(collectionID)
+}
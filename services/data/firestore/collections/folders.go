@@ -0,0 +1,19 @@
+package collections
+
+import (
+	cnfgs "eavesdropper/configurations"
+
+	"cloud.google.com/go/firestore"
+)
+
+// // Folders (Subcollection of Users) ////
+const foldersCollectionID = "folders"
+const foldersTestingCollectionID = "foldersTest"
+
+func Folders(userID string) *firestore.CollectionRef {
+	collectionID := foldersTestingCollectionID
+	if cnfgs.SelectedBackendMode == cnfgs.Production {
+		collectionID = foldersCollectionID
+	}
+	return Users.Doc(userID).Collection(collectionID)
+}
@@ -0,0 +1,21 @@
+package collections
+
+import (
+	cnfgs "eavesdropper/configurations"
+
+	"cloud.google.com/go/firestore"
+)
+
+const migrationsCollectionID = "migrations"
+const migrationsTestingCollectionID = "migrationsTest"
+
+// One document per data migration that completed, named after it
+var Migrations = getMigrationsCollection()
+
+func getMigrationsCollection() *firestore.CollectionRef {
+	collectionID := migrationsTestingCollectionID
+	if cnfgs.SelectedBackendMode == cnfgs.Production {
+		collectionID = migrationsCollectionID
+	}
+	return db.Collection(collectionID)
+}
@@ -0,0 +1,29 @@
+package collections
+
+import (
+	cnfgs "eavesdropper/configurations"
+
+	"cloud.google.com/go/firestore"
+)
+
+// // Recording sessions (Subcollection of Users) ////
+const recordingSessionsCollectionID = "recordingSessions"
+const recordingSessionsTestingCollectionID = "recordingSessionsTest"
+
+var AllRecordingSessions = getAllRecordingSessionsCollections()
+
+func RecordingSessions(userID string) *firestore.CollectionRef {
+	collectionID := recordingSessionsTestingCollectionID
+	if cnfgs.SelectedBackendMode == cnfgs.Production {
+		collectionID = recordingSessionsCollectionID
+	}
+	return Users.Doc(userID).Collection(collectionID)
+}
+
+func getAllRecordingSessionsCollections() *firestore.CollectionGroupRef {
+	collectionID := recordingSessionsTestingCollectionID
+	if cnfgs.SelectedBackendMode == cnfgs.Production {
+		collectionID = recordingSessionsCollectionID
+	}
+	return db.CollectionGroup(collectionID)
+}
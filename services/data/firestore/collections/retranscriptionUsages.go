@@ -0,0 +1,19 @@
+package collections
+
+import (
+	cnfgs "eavesdropper/configurations"
+
+	"cloud.google.com/go/firestore"
+)
+
+// // Re-transcription usages (Subcollection of Users) ////
+const retranscriptionUsagesCollectionID = "retranscriptionUsages"
+const retranscriptionUsagesTestingCollectionID = "retranscriptionUsagesTest"
+
+func RetranscriptionUsages(userID string) *firestore.CollectionRef {
+	collectionID := retranscriptionUsagesTestingCollectionID
+	if cnfgs.SelectedBackendMode == cnfgs.Production {
+		collectionID = retranscriptionUsagesCollectionID
+	}
+	return Users.Doc(userID).Collection(collectionID)
+}
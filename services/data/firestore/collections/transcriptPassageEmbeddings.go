@@ -0,0 +1,19 @@
+package collections
+
+import (
+	cnfgs "eavesdropper/configurations"
+
+	"cloud.google.com/go/firestore"
+)
+
+// // Transcript passage embeddings (Subcollection of Users) ////
+const transcriptPassageEmbeddingsCollectionID = "transcriptPassageEmbeddings"
+const transcriptPassageEmbeddingsTestingCollectionID = "transcriptPassageEmbeddingsTest"
+
+func TranscriptPassageEmbeddings(userID string) *firestore.CollectionRef {
+	collectionID := transcriptPassageEmbeddingsTestingCollectionID
+	if cnfgs.SelectedBackendMode == cnfgs.Production {
+		collectionID = transcriptPassageEmbeddingsCollectionID
+	}
+	return Users.Doc(userID).Collection(collectionID)
+}
@@ -0,0 +1,19 @@
+package collections
+
+import (
+	cnfgs "eavesdropper/configurations"
+
+	"cloud.google.com/go/firestore"
+)
+
+// // Transcript revisions (Subcollection of Transcripts) ////
+const transcriptRevisionsCollectionID = "transcriptRevisions"
+const transcriptRevisionsTestingCollectionID = "transcriptRevisionsTest"
+
+func TranscriptRevisions(userID, transcriptID string) *firestore.CollectionRef {
+	collectionID := transcriptRevisionsTestingCollectionID
+	if cnfgs.SelectedBackendMode == cnfgs.Production {
+		collectionID = transcriptRevisionsCollectionID
+	}
+	return Transcripts(userID).Doc(transcriptID).Collection(collectionID)
+}
@@ -0,0 +1,19 @@
+package collections
+
+import (
+	cnfgs "eavesdropper/configurations"
+
+	"cloud.google.com/go/firestore"
+)
+
+// // Transcript search indexes (Subcollection of Users) ////
+const transcriptSearchIndexesCollectionID = "transcriptSearchIndexes"
+const transcriptSearchIndexesTestingCollectionID = "transcriptSearchIndexesTest"
+
+func TranscriptSearchIndexes(userID string) *firestore.CollectionRef {
+	collectionID := transcriptSearchIndexesTestingCollectionID
+	if cnfgs.SelectedBackendMode == cnfgs.Production {
+		collectionID = transcriptSearchIndexesCollectionID
+	}
+	return Users.Doc(userID).Collection(collectionID)
+}
@@ -0,0 +1,19 @@
+package collections
+
+import (
+	cnfgs "eavesdropper/configurations"
+
+	"cloud.google.com/go/firestore"
+)
+
+// // Transcription jobs (Subcollection of Users) ////
+const transcriptionJobsCollectionID = "transcriptionJobs"
+const transcriptionJobsTestingCollectionID = "transcriptionJobsTest"
+
+func TranscriptionJobs(userID string) *firestore.CollectionRef {
+	collectionID := transcriptionJobsTestingCollectionID
+	if cnfgs.SelectedBackendMode == cnfgs.Production {
+		collectionID = transcriptionJobsCollectionID
+	}
+	return Users.Doc(userID).Collection(collectionID)
+}
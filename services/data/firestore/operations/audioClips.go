@@ -0,0 +1,129 @@
+package operations
+
+import (
+	"context"
+	"eavesdropper/dtos/resources"
+	"eavesdropper/errs"
+	"eavesdropper/services/data/firestore/collections"
+	"fmt"
+	"sort"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func CreateAudioClip(ctx context.Context, userID string, clip *resources.AudioClip) error {
+	_, err := collections.AudioClips(userID).Doc(clip.ID).Create(ctx, clip)
+	if err != nil {
+		return fmt.Errorf("failed to create audio clip: %w", err)
+	}
+	return nil
+}
+
+func GetUserAudioClip(ctx context.Context, userID, clipID string) (*resources.AudioClip, error) {
+	doc, err := collections.AudioClips(userID).Doc(clipID).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return nil, errs.ErrAudioClipNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get audio clip: %w", err)
+	}
+	return audioClipFromDoc(doc)
+}
+
+// Finds the clip among the clips of every user, with the ID of its owner.
+// Needs a collection group index exemption on ID (see firestore.indexes.json).
+func GetAudioClipByID(ctx context.Context, clipID string) (*resources.AudioClip, string, error) {
+	iter := collections.AllAudioClips.Where("ID", "==", clipID).Limit(1).Documents(ctx)
+	defer iter.Stop()
+
+	doc, err := iter.Next()
+	if err == iterator.Done {
+		return nil, "", errs.ErrAudioClipNotFound
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to query audio clip: %w", err)
+	}
+	clip, err := audioClipFromDoc(doc)
+	if err != nil {
+		return nil, "", err
+	}
+	return clip, doc.Ref.Parent.Parent.ID, nil
+}
+
+// Clips of the transcript, newest first. Expired clips waiting to be deleted are included.
+func GetTranscriptAudioClips(ctx context.Context, userID, transcriptID string) ([]resources.AudioClip, error) {
+	iter := collections.AudioClips(userID).Where("TranscriptID", "==", transcriptID).Documents(ctx)
+
+	clips := []resources.AudioClip{}
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to list audio clips: %w", err)
+		}
+		clip, err := audioClipFromDoc(doc)
+		if err != nil {
+			return nil, err
+		}
+		clips = append(clips, *clip)
+	}
+
+	// Few clips per transcript, sorted here rather than with a composite index
+	sort.Slice(clips, func(i, j int) bool { return clips[i].CreatedAt.After(clips[j].CreatedAt) })
+	return clips, nil
+}
+
+func DeleteAudioClip(ctx context.Context, userID, clipID string) error {
+	_, err := collections.AudioClips(userID).Doc(clipID).Delete(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to delete audio clip: %w", err)
+	}
+	return nil
+}
+
+// An audio clip of some user
+type OwnedAudioClip struct {
+	OwnerID string
+	Clip    resources.AudioClip
+}
+
+// Clips whose expiration passed, across every user.
+// Needs a collection group index exemption on ExpiresAt (see firestore.indexes.json).
+func GetExpiredAudioClips(ctx context.Context, now time.Time, limit int) ([]OwnedAudioClip, error) {
+	iter := collections.AllAudioClips.
+		Where("ExpiresAt", "<=", now).
+		Limit(limit).
+		Documents(ctx)
+
+	clips := []OwnedAudioClip{}
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		clip, err := audioClipFromDoc(doc)
+		if err != nil {
+			return nil, err
+		}
+		clips = append(clips, OwnedAudioClip{OwnerID: doc.Ref.Parent.Parent.ID, Clip: *clip})
+	}
+
+	return clips, nil
+}
+
+func audioClipFromDoc(doc *firestore.DocumentSnapshot) (*resources.AudioClip, error) {
+	clip := new(resources.AudioClip)
+	if err := doc.DataTo(clip); err != nil {
+		return nil, fmt.Errorf("failed to transfer data from document to model: %w", err)
+	}
+	return clip, nil
+}
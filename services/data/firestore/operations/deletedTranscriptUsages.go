@@ -0,0 +1,121 @@
+package operations
+
+import (
+	"context"
+	"eavesdropper/dtos/resources"
+	"eavesdropper/services/data/firestore/collections"
+	"fmt"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Deletes the transcript document and records its usage, atomically, so billing never loses it.
+// Subcollections are not deleted by Firestore with their parent, see DeleteTranscriptSubcollections.
+// Complimentary transcripts were never billed, their record keeps no usage.
+func DeleteTranscriptKeepingUsage(ctx context.Context, userID string, transcript *resources.Transcript) error {
+	usage := &resources.DeletedTranscriptUsage{
+		TranscriptID:       transcript.ID,
+		RecordingSessionID: TranscriptAudioSession(transcript),
+		Complimentary:      transcript.Complimentary,
+		CreatedAt:          transcript.CreatedAt,
+		DeletedAt:          time.Now(),
+	}
+	if !transcript.Complimentary {
+		usage.ConsumedInputAudioSeconds = transcript.ConsumedInputAudioSeconds
+		usage.ConsumedFreeAudioSeconds = transcript.ConsumedFreeAudioSeconds
+		usage.ConsumedInputTokens = transcript.ConsumedInputTokens
+		usage.ConsumedOutputTokens = transcript.ConsumedOutputTokens
+	}
+
+	batch := dbClient.Batch()
+	batch.Set(collections.DeletedTranscriptUsages(userID).Doc(transcript.ID), usage)
+	batch.Delete(collections.Transcripts(userID).Doc(transcript.ID))
+
+	_, err := batch.Commit(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to delete transcript: %w", err)
+	}
+	return nil
+}
+
+// Returns nil when the transcript was never deleted
+func GetDeletedTranscriptUsage(ctx context.Context, userID, transcriptID string) (*resources.DeletedTranscriptUsage, error) {
+	doc, err := collections.DeletedTranscriptUsages(userID).Doc(transcriptID).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get deleted transcript usage: %w", err)
+	}
+
+	usage := new(resources.DeletedTranscriptUsage)
+	err = doc.DataTo(usage)
+	if err != nil {
+		return nil, fmt.Errorf("failed to transfer data from document to model: %w", err)
+	}
+	return usage, nil
+}
+
+// Usage of the transcripts created in the range and deleted since
+func GetDeletedTranscriptUsages(ctx context.Context, userID string, start, end time.Time) ([]resources.DeletedTranscriptUsage, error) {
+	iter := collections.DeletedTranscriptUsages(userID).
+		Where("CreatedAt", ">=", start).
+		Where("CreatedAt", "<=", end).
+		Documents(ctx)
+
+	usages := []resources.DeletedTranscriptUsage{}
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		usage := new(resources.DeletedTranscriptUsage)
+		err = doc.DataTo(usage)
+		if err != nil {
+			return nil, fmt.Errorf("failed to transfer data from snap to model: %w", err)
+		}
+		usages = append(usages, *usage)
+	}
+
+	return usages, nil
+}
+
+// Deletes the whitelist and the revisions of a transcript
+func DeleteTranscriptSubcollections(ctx context.Context, userID, transcriptID string) error {
+	err := deleteCollection(ctx, collections.TranscriptWhitelistedUsers(userID, transcriptID))
+	if err != nil {
+		return fmt.Errorf("failed to delete transcript whitelist: %w", err)
+	}
+	err = deleteCollection(ctx, collections.TranscriptRevisions(userID, transcriptID))
+	if err != nil {
+		return fmt.Errorf("failed to delete transcript revisions: %w", err)
+	}
+	return nil
+}
+
+func deleteCollection(ctx context.Context, collection *firestore.CollectionRef) error {
+	for {
+		docs, err := collection.Limit(maxBatchWrites).Documents(ctx).GetAll()
+		if err != nil {
+			return err
+		}
+		if len(docs) == 0 {
+			return nil
+		}
+
+		batch := dbClient.Batch()
+		for _, doc := range docs {
+			batch.Delete(doc.Ref)
+		}
+		if _, err := batch.Commit(ctx); err != nil {
+			return err
+		}
+	}
+}
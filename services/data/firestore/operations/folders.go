@@ -0,0 +1,181 @@
+package operations
+
+import (
+	"context"
+	"eavesdropper/dtos/resources"
+	"eavesdropper/errs"
+	"eavesdropper/services/data/firestore/collections"
+	"fmt"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/google/uuid"
+	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func CreateFolder(ctx context.Context, userID, name, parentID string) (*resources.Folder, error) {
+	now := time.Now()
+	folder := &resources.Folder{
+		ID:        uuid.NewString(),
+		Name:      name,
+		ParentID:  parentID,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	_, err := collections.Folders(userID).Doc(folder.ID).Create(ctx, folder)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create folder: %w", err)
+	}
+	return folder, nil
+}
+
+func GetFolder(ctx context.Context, userID, folderID string) (*resources.Folder, error) {
+	doc, err := collections.Folders(userID).Doc(folderID).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return nil, errs.ErrFolderNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get folder: %w", err)
+	}
+
+	folder := new(resources.Folder)
+	err = doc.DataTo(folder)
+	if err != nil {
+		return nil, fmt.Errorf("failed to transfer data from document to model: %w", err)
+	}
+	return folder, nil
+}
+
+// Every folder of the user, by name
+func GetFolders(ctx context.Context, userID string) ([]resources.Folder, error) {
+	iter := collections.Folders(userID).OrderBy("Name", firestore.Asc).Documents(ctx)
+
+	folders := []resources.Folder{}
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		folder := new(resources.Folder)
+		err = doc.DataTo(folder)
+		if err != nil {
+			return nil, fmt.Errorf("failed to transfer data from snap to model: %w", err)
+		}
+		folders = append(folders, *folder)
+	}
+
+	return folders, nil
+}
+
+func UpdateFolder(ctx context.Context, userID string, folder *resources.Folder) error {
+	folder.UpdatedAt = time.Now()
+	_, err := collections.Folders(userID).Doc(folder.ID).Update(ctx, []firestore.Update{
+		{Path: "Name", Value: folder.Name},
+		{Path: "ParentID", Value: folder.ParentID},
+		{Path: "UpdatedAt", Value: folder.UpdatedAt},
+	})
+	if status.Code(err) == codes.NotFound {
+		return errs.ErrFolderNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update folder: %w", err)
+	}
+	return nil
+}
+
+func DeleteFolder(ctx context.Context, userID, folderID string) error {
+	_, err := collections.Folders(userID).Doc(folderID).Delete(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to delete folder: %w", err)
+	}
+	return nil
+}
+
+// IDs of the transcripts in the folder, trashed ones included
+func GetFolderTranscriptIDs(ctx context.Context, userID, folderID string) ([]string, error) {
+	docs, err := collections.Transcripts(userID).
+		Where("FolderID", "==", folderID).
+		Select().
+		Documents(ctx).
+		GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list folder transcripts: %w", err)
+	}
+
+	ids := make([]string, len(docs))
+	for i, doc := range docs {
+		ids[i] = doc.Ref.ID
+	}
+	return ids, nil
+}
+
+// Moves the transcripts to the folder, empty folderID moves them out of any folder.
+// Fails with errs.ErrTranscriptNotFound, without moving any, when one of them does not exist.
+func MoveTranscriptsToFolder(ctx context.Context, userID string, transcriptIDs []string, folderID string) error {
+	return updateTranscripts(ctx, userID, transcriptIDs, []firestore.Update{
+		{Path: "FolderID", Value: folderID},
+	})
+}
+
+func SetTranscriptTags(ctx context.Context, userID, transcriptID string, tags []string) error {
+	return updateTranscripts(ctx, userID, []string{transcriptID}, []firestore.Update{
+		{Path: "Tags", Value: tags},
+	})
+}
+
+// Adds and removes tags on every transcript. A tag in both lists ends up removed.
+func UpdateTranscriptsTags(ctx context.Context, userID string, transcriptIDs []string, add, remove []string) error {
+	// A field can only be changed once per write, so additions and removals are two writes
+	if len(add) > 0 {
+		err := updateTranscripts(ctx, userID, transcriptIDs, []firestore.Update{
+			{Path: "Tags", Value: firestore.ArrayUnion(toInterfaces(add)...)},
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if len(remove) > 0 {
+		return updateTranscripts(ctx, userID, transcriptIDs, []firestore.Update{
+			{Path: "Tags", Value: firestore.ArrayRemove(toInterfaces(remove)...)},
+		})
+	}
+	return nil
+}
+
+// Bulk operations apply to at most this many transcripts, they are written in a single batch
+const MaxBulkTranscripts = maxBatchWrites
+
+// Applies the same update to every transcript in a single batch, so either all or none are updated
+func updateTranscripts(ctx context.Context, userID string, transcriptIDs []string, updates []firestore.Update) error {
+	if len(transcriptIDs) > MaxBulkTranscripts {
+		return fmt.Errorf("cannot update more than %d transcripts at once", MaxBulkTranscripts)
+	}
+
+	batch := dbClient.Batch()
+	for _, transcriptID := range transcriptIDs {
+		batch.Update(collections.Transcripts(userID).Doc(transcriptID), updates)
+	}
+
+	_, err := batch.Commit(ctx)
+	if status.Code(err) == codes.NotFound {
+		return errs.ErrTranscriptNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update transcripts: %w", err)
+	}
+	return nil
+}
+
+func toInterfaces(values []string) []interface{} {
+	result := make([]interface{}, len(values))
+	for i, v := range values {
+		result[i] = v
+	}
+	return result
+}
@@ -0,0 +1,30 @@
+package operations
+
+import (
+	"context"
+	"eavesdropper/services/data/firestore/collections"
+	"fmt"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func IsMigrationDone(ctx context.Context, name string) (bool, error) {
+	_, err := collections.Migrations.Doc(name).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to get migration: %w", err)
+	}
+	return true, nil
+}
+
+func MarkMigrationDone(ctx context.Context, name string) error {
+	_, err := collections.Migrations.Doc(name).Set(ctx, map[string]interface{}{"CompletedAt": time.Now()})
+	if err != nil {
+		return fmt.Errorf("failed to mark migration done: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,193 @@
+package operations
+
+import (
+	"context"
+	cnfgs "eavesdropper/configurations"
+	"eavesdropper/dtos/resources"
+	"eavesdropper/errs"
+	"eavesdropper/services/data/firestore/collections"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/google/uuid"
+	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func CreateRecordingSession(ctx context.Context, userID, mime string, chunkSeconds float64) (*resources.RecordingSession, error) {
+	now := time.Now()
+	expiresAt := now.Add(cnfgs.RecordingSessionIdleTimeout)
+	session := &resources.RecordingSession{
+		ID:           uuid.NewString(),
+		MIME:         mime,
+		Status:       resources.RecordingSessionRecording,
+		ChunkSeconds: chunkSeconds,
+		Chunks:       []resources.RecordingChunk{},
+		CreatedAt:    now,
+		UpdatedAt:    now,
+		ExpiresAt:    &expiresAt,
+	}
+
+	_, err := collections.RecordingSessions(userID).Doc(session.ID).Create(ctx, session)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create recording session: %w", err)
+	}
+	return session, nil
+}
+
+func GetRecordingSession(ctx context.Context, userID, sessionID string) (*resources.RecordingSession, error) {
+	doc, err := collections.RecordingSessions(userID).Doc(sessionID).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return nil, errs.ErrRecordingSessionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get recording session: %w", err)
+	}
+	return recordingSessionFromDoc(doc)
+}
+
+// Returned by a change to leave the session as it is
+var errRecordingSessionUnchanged = errors.New("recording session unchanged")
+
+// Runs the change on the session while it is recording, and pushes its expiration back.
+// Fails with errs.ErrRecordingSessionClosed once it is finalized or expired.
+func updateRecordingSession(
+	ctx context.Context,
+	userID, sessionID string,
+	change func(session *resources.RecordingSession) error,
+) (*resources.RecordingSession, error) {
+	ref := collections.RecordingSessions(userID).Doc(sessionID)
+	updated := new(resources.RecordingSession)
+
+	err := dbClient.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		doc, err := tx.Get(ref)
+		if status.Code(err) == codes.NotFound {
+			return errs.ErrRecordingSessionNotFound
+		}
+		if err != nil {
+			return err
+		}
+		session, err := recordingSessionFromDoc(doc)
+		if err != nil {
+			return err
+		}
+		if session.Status != resources.RecordingSessionRecording {
+			return errs.ErrRecordingSessionClosed
+		}
+
+		err = change(session)
+		if err == errRecordingSessionUnchanged {
+			*updated = *session
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		session.UpdatedAt = time.Now()
+		if session.Status == resources.RecordingSessionRecording {
+			expiresAt := session.UpdatedAt.Add(cnfgs.RecordingSessionIdleTimeout)
+			session.ExpiresAt = &expiresAt
+		} else {
+			session.ExpiresAt = nil
+		}
+
+		*updated = *session
+		return tx.Set(ref, session)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// Keeps a recording session alive, the client is still uploading
+func TouchRecordingSession(ctx context.Context, userID, sessionID string) (*resources.RecordingSession, error) {
+	return updateRecordingSession(ctx, userID, sessionID, func(*resources.RecordingSession) error { return nil })
+}
+
+// Adds the chunk to the session. Registering an index again replaces it, so retried uploads are fine.
+func RegisterRecordingChunk(ctx context.Context, userID, sessionID string, chunk resources.RecordingChunk) (*resources.RecordingSession, error) {
+	return updateRecordingSession(ctx, userID, sessionID, func(session *resources.RecordingSession) error {
+		chunk.RegisteredAt = time.Now()
+		chunks := []resources.RecordingChunk{chunk}
+		for _, registered := range session.Chunks {
+			if registered.Index != chunk.Index {
+				chunks = append(chunks, registered)
+			}
+		}
+		sort.Slice(chunks, func(i, j int) bool { return chunks[i].Index < chunks[j].Index })
+		session.Chunks = chunks
+		return nil
+	})
+}
+
+// Closes the session once its manifest is written. Fails with errs.ErrInvalidRecordingChunk
+// when chunks were registered after the manifest was built from chunkCount of them.
+func FinalizeRecordingSession(ctx context.Context, userID, sessionID string, chunkCount int) (*resources.RecordingSession, error) {
+	return updateRecordingSession(ctx, userID, sessionID, func(session *resources.RecordingSession) error {
+		if len(session.Chunks) != chunkCount {
+			return fmt.Errorf("%w: chunks were registered while finalizing", errs.ErrInvalidRecordingChunk)
+		}
+		session.Status = resources.RecordingSessionFinalized
+		return nil
+	})
+}
+
+// A recording session of some user
+type OwnedRecordingSession struct {
+	OwnerID string
+	Session resources.RecordingSession
+}
+
+// Sessions still recording whose expiration passed, across every user.
+// Needs a collection group index exemption on ExpiresAt (see firestore.indexes.json).
+func GetAbandonedRecordingSessions(ctx context.Context, now time.Time, limit int) ([]OwnedRecordingSession, error) {
+	iter := collections.AllRecordingSessions.
+		Where("ExpiresAt", "<=", now).
+		Limit(limit).
+		Documents(ctx)
+
+	sessions := []OwnedRecordingSession{}
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		session, err := recordingSessionFromDoc(doc)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, OwnedRecordingSession{OwnerID: doc.Ref.Parent.Parent.ID, Session: *session})
+	}
+
+	return sessions, nil
+}
+
+// Marks the session expired, unless an upload kept it alive meanwhile. Returns if it was expired.
+func ExpireRecordingSession(ctx context.Context, userID, sessionID string) (bool, error) {
+	expired := false
+	_, err := updateRecordingSession(ctx, userID, sessionID, func(session *resources.RecordingSession) error {
+		if session.ExpiresAt != nil && session.ExpiresAt.After(time.Now()) {
+			return errRecordingSessionUnchanged
+		}
+		session.Status = resources.RecordingSessionExpired
+		expired = true
+		return nil
+	})
+	return expired, err
+}
+
+func recordingSessionFromDoc(doc *firestore.DocumentSnapshot) (*resources.RecordingSession, error) {
+	session := new(resources.RecordingSession)
+	if err := doc.DataTo(session); err != nil {
+		return nil, fmt.Errorf("failed to transfer data from document to model: %w", err)
+	}
+	return session, nil
+}
@@ -0,0 +1,89 @@
+package operations
+
+import (
+	"context"
+	cnfgs "eavesdropper/configurations"
+	"eavesdropper/dtos/resources"
+	"eavesdropper/errs"
+	"eavesdropper/services/data/firestore/collections"
+	"fmt"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/google/uuid"
+	"google.golang.org/api/iterator"
+)
+
+func createRetranscriptionUsage(tx *firestore.Transaction, userID string, usage *resources.RetranscriptionUsage) error {
+	usage.ID = uuid.NewString()
+	usage.CreatedAt = time.Now()
+
+	err := tx.Create(collections.RetranscriptionUsages(userID).Doc(usage.ID), usage)
+	if err != nil {
+		return fmt.Errorf("failed to save retranscription usage: %w", err)
+	}
+	return nil
+}
+
+// Counts the re-transcriptions of the audio session covered as quality complaints.
+// Their records are kept when the transcripts are deleted, so the count cannot be reset.
+func CountComplaintRetranscriptions(ctx context.Context, userID, audioSessionID string) (int, error) {
+	docs, err := complaintRetranscriptionsQuery(userID, audioSessionID).Documents(ctx).GetAll()
+	if err != nil {
+		return 0, fmt.Errorf("failed to count complaint retranscriptions: %w", err)
+	}
+	return len(docs), nil
+}
+
+// Fails with errs.ErrComplaintRetranscriptionsExceeded when the usage is a complaint and the audio
+// already had cnfgs.MaxComplaintRetranscriptions. Read in the transaction that saves the usage, so concurrent
+// complaints cannot go over the limit.
+func checkComplaintRetranscriptions(tx *firestore.Transaction, userID string, usage *resources.RetranscriptionUsage) error {
+	if usage.Billed {
+		return nil
+	}
+	docs, err := tx.Documents(complaintRetranscriptionsQuery(userID, usage.AudioSessionID)).GetAll()
+	if err != nil {
+		return fmt.Errorf("failed to count complaint retranscriptions: %w", err)
+	}
+	if len(docs) >= cnfgs.MaxComplaintRetranscriptions {
+		return errs.ErrComplaintRetranscriptionsExceeded
+	}
+	return nil
+}
+
+func complaintRetranscriptionsQuery(userID, audioSessionID string) firestore.Query {
+	return collections.RetranscriptionUsages(userID).
+		Where("AudioSessionID", "==", audioSessionID).
+		Where("Billed", "==", false)
+}
+
+// Billed usage of the re-transcriptions saved as revisions in the range. Complaints are left out,
+// and so are siblings, their usage is on the sibling transcript.
+func GetBilledRetranscriptionUsages(ctx context.Context, userID string, start, end time.Time) ([]resources.RetranscriptionUsage, error) {
+	iter := collections.RetranscriptionUsages(userID).
+		Where("CreatedAt", ">=", start).
+		Where("CreatedAt", "<=", end).
+		Documents(ctx)
+
+	usages := []resources.RetranscriptionUsage{}
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		usage := new(resources.RetranscriptionUsage)
+		err = doc.DataTo(usage)
+		if err != nil {
+			return nil, fmt.Errorf("failed to transfer data from snap to model: %w", err)
+		}
+		if usage.Billed && usage.SiblingID == "" {
+			usages = append(usages, *usage)
+		}
+	}
+
+	return usages, nil
+}
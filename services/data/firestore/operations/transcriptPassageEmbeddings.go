@@ -0,0 +1,115 @@
+package operations
+
+import (
+	"context"
+	"eavesdropper/dtos/resources"
+	"eavesdropper/services/data/firestore/collections"
+	"fmt"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/api/iterator"
+)
+
+// Firestore rejects batches with more writes than this
+const maxBatchWrites = 500
+
+// The "in" filter accepts at most this many values
+const maxInFilterValues = 30
+
+// Replaces every stored passage embedding of the transcript with the given ones
+func ReplaceTranscriptPassageEmbeddings(
+	ctx context.Context,
+	userID, transcriptID string,
+	passages []resources.TranscriptPassageEmbedding,
+) error {
+	err := DeleteTranscriptPassageEmbeddings(ctx, userID, transcriptID)
+	if err != nil {
+		return err
+	}
+
+	for start := 0; start < len(passages); start += maxBatchWrites {
+		end := min(start+maxBatchWrites, len(passages))
+
+		batch := dbClient.Batch()
+		for _, passage := range passages[start:end] {
+			batch.Set(collections.TranscriptPassageEmbeddings(userID).Doc(passage.ID), passage)
+		}
+		if _, err := batch.Commit(ctx); err != nil {
+			return fmt.Errorf("failed to save passage embeddings: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func DeleteTranscriptPassageEmbeddings(ctx context.Context, userID, transcriptID string) error {
+	refs, err := collections.TranscriptPassageEmbeddings(userID).
+		Where("TranscriptID", "==", transcriptID).
+		Documents(ctx).
+		GetAll()
+	if err != nil {
+		return fmt.Errorf("failed to list passage embeddings: %w", err)
+	}
+
+	for start := 0; start < len(refs); start += maxBatchWrites {
+		end := min(start+maxBatchWrites, len(refs))
+
+		batch := dbClient.Batch()
+		for _, doc := range refs[start:end] {
+			batch.Delete(doc.Ref)
+		}
+		if _, err := batch.Commit(ctx); err != nil {
+			return fmt.Errorf("failed to delete passage embeddings: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Returns the passages of the user closest to the vector, by cosine distance.
+// When transcriptIDs is not nil only passages of those transcripts are considered.
+// Each filter combination needs the matching vector index in firestore.indexes.json.
+func FindNearestTranscriptPassages(
+	ctx context.Context,
+	userID string,
+	provider string,
+	vector []float32,
+	transcriptIDs []string,
+	limit int,
+) ([]resources.TranscriptPassageEmbedding, error) {
+
+	queries := []firestore.Query{}
+	base := collections.TranscriptPassageEmbeddings(userID).Where("Provider", "==", provider)
+	if transcriptIDs == nil {
+		queries = append(queries, base)
+	}
+	for start := 0; start < len(transcriptIDs); start += maxInFilterValues {
+		end := min(start+maxInFilterValues, len(transcriptIDs))
+		queries = append(queries, base.Where("TranscriptID", "in", transcriptIDs[start:end]))
+	}
+
+	passages := []resources.TranscriptPassageEmbedding{}
+	for _, query := range queries {
+		iter := query.FindNearest("Vector", firestore.Vector32(vector), limit, firestore.DistanceMeasureCosine,
+			&firestore.FindNearestOptions{DistanceResultField: "VectorDistance"},
+		).Documents(ctx)
+
+		for {
+			doc, err := iter.Next()
+			if err == iterator.Done {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+			passage := new(resources.TranscriptPassageEmbedding)
+			err = doc.DataTo(passage)
+			if err != nil {
+				return nil, fmt.Errorf("failed to transfer data from snap to model: %w", err)
+			}
+			passages = append(passages, *passage)
+		}
+	}
+
+	return passages, nil
+}
@@ -0,0 +1,209 @@
+package operations
+
+import (
+	"context"
+	"eavesdropper/dtos/resources"
+	"eavesdropper/errs"
+	"eavesdropper/services/data/firestore/collections"
+	"fmt"
+	"strconv"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const FirstTranscriptVersion = 1
+
+// Transcripts saved before revisions existed have no Version, they are on their first version
+func TranscriptVersion(t *resources.Transcript) int {
+	return max(t.Version, FirstTranscriptVersion)
+}
+
+func transcriptRevisionRef(userID, transcriptID string, version int) *firestore.DocumentRef {
+	return collections.TranscriptRevisions(userID, transcriptID).Doc(strconv.Itoa(version))
+}
+
+// Replaces the transcript content, and the language detected from it, and stores it as a new revision,
+// in a single transaction. Fails with errs.ErrTranscriptVersionConflict when the transcript is no longer at expectedVersion,
+// so concurrent editors cannot overwrite each other, and errs.ErrTranscriptNotFound when it does not exist or is trashed.
+func UpdateTranscriptContent(
+	ctx context.Context,
+	userID, transcriptID, authorID string,
+	content, language string,
+	expectedVersion int,
+	source resources.RevisionSource,
+	restoredFromVersion int,
+) (*resources.Transcript, error) {
+	var updated *resources.Transcript
+	err := dbClient.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		var err error
+		updated, err = updateTranscriptContent(tx, userID, transcriptID, authorID, content, language, expectedVersion, source, restoredFromVersion)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// Saves a re-transcription as a new revision of the transcript, see UpdateTranscriptContent, and records
+// the usage of the run in the same transaction, so a billed run is never saved without its usage.
+// Fails with errs.ErrComplaintRetranscriptionsExceeded when a complaint goes over the limit of the audio.
+func SaveRetranscribedRevision(
+	ctx context.Context,
+	userID, transcriptID string,
+	content, language string,
+	expectedVersion int,
+	usage *resources.RetranscriptionUsage,
+) (*resources.Transcript, error) {
+	var updated *resources.Transcript
+	err := dbClient.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		if err := checkComplaintRetranscriptions(tx, userID, usage); err != nil {
+			return err
+		}
+
+		var err error
+		updated, err = updateTranscriptContent(
+			tx, userID, transcriptID, userID, content, language, expectedVersion, resources.RevisionFromRetranscribe, 0,
+		)
+		if err != nil {
+			return err
+		}
+
+		usage.Version = updated.Version
+		return createRetranscriptionUsage(tx, userID, usage)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// Body of UpdateTranscriptContent. It reads the transcript, so in a larger transaction it runs before any write.
+func updateTranscriptContent(
+	tx *firestore.Transaction,
+	userID, transcriptID, authorID string,
+	content, language string,
+	expectedVersion int,
+	source resources.RevisionSource,
+	restoredFromVersion int,
+) (*resources.Transcript, error) {
+	transcriptRef := collections.Transcripts(userID).Doc(transcriptID)
+
+	snap, err := tx.Get(transcriptRef)
+	if status.Code(err) == codes.NotFound {
+		return nil, errs.ErrTranscriptNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transcript: %w", err)
+	}
+	t := new(resources.Transcript)
+	if err := snap.DataTo(t); err != nil {
+		return nil, fmt.Errorf("failed to transfer data from document to model: %w", err)
+	}
+	// Trashed transcripts are read only, and out of the search indexes a change would put them back in
+	if t.TrashedAt != nil {
+		return nil, errs.ErrTranscriptNotFound
+	}
+
+	currentVersion := TranscriptVersion(t)
+	if currentVersion != expectedVersion {
+		return nil, errs.ErrTranscriptVersionConflict
+	}
+
+	// Transcripts saved before revisions existed get their original content stored first
+	currentRevisionRef := transcriptRevisionRef(userID, transcriptID, currentVersion)
+	currentRevisionSnap, err := tx.Get(currentRevisionRef)
+	if err != nil && status.Code(err) != codes.NotFound {
+		return nil, err
+	}
+	if !currentRevisionSnap.Exists() {
+		err = tx.Create(currentRevisionRef, &resources.TranscriptRevision{
+			Version:   currentVersion,
+			Content:   t.Content,
+			Source:    resources.RevisionFromTranscription,
+			AuthorRef: t.UserRef,
+			CreatedAt: t.CreatedAt,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	now := time.Now()
+	t.Content = content
+	t.Language = language
+	t.Version = currentVersion + 1
+	t.UpdatedAt = now
+
+	err = tx.Update(transcriptRef, []firestore.Update{
+		{Path: "Content", Value: t.Content},
+		{Path: "Language", Value: t.Language},
+		{Path: "Version", Value: t.Version},
+		{Path: "UpdatedAt", Value: t.UpdatedAt},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Create(transcriptRevisionRef(userID, transcriptID, t.Version), &resources.TranscriptRevision{
+		Version:             t.Version,
+		Content:             content,
+		Source:              source,
+		RestoredFromVersion: restoredFromVersion,
+		AuthorRef:           collections.Users.Doc(authorID),
+		CreatedAt:           now,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Returns the stored revisions of the transcript, newest first
+func GetTranscriptRevisions(ctx context.Context, userID, transcriptID string) ([]resources.TranscriptRevision, error) {
+	iter := collections.TranscriptRevisions(userID, transcriptID).
+		OrderBy("Version", firestore.Desc).
+		Documents(ctx)
+
+	revisions := []resources.TranscriptRevision{}
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		revision := new(resources.TranscriptRevision)
+		err = doc.DataTo(revision)
+		if err != nil {
+			return nil, fmt.Errorf("failed to transfer data from snap to model: %w", err)
+		}
+		revisions = append(revisions, *revision)
+	}
+
+	return revisions, nil
+}
+
+// Fails with errs.ErrTranscriptRevisionNotFound when the version was never stored
+func GetTranscriptRevision(ctx context.Context, userID, transcriptID string, version int) (*resources.TranscriptRevision, error) {
+	doc, err := transcriptRevisionRef(userID, transcriptID, version).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return nil, errs.ErrTranscriptRevisionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transcript revision: %w", err)
+	}
+
+	revision := new(resources.TranscriptRevision)
+	err = doc.DataTo(revision)
+	if err != nil {
+		return nil, fmt.Errorf("failed to transfer data from document to model: %w", err)
+	}
+
+	return revision, nil
+}
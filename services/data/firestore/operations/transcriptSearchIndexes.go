@@ -0,0 +1,69 @@
+package operations
+
+import (
+	"context"
+	"eavesdropper/dtos/resources"
+	"eavesdropper/services/data/firestore/collections"
+	"fmt"
+	"time"
+
+	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Creates or replaces the search index entry of a transcript
+func SaveTranscriptSearchIndex(ctx context.Context, userID string, index *resources.TranscriptSearchIndex) error {
+	_, err := collections.TranscriptSearchIndexes(userID).Doc(index.TranscriptID).Set(ctx, index)
+	if err != nil {
+		return fmt.Errorf("failed to save transcript search index: %w", err)
+	}
+	return nil
+}
+
+// Deletes the search index entry of a transcript. Does nothing if it does not exist.
+func DeleteTranscriptSearchIndex(ctx context.Context, userID, transcriptID string) error {
+	_, err := collections.TranscriptSearchIndexes(userID).Doc(transcriptID).Delete(ctx)
+	if err != nil && status.Code(err) != codes.NotFound {
+		return fmt.Errorf("failed to delete transcript search index: %w", err)
+	}
+	return nil
+}
+
+// Returns the index entries containing the term, optionally limited to a creation date range.
+// Combining array-contains with the CreatedAt range needs the composite index defined in firestore.indexes.json.
+func GetTranscriptSearchIndexesWithTerm(
+	ctx context.Context,
+	userID string,
+	term string,
+	from, to *time.Time,
+) ([]resources.TranscriptSearchIndex, error) {
+	query := collections.TranscriptSearchIndexes(userID).Where("Terms", "array-contains", term)
+	if from != nil {
+		query = query.Where("CreatedAt", ">=", *from)
+	}
+	if to != nil {
+		query = query.Where("CreatedAt", "<=", *to)
+	}
+
+	iter := query.Documents(ctx)
+
+	indexes := []resources.TranscriptSearchIndex{}
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		index := new(resources.TranscriptSearchIndex)
+		err = doc.DataTo(index)
+		if err != nil {
+			return nil, fmt.Errorf("failed to transfer data from snap to model: %w", err)
+		}
+		indexes = append(indexes, *index)
+	}
+
+	return indexes, nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"eavesdropper/dtos/resources"
 	"eavesdropper/services/data/firestore/collections"
+	"eavesdropper/services/pagination"
 	"errors"
 	"fmt"
 	"time"
@@ -11,6 +12,7 @@ import (
 	client "eavesdropper/services/data/firestore/client"
 
 	"cloud.google.com/go/firestore"
+	"cloud.google.com/go/firestore/apiv1/firestorepb"
 	"github.com/google/uuid"
 	"google.golang.org/api/iterator"
 )
@@ -58,7 +60,10 @@ func AddTranscriptWhitelistedUsers(
 	}
 
 	_, err := batch.Commit(ctx)
-	return err
+	if err != nil {
+		return err
+	}
+	return syncTranscriptWhitelistedUsersCount(ctx, userID, transcriptID)
 }
 
 func RemoveTranscriptWhitelistedUsers(
@@ -123,13 +128,90 @@ func RemoveTranscriptWhitelistedUsers(
 	}
 
 	_, err := batch.Commit(ctx)
-	return err
+	if err != nil {
+		return err
+	}
+	return syncTranscriptWhitelistedUsersCount(ctx, userID, transcriptID)
+}
+
+func countTranscriptWhitelistedUsers(ctx context.Context, userID, transcriptID string) (int, error) {
+	aggSnap, err := collections.TranscriptWhitelistedUsers(userID, transcriptID).
+		NewAggregationQuery().
+		WithCount("total").
+		Get(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count whitelisted users: %w", err)
+	}
+
+	value, ok := aggSnap["total"].(*firestorepb.Value)
+	if !ok {
+		return 0, fmt.Errorf("failed to parse count")
+	}
+	return int(value.GetIntegerValue()), nil
+}
+
+// Stores the whitelist size on the transcript, and whether it is shared so the listing can filter on it.
+// Recounted instead of incremented so transcripts shared before the count existed get the right value.
+func syncTranscriptWhitelistedUsersCount(ctx context.Context, userID, transcriptID string) error {
+	count, err := countTranscriptWhitelistedUsers(ctx, userID, transcriptID)
+	if err != nil {
+		return err
+	}
+
+	_, err = collections.Transcripts(userID).Doc(transcriptID).Update(ctx, []firestore.Update{
+		{Path: "WhitelistedUsersCount", Value: count},
+		{Path: "IsShared", Value: count > 0},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update whitelisted users count: %w", err)
+	}
+	return nil
+}
+
+// Sets WhitelistedUsersCount and IsShared on the transcripts of every user saved before IsShared existed.
+// Firestore never matches missing fields, so every transcript is read once. Returns how many were updated.
+func BackfillTranscriptSharing(ctx context.Context) (int, error) {
+	iter := collections.AllTranscripts.Documents(ctx)
+	defer iter.Stop()
+
+	updated := 0
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return updated, fmt.Errorf("failed to list transcripts: %w", err)
+		}
+		if _, err := doc.DataAt("IsShared"); err == nil {
+			continue
+		}
+		if err := syncTranscriptWhitelistedUsersCount(ctx, doc.Ref.Parent.Parent.ID, doc.Ref.ID); err != nil {
+			return updated, err
+		}
+		updated++
+	}
+	return updated, nil
 }
 
-func GetTranscriptWhitelistedUsers(ctx context.Context, userID, transcriptID string) ([]resources.TranscriptWhitelistedUser, error) {
-	iter := collections.TranscriptWhitelistedUsers(userID, transcriptID).
+// Returns a page of the transcript whitelist, newest first, starting after the cursor (nil for the first page).
+// The returned cursor is nil when there are no more entries.
+func GetTranscriptWhitelistedUsers(
+	ctx context.Context,
+	userID, transcriptID string,
+	after *pagination.Cursor,
+	limit int,
+) ([]resources.TranscriptWhitelistedUser, *pagination.Cursor, error) {
+	query := collections.TranscriptWhitelistedUsers(userID, transcriptID).
 		OrderBy("CreatedAt", firestore.Desc).
-		Documents(ctx)
+		OrderBy("ID", firestore.Desc)
+
+	if after != nil {
+		query = query.StartAfter(after.CreatedAt, after.ID)
+	}
+
+	// Reads one extra document to know if there is a next page
+	iter := query.Limit(limit + 1).Documents(ctx)
 
 	whitelistedUsers := []resources.TranscriptWhitelistedUser{}
 
@@ -139,18 +221,24 @@ func GetTranscriptWhitelistedUsers(ctx context.Context, userID, transcriptID str
 			break
 		}
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 
 		user := new(resources.TranscriptWhitelistedUser)
 		err = doc.DataTo(user)
 		if err != nil {
-			return nil, errors.New(fmt.Sprintf("failed to transfer data from snap to model: %s", err.Error()))
+			return nil, nil, errors.New(fmt.Sprintf("failed to transfer data from snap to model: %s", err.Error()))
 		}
 		whitelistedUsers = append(whitelistedUsers, *user)
 	}
 
-	return whitelistedUsers, nil
+	if len(whitelistedUsers) <= limit {
+		return whitelistedUsers, nil, nil
+	}
+
+	whitelistedUsers = whitelistedUsers[:limit]
+	last := whitelistedUsers[limit-1]
+	return whitelistedUsers, pagination.NewCursor(last.CreatedAt, last.ID), nil
 }
 
 func findUserByHandle(ctx context.Context, handle string) (*firestore.DocumentRef, error) {
@@ -199,3 +287,37 @@ func IsUserWhitelistedForTranscript(ctx context.Context, transcriptOwnerID, tran
 
 	return true, nil // Found a matching whitelist entry
 }
+
+// A transcript of another user the user is whitelisted on
+type WhitelistedTranscriptRef struct {
+	OwnerID      string
+	TranscriptID string
+}
+
+// Lists the transcripts the user has been whitelisted on, across every owner.
+// Needs a collection group index exemption on UserRef (see firestore.indexes.json).
+func GetTranscriptsWhitelistedForUser(ctx context.Context, userID string) ([]WhitelistedTranscriptRef, error) {
+	userRef := collections.Users.Doc(userID)
+
+	iter := collections.AllTranscriptWhitelistedUsers.Where("UserRef", "==", userRef).Documents(ctx)
+
+	refs := []WhitelistedTranscriptRef{}
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to query whitelisted transcripts: %w", err)
+		}
+
+		// users/{ownerID}/transcripts/{transcriptID}/transcriptWhitelistedUsers/{id}
+		transcriptRef := doc.Ref.Parent.Parent
+		refs = append(refs, WhitelistedTranscriptRef{
+			OwnerID:      transcriptRef.Parent.Parent.ID,
+			TranscriptID: transcriptRef.ID,
+		})
+	}
+
+	return refs, nil
+}
@@ -0,0 +1,174 @@
+package operations
+
+import (
+	"context"
+	cnfgs "eavesdropper/configurations"
+	"eavesdropper/dtos/resources"
+	"eavesdropper/errs"
+	"eavesdropper/services/data/firestore/collections"
+	"fmt"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Outcome of claiming the transcription of a session.
+// Only when Claimed the caller transcribes, otherwise Existing or an in flight Job is responded.
+type TranscriptionClaim struct {
+	Claimed  bool
+	Job      *resources.TranscriptionJob
+	Existing *resources.Transcript // transcript already generated from the session
+}
+
+// Claims the transcription of the session for the caller. Not claimed while another request is processing it,
+// or when the session already has a transcript and force is false.
+// Sessions transcribed before jobs existed are found by the RecordingSessionID of their transcript.
+func ClaimTranscriptionJob(ctx context.Context, userID, sessionID string, force bool) (*TranscriptionClaim, error) {
+	jobRef := collections.TranscriptionJobs(userID).Doc(sessionID)
+	claim := &TranscriptionClaim{}
+
+	err := dbClient.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		claim = &TranscriptionClaim{}
+
+		doc, err := tx.Get(jobRef)
+		if err != nil && status.Code(err) != codes.NotFound {
+			return err
+		}
+		if err == nil {
+			claim.Job = new(resources.TranscriptionJob)
+			if err := doc.DataTo(claim.Job); err != nil {
+				return fmt.Errorf("failed to transfer data from document to model: %w", err)
+			}
+			inFlight := claim.Job.Status == resources.TranscriptionJobProcessing &&
+				time.Since(claim.Job.UpdatedAt) < cnfgs.TranscriptionJobStaleAfter
+			if inFlight {
+				return nil
+			}
+		}
+
+		if !force {
+			existing, err := sessionTranscript(tx, userID, sessionID, claim.Job)
+			if err != nil {
+				return err
+			}
+			if existing != nil {
+				claim.Existing = existing
+				return nil
+			}
+		}
+
+		now := time.Now()
+		job := &resources.TranscriptionJob{
+			SessionID: sessionID,
+			Status:    resources.TranscriptionJobProcessing,
+			Forced:    force,
+			StartedAt: now,
+			UpdatedAt: now,
+		}
+		if claim.Job != nil {
+			job.TranscriptID = claim.Job.TranscriptID
+		}
+		claim.Job = job
+		claim.Claimed = true
+		return tx.Set(jobRef, job)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim transcription job: %w", err)
+	}
+
+	return claim, nil
+}
+
+// The transcript of the last completed job, or any transcript of the session for legacy sessions.
+// Trashed transcripts are not replayed, the session is transcribed again.
+func sessionTranscript(tx *firestore.Transaction, userID, sessionID string, job *resources.TranscriptionJob) (*resources.Transcript, error) {
+	if job != nil && job.TranscriptID != "" {
+		doc, err := tx.Get(collections.Transcripts(userID).Doc(job.TranscriptID))
+		if err == nil {
+			transcript := new(resources.Transcript)
+			if err := doc.DataTo(transcript); err != nil {
+				return nil, fmt.Errorf("failed to transfer data from document to model: %w", err)
+			}
+			if transcript.TrashedAt == nil {
+				return transcript, nil
+			}
+		} else if status.Code(err) != codes.NotFound {
+			return nil, err
+		}
+	}
+
+	// Sessions have a single transcript, trashed ones and old siblings are skipped in memory
+	docs, err := tx.Documents(
+		collections.Transcripts(userID).Where("RecordingSessionID", "==", sessionID),
+	).GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, doc := range docs {
+		transcript := new(resources.Transcript)
+		if err := doc.DataTo(transcript); err != nil {
+			return nil, fmt.Errorf("failed to transfer data from document to model: %w", err)
+		}
+		// Siblings saved before AudioSessionID existed kept the session of their original
+		if transcript.TrashedAt == nil && transcript.RetranscribedFromID == "" {
+			return transcript, nil
+		}
+	}
+	return nil, nil
+}
+
+func GetTranscriptionJob(ctx context.Context, userID, sessionID string) (*resources.TranscriptionJob, error) {
+	doc, err := collections.TranscriptionJobs(userID).Doc(sessionID).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return nil, errs.ErrTranscriptionJobNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transcription job: %w", err)
+	}
+
+	job := new(resources.TranscriptionJob)
+	if err := doc.DataTo(job); err != nil {
+		return nil, fmt.Errorf("failed to transfer data from document to model: %w", err)
+	}
+	return job, nil
+}
+
+// Refreshes the UpdatedAt of a processing job so it is not considered abandoned
+func TouchTranscriptionJob(ctx context.Context, userID, sessionID string) error {
+	_, err := collections.TranscriptionJobs(userID).Doc(sessionID).Update(ctx, []firestore.Update{
+		{Path: "UpdatedAt", Value: time.Now()},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to touch transcription job: %w", err)
+	}
+	return nil
+}
+
+func CompleteTranscriptionJob(ctx context.Context, userID, sessionID, transcriptID string) error {
+	_, err := collections.TranscriptionJobs(userID).Doc(sessionID).Update(ctx, []firestore.Update{
+		{Path: "Status", Value: resources.TranscriptionJobCompleted},
+		{Path: "TranscriptID", Value: transcriptID},
+		{Path: "Error", Value: ""},
+		{Path: "UpdatedAt", Value: time.Now()},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to complete transcription job: %w", err)
+	}
+	return nil
+}
+
+// Releases the session so the next request retries it
+func FailTranscriptionJob(ctx context.Context, userID, sessionID string, cause error) error {
+	_, err := collections.TranscriptionJobs(userID).Doc(sessionID).Update(ctx, []firestore.Update{
+		{Path: "Status", Value: resources.TranscriptionJobFailed},
+		{Path: "Error", Value: cause.Error()},
+		{Path: "UpdatedAt", Value: time.Now()},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to fail transcription job: %w", err)
+	}
+	return nil
+}
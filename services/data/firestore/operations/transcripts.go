@@ -2,8 +2,12 @@ package operations
 
 import (
 	"context"
+	cnfgs "eavesdropper/configurations"
+	"eavesdropper/dtos/requests"
 	"eavesdropper/dtos/resources"
+	"eavesdropper/errs"
 	"eavesdropper/services/data/firestore/collections"
+	"eavesdropper/services/pagination"
 	"errors"
 	"fmt"
 	"time"
@@ -12,6 +16,8 @@ import (
 	"cloud.google.com/go/firestore/apiv1/firestorepb"
 	"github.com/google/uuid"
 	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 func countUserTranscripts(ctx context.Context, userID string) (int64, error) {
@@ -35,15 +41,28 @@ func countUserTranscripts(ctx context.Context, userID string) (int64, error) {
 	return value.GetIntegerValue(), nil
 }
 
+// What was measured on the audio of a new transcript
+type TranscriptAudio struct {
+	DurationSeconds float64
+	SpeechSeconds   float64
+	NonSpeechSpans  []resources.AudioSpan
+	SilenceTrimmed  bool
+	Enhancement     string
+	Quality         *resources.AudioQuality
+	MissingAudio    []resources.MissingAudioRange
+}
+
 func SaveTranscript(
 	ctx context.Context,
 	userID string,
 	recordingSessionID string,
 	transcriptStr string,
+	language string,
 	consumedInputAudioSeconds int,
 	consumedFreeAudioSeconds int,
 	consumedInputTokens int,
 	consumedOutputTokens int,
+	audio TranscriptAudio,
 ) (*resources.Transcript, error) {
 
 	transcriptCount, err := countUserTranscripts(ctx, userID)
@@ -58,55 +77,335 @@ func SaveTranscript(
 		RecordingSessionID:        recordingSessionID,
 		Tittle:                    defaultTitle,
 		Content:                   transcriptStr,
+		Language:                  language,
 		ConsumedInputAudioSeconds: consumedInputAudioSeconds,
 		ConsumedFreeAudioSeconds:  consumedFreeAudioSeconds,
 		ConsumedInputTokens:       consumedInputTokens,
 		ConsumedOutputTokens:      consumedOutputTokens,
-		CreatedAt:                 time.Now(),
+		MissingAudio:              audio.MissingAudio,
+		AudioDurationSeconds:      audio.DurationSeconds,
+		SpeechSeconds:             audio.SpeechSeconds,
+		NonSpeechSpans:            audio.NonSpeechSpans,
+		SilenceTrimmed:            audio.SilenceTrimmed,
+		AudioEnhancement:          audio.Enhancement,
+		AudioQuality:              audio.Quality,
+	}
+
+	return t, createTranscript(ctx, userID, t)
+}
+
+// The recording session whose archived audio the transcript was made from
+func TranscriptAudioSession(t *resources.Transcript) string {
+	if t.AudioSessionID != "" {
+		return t.AudioSessionID
+	}
+	return t.RecordingSessionID
+}
+
+// Saves a transcript re-transcribed from the audio of the original, next to it, with the usage of the run.
+// It shares the audio, the folder, the tags and the privacy of the original. Its RecordingSessionID stays empty,
+// a session has a single transcript, and the audio is found by AudioSessionID.
+func SaveSiblingTranscript(
+	ctx context.Context,
+	userID string,
+	original *resources.Transcript,
+	transcriptStr string,
+	language string,
+	consumedFreeAudioSeconds int,
+	consumedInputTokens int,
+	consumedOutputTokens int,
+	complimentary bool,
+	usage *resources.RetranscriptionUsage,
+) (*resources.Transcript, error) {
+	t := &resources.Transcript{
+		ID:                        uuid.NewString(),
+		UserRef:                   collections.Users.Doc(userID),
+		AudioSessionID:            TranscriptAudioSession(original),
+		Tittle:                    original.Tittle + " (re-transcribed)",
+		Content:                   transcriptStr,
+		Language:                  language,
+		ConsumedInputAudioSeconds: original.ConsumedInputAudioSeconds,
+		ConsumedFreeAudioSeconds:  consumedFreeAudioSeconds,
+		ConsumedInputTokens:       consumedInputTokens,
+		ConsumedOutputTokens:      consumedOutputTokens,
+		IsPrivate:                 original.IsPrivate,
+		FolderID:                  original.FolderID,
+		Tags:                      original.Tags,
+		RetranscribedFromID:       original.ID,
+		Complimentary:             complimentary,
+		MissingAudio:              original.MissingAudio, // the archived audio has the same gaps
+		AudioDurationSeconds:      original.AudioDurationSeconds,
+		SpeechSeconds:             original.SpeechSeconds,
+		NonSpeechSpans:            original.NonSpeechSpans,
+		AudioEnhancement:          string(cnfgs.EnhancementNone), // the archived audio is not enhanced
+		AudioQuality:              original.AudioQuality,
 	}
+	usage.SiblingID = t.ID
 
-	_, err = collections.Transcripts(userID).Doc(t.ID).Create(ctx, t)
+	err := dbClient.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		if err := checkComplaintRetranscriptions(tx, userID, usage); err != nil {
+			return err
+		}
+		if err := createTranscriptInTx(tx, userID, t); err != nil {
+			return err
+		}
+		return createRetranscriptionUsage(tx, userID, usage)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
 
-	return t, err
+// Creates the transcript with its generated content as the first revision
+func createTranscript(ctx context.Context, userID string, t *resources.Transcript) error {
+	return dbClient.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		return createTranscriptInTx(tx, userID, t)
+	})
 }
 
-func GetUserTranscripts(ctx context.Context, userID string, pageI int, pageSize int) ([]resources.Transcript, error) {
-	query := collections.Transcripts(userID).
-		OrderBy("CreatedAt", firestore.Desc)
+func createTranscriptInTx(tx *firestore.Transaction, userID string, t *resources.Transcript) error {
+	t.CreatedAt = time.Now()
+	t.UpdatedAt = t.CreatedAt
+	t.Version = FirstTranscriptVersion
 
-	if pageI > 0 {
-		query = query.Offset(pageI * pageSize)
+	if err := tx.Create(collections.Transcripts(userID).Doc(t.ID), t); err != nil {
+		return err
 	}
+	return tx.Create(transcriptRevisionRef(userID, t.ID, t.Version), &resources.TranscriptRevision{
+		Version:   t.Version,
+		Content:   t.Content,
+		Source:    resources.RevisionFromTranscription,
+		AuthorRef: t.UserRef,
+		CreatedAt: t.CreatedAt,
+	})
+}
 
-	if pageSize > 0 {
-		query = query.Limit(pageSize)
+// Counts the transcripts using the audio of the recording session, trashed ones included:
+// the transcript of the session and the re-transcriptions saved as its siblings.
+func CountSessionTranscripts(ctx context.Context, userID, sessionID string) (int, error) {
+	total := 0
+	for _, field := range []string{"RecordingSessionID", "AudioSessionID"} {
+		query := collections.Transcripts(userID).Where(field, "==", sessionID)
+		aggSnap, err := query.
+			NewAggregationQuery().
+			WithCount("total").
+			Get(ctx)
+		if err != nil {
+			return 0, fmt.Errorf("failed to count session transcripts: %w", err)
+		}
+
+		value, ok := aggSnap["total"].(*firestorepb.Value)
+		if !ok {
+			return 0, fmt.Errorf("failed to parse count")
+		}
+		total += int(value.GetIntegerValue())
 	}
+	return total, nil
+}
 
-	iter := query.Documents(ctx)
+// Returns a page of the user transcripts, starting after the cursor (nil for the first page).
+// Trashed transcripts are skipped. The returned cursor is nil when there are no more transcripts.
+// Filters are optional, nil lists newest first. Every filter is part of the query, so only the combinations
+// with a composite index in firestore.indexes.json are allowed, the others fail with errs.ErrUnsupportedTranscriptFilters.
+func GetUserTranscripts(
+	ctx context.Context,
+	userID string,
+	filters *requests.TranscriptListFilters,
+	after *pagination.Cursor,
+	limit int,
+) ([]resources.Transcript, *pagination.Cursor, error) {
+	if filters == nil {
+		filters = requests.DefaultTranscriptListFilters()
+	}
+	if err := supportedTranscriptListFilters(filters); err != nil {
+		return nil, nil, err
+	}
+	if after != nil && after.Sort != transcriptCursorSort(filters) {
+		return nil, nil, errs.ErrInvalidCursor
+	}
 
+	query := transcriptListQuery(userID, filters)
 	transcripts := []resources.Transcript{}
 
-	for {
-		doc, err := iter.Next()
-		if err == iterator.Done {
-			break
+	// Trashed transcripts are filtered here and not in the query because transcripts saved
+	// before the trash existed have no TrashedAt field, and Firestore never matches missing fields.
+	// Reads again when trashed ones leave the page short, at most the size of the trash.
+	// One extra transcript tells if there is a next page.
+	for len(transcripts) <= limit {
+		pageQuery := query
+		if after != nil {
+			pageQuery = query.StartAfter(transcriptCursorValues(filters, after)...)
 		}
+
+		docs, err := pageQuery.Limit(limit + 1).Documents(ctx).GetAll()
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
-		t := new(resources.Transcript)
-		err = doc.DataTo(t)
-		if err != nil {
-			return nil, errors.New(fmt.Sprintf("failed to transfer data from snap to model: %s", err.Error()))
+
+		for _, doc := range docs {
+			t := new(resources.Transcript)
+			err = doc.DataTo(t)
+			if err != nil {
+				return nil, nil, errors.New(fmt.Sprintf("failed to transfer data from snap to model: %s", err.Error()))
+			}
+			after = newTranscriptCursor(filters, t)
+
+			if t.TrashedAt != nil {
+				continue
+			}
+			transcripts = append(transcripts, *t)
+			if len(transcripts) > limit {
+				break
+			}
+		}
+
+		if len(docs) <= limit {
+			break
 		}
-		transcripts = append(transcripts, *t)
 	}
 
-	return transcripts, nil
+	if len(transcripts) <= limit {
+		return transcripts, nil, nil
+	}
+
+	transcripts = transcripts[:limit]
+	return transcripts, newTranscriptCursor(filters, &transcripts[limit-1]), nil
+}
+
+// The listing has a composite index per equality filter and sort, and for the folder and tag filters together.
+// Ranges are only allowed on the sorted field, Firestore orders by the field of the range first.
+func supportedTranscriptListFilters(filters *requests.TranscriptListFilters) error {
+	equalities := 0
+	for _, set := range []bool{
+		filters.FolderID != "",
+		filters.Tag != "",
+		filters.IsPrivate != nil,
+		filters.Shared != nil,
+		filters.Language != "",
+	} {
+		if set {
+			equalities++
+		}
+	}
+	folderAndTag := equalities == 2 && filters.FolderID != "" && filters.Tag != ""
+	if equalities > 1 && !folderAndTag {
+		return fmt.Errorf("%w: folderId, tag, private, shared and language cannot be combined, except folderId with tag", errs.ErrUnsupportedTranscriptFilters)
+	}
+
+	createdRange := filters.CreatedFrom != nil || filters.CreatedTo != nil
+	durationRange := filters.MinDuration != nil || filters.MaxDuration != nil
+	switch {
+	case createdRange && filters.SortBy != requests.SortByCreatedAt:
+		return fmt.Errorf("%w: createdFrom and createdTo need sort=createdAt", errs.ErrUnsupportedTranscriptFilters)
+	case durationRange && filters.SortBy != requests.SortByDuration:
+		return fmt.Errorf("%w: minDuration and maxDuration need sort=duration", errs.ErrUnsupportedTranscriptFilters)
+	case filters.TitlePrefix != "" && filters.SortBy != requests.SortByTitle:
+		return fmt.Errorf("%w: titlePrefix needs sort=title", errs.ErrUnsupportedTranscriptFilters)
+	}
+	return nil
+}
+
+// Firestore field sorted by, ties are broken by the ID in the same direction
+var transcriptSortFields = map[requests.TranscriptSortField]string{
+	requests.SortByCreatedAt: "CreatedAt",
+	requests.SortByDuration:  "ConsumedInputAudioSeconds",
+	requests.SortByTitle:     "Tittle",
+}
+
+func transcriptListQuery(userID string, filters *requests.TranscriptListFilters) firestore.Query {
+	query := collections.Transcripts(userID).Query
+	if filters.FolderID != "" {
+		query = query.Where("FolderID", "==", filters.FolderID)
+	}
+	if filters.Tag != "" {
+		query = query.Where("Tags", "array-contains", filters.Tag)
+	}
+	if filters.IsPrivate != nil {
+		query = query.Where("IsPrivate", "==", *filters.IsPrivate)
+	}
+	if filters.Shared != nil {
+		query = query.Where("IsShared", "==", *filters.Shared)
+	}
+	if filters.Language != "" {
+		query = query.Where("Language", "==", filters.Language)
+	}
+
+	switch filters.SortBy {
+	case requests.SortByDuration:
+		if filters.MinDuration != nil {
+			query = query.Where("ConsumedInputAudioSeconds", ">=", *filters.MinDuration)
+		}
+		if filters.MaxDuration != nil {
+			query = query.Where("ConsumedInputAudioSeconds", "<=", *filters.MaxDuration)
+		}
+	case requests.SortByTitle:
+		if filters.TitlePrefix != "" {
+			query = query.
+				Where("Tittle", ">=", filters.TitlePrefix).
+				Where("Tittle", "<", filters.TitlePrefix+"\uf8ff")
+		}
+	default:
+		if filters.CreatedFrom != nil {
+			query = query.Where("CreatedAt", ">=", *filters.CreatedFrom)
+		}
+		if filters.CreatedTo != nil {
+			query = query.Where("CreatedAt", "<=", *filters.CreatedTo)
+		}
+	}
+
+	direction := firestore.Asc
+	if filters.Descending {
+		direction = firestore.Desc
+	}
+	field, ok := transcriptSortFields[filters.SortBy]
+	if !ok {
+		field = "CreatedAt"
+	}
+	return query.OrderBy(field, direction).OrderBy("ID", direction)
+}
+
+// Cursors only continue the listing with the same sort. Empty for the default one,
+// so cursors from before sorting existed stay valid.
+func transcriptCursorSort(filters *requests.TranscriptListFilters) string {
+	if (filters.SortBy == "" || filters.SortBy == requests.SortByCreatedAt) && filters.Descending {
+		return ""
+	}
+	direction := "asc"
+	if filters.Descending {
+		direction = "desc"
+	}
+	return string(filters.SortBy) + ":" + direction
+}
+
+func newTranscriptCursor(filters *requests.TranscriptListFilters, t *resources.Transcript) *pagination.Cursor {
+	cursor := pagination.NewCursor(t.CreatedAt, t.ID)
+	cursor.Sort = transcriptCursorSort(filters)
+	switch filters.SortBy {
+	case requests.SortByDuration:
+		cursor.Seconds = t.ConsumedInputAudioSeconds
+	case requests.SortByTitle:
+		cursor.Title = t.Tittle
+	}
+	return cursor
+}
+
+func transcriptCursorValues(filters *requests.TranscriptListFilters, cursor *pagination.Cursor) []interface{} {
+	switch filters.SortBy {
+	case requests.SortByDuration:
+		return []interface{}{cursor.Seconds, cursor.ID}
+	case requests.SortByTitle:
+		return []interface{}{cursor.Title, cursor.ID}
+	default:
+		return []interface{}{cursor.CreatedAt, cursor.ID}
+	}
 }
 
 func GetUserTranscript(ctx context.Context, userID, transcriptID string) (*resources.Transcript, error) {
 	doc, err := collections.Transcripts(userID).Doc(transcriptID).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return nil, errs.ErrTranscriptNotFound
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get transcript: %w", err)
 	}
@@ -120,6 +419,7 @@ func GetUserTranscript(ctx context.Context, userID, transcriptID string) (*resou
 	return transcript, nil
 }
 
+// Transcripts created in the range, used for billing. Includes the trashed ones, their usage was billed.
 func GetTranscripts(ctx context.Context, userID string, start, end time.Time) ([]resources.Transcript, error) {
 
 	iter := collections.Transcripts(userID).
@@ -184,7 +484,123 @@ func GetTranscriptByID(ctx context.Context, transcriptID string) (*resources.Tra
 		return nil, "", fmt.Errorf("failed to parse transcript: %w", err)
 	}
 
+	// Trashed transcripts are only visible to their owner, through the trash
+	if transcript.TrashedAt != nil {
+		return nil, "", fmt.Errorf("transcript not found")
+	}
+
 	userID := doc.Ref.Parent.Parent.ID
 
 	return transcript, userID, nil
 }
+
+//////// TRASH ////////
+
+// Moves the transcript to the trash. Trashing it again keeps the original trash date.
+// Also returns whether this call trashed it, false when it already was in the trash.
+func TrashTranscript(ctx context.Context, userID, transcriptID string) (*resources.Transcript, bool, error) {
+	transcriptRef := collections.Transcripts(userID).Doc(transcriptID)
+	trashed := new(resources.Transcript)
+	trashedNow := false
+
+	transaction := func(ctx context.Context, tx *firestore.Transaction) error {
+		snap, err := tx.Get(transcriptRef)
+		if status.Code(err) == codes.NotFound {
+			return errs.ErrTranscriptNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get transcript: %w", err)
+		}
+		if err := snap.DataTo(trashed); err != nil {
+			return fmt.Errorf("failed to transfer data from document to model: %w", err)
+		}
+		if trashed.TrashedAt != nil {
+			trashedNow = false
+			return nil
+		}
+
+		now := time.Now()
+		trashed.TrashedAt = &now
+		trashedNow = true
+		return tx.Update(transcriptRef, []firestore.Update{{Path: "TrashedAt", Value: now}})
+	}
+
+	err := dbClient.RunTransaction(ctx, transaction)
+	if err != nil {
+		return nil, false, err
+	}
+	return trashed, trashedNow, nil
+}
+
+func RestoreTrashedTranscript(ctx context.Context, userID, transcriptID string) error {
+	_, err := collections.Transcripts(userID).Doc(transcriptID).Update(ctx, []firestore.Update{
+		{Path: "TrashedAt", Value: nil},
+	})
+	if status.Code(err) == codes.NotFound {
+		return errs.ErrTranscriptNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to restore transcript: %w", err)
+	}
+	return nil
+}
+
+// Trashed transcripts of the user, most recently trashed first
+func GetTrashedUserTranscripts(ctx context.Context, userID string) ([]resources.Transcript, error) {
+	iter := collections.Transcripts(userID).
+		Where("TrashedAt", "!=", nil).
+		OrderBy("TrashedAt", firestore.Desc).
+		Documents(ctx)
+
+	transcripts := []resources.Transcript{}
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		t := new(resources.Transcript)
+		err = doc.DataTo(t)
+		if err != nil {
+			return nil, fmt.Errorf("failed to transfer data from snap to model: %w", err)
+		}
+		transcripts = append(transcripts, *t)
+	}
+
+	return transcripts, nil
+}
+
+type OwnedTranscript struct {
+	OwnerID    string
+	Transcript resources.Transcript
+}
+
+// Transcripts of every user trashed before the given time, at most limit of them.
+// Needs a collection group index on TrashedAt (see firestore.indexes.json).
+func GetTranscriptsTrashedBefore(ctx context.Context, before time.Time, limit int) ([]OwnedTranscript, error) {
+	iter := collections.AllTranscripts.
+		Where("TrashedAt", "<=", before).
+		Limit(limit).
+		Documents(ctx)
+
+	transcripts := []OwnedTranscript{}
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		t := new(resources.Transcript)
+		err = doc.DataTo(t)
+		if err != nil {
+			return nil, fmt.Errorf("failed to transfer data from snap to model: %w", err)
+		}
+		transcripts = append(transcripts, OwnedTranscript{OwnerID: doc.Ref.Parent.Parent.ID, Transcript: *t})
+	}
+
+	return transcripts, nil
+}
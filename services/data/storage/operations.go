@@ -4,14 +4,21 @@ import (
 	"context"
 	"eavesdropper/dtos/resources"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"net/http"
 	"os"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/iterator"
 )
 
 func LoadManifest(ctx context.Context, sessionId string, userId string) (*resources.RecordingManifest, error) {
 
-	path := fmt.Sprintf("%s/%s/%s/manifest.json", recordingSessionsDir, userId, sessionId)
+	path := ManifestPath(userId, sessionId)
 
 	reader, err := Storage.BucketHandle.Object(path).NewReader(ctx)
 	if err != nil {
@@ -26,6 +33,40 @@ func LoadManifest(ctx context.Context, sessionId string, userId string) (*resour
 	return &m, nil
 }
 
+func SaveManifest(ctx context.Context, manifest *resources.RecordingManifest) error {
+	bucketWritter := Storage.BucketHandle.Object(ManifestPath(manifest.UID, manifest.SessionID)).NewWriter(ctx)
+	bucketWritter.ContentType = "application/json"
+	if err := json.NewEncoder(bucketWritter).Encode(manifest); err != nil {
+		_ = bucketWritter.Close()
+		return fmt.Errorf("encode manifest: %w", err)
+	}
+	return bucketWritter.Close()
+}
+
+// V4 signed URL the client uploads the object with, with a PUT of the given content type
+func SignedUploadURL(storagePath, contentType string, expires time.Time) (string, error) {
+	return Storage.BucketHandle.SignedURL(storagePath, &storage.SignedURLOptions{
+		Scheme:      storage.SigningSchemeV4,
+		Method:      "PUT",
+		ContentType: contentType,
+		Expires:     expires,
+	})
+}
+
+// V4 signed URL the client downloads the object with, with a GET
+func SignedDownloadURL(storagePath string, expires time.Time) (string, error) {
+	return Storage.BucketHandle.SignedURL(storagePath, &storage.SignedURLOptions{
+		Scheme:  storage.SigningSchemeV4,
+		Method:  "GET",
+		Expires: expires,
+	})
+}
+
+// Returns storage.ErrObjectNotExist when there is no object at the path
+func GetObjectAttrs(ctx context.Context, storagePath string) (*storage.ObjectAttrs, error) {
+	return Storage.BucketHandle.Object(storagePath).Attrs(ctx)
+}
+
 func Upload(ctx context.Context, storagePath, localPath, contentType string) error {
 	file, err := os.Open(localPath)
 	if err != nil {
@@ -43,6 +84,129 @@ func Upload(ctx context.Context, storagePath, localPath, contentType string) err
 	return bucketWritter.Close()
 }
 
+// Reader of the object, closed by the caller. Its Attrs have the size of the object.
+// Returns storage.ErrObjectNotExist when there is no object at the path.
+func OpenReader(ctx context.Context, storagePath string) (*storage.Reader, error) {
+	return Storage.BucketHandle.Object(storagePath).NewReader(ctx)
+}
+
+// Seekable reader of a generation of an object, for serving ranges of it.
+// Each read after a seek opens a range reader from the new offset. Closed by the caller.
+type ObjectReadSeeker struct {
+	ctx    context.Context
+	object *storage.ObjectHandle
+	size   int64
+	offset int64
+	reader *storage.Reader
+}
+
+// Reader of the object version described by attrs, so every range comes from the same content
+func NewObjectReadSeeker(ctx context.Context, attrs *storage.ObjectAttrs) *ObjectReadSeeker {
+	return &ObjectReadSeeker{
+		ctx:    ctx,
+		object: Storage.BucketHandle.Object(attrs.Name).Generation(attrs.Generation),
+		size:   attrs.Size,
+	}
+}
+
+func (o *ObjectReadSeeker) Read(p []byte) (int, error) {
+	if o.offset >= o.size {
+		return 0, io.EOF
+	}
+	if o.reader == nil {
+		reader, err := o.object.NewRangeReader(o.ctx, o.offset, -1)
+		if err != nil {
+			return 0, err
+		}
+		o.reader = reader
+	}
+	n, err := o.reader.Read(p)
+	o.offset += int64(n)
+	return n, err
+}
+
+func (o *ObjectReadSeeker) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekCurrent:
+		offset += o.offset
+	case io.SeekEnd:
+		offset += o.size
+	}
+	if offset < 0 {
+		return 0, errors.New("seek before the start of the object")
+	}
+	if offset != o.offset {
+		o.closeReader()
+		o.offset = offset
+	}
+	return offset, nil
+}
+
+func (o *ObjectReadSeeker) Close() error {
+	o.closeReader()
+	return nil
+}
+
+func (o *ObjectReadSeeker) closeReader() {
+	if o.reader != nil {
+		_ = o.reader.Close()
+		o.reader = nil
+	}
+}
+
+// Reader of length bytes of the object from offset, or of the rest of it when length is negative.
+// Returns storage.ErrObjectNotExist when there is no object at the path.
+func OpenRangeReader(ctx context.Context, storagePath string, offset, length int64) (*storage.Reader, error) {
+	return Storage.BucketHandle.Object(storagePath).NewRangeReader(ctx, offset, length)
+}
+
+// Writer streaming into the object, which is created when the writer is closed.
+// Cancelling the context aborts the upload.
+func NewWriter(ctx context.Context, storagePath, contentType string) *storage.Writer {
+	bucketWritter := Storage.BucketHandle.Object(storagePath).NewWriter(ctx)
+	bucketWritter.ChunkSize = 4 * 1024 * 1024
+	bucketWritter.ContentType = contentType
+	return bucketWritter
+}
+
+// Like NewWriter, but closing it fails with a precondition error when the object already exists
+func NewExclusiveWriter(ctx context.Context, storagePath, contentType string) *storage.Writer {
+	bucketWritter := Storage.BucketHandle.Object(storagePath).If(storage.Conditions{DoesNotExist: true}).NewWriter(ctx)
+	bucketWritter.ChunkSize = 4 * 1024 * 1024
+	bucketWritter.ContentType = contentType
+	return bucketWritter
+}
+
+// Creates the object, empty, unless it already exists. Returns false when it exists.
+func CreateIfNotExists(ctx context.Context, storagePath string) (bool, error) {
+	err := NewExclusiveWriter(ctx, storagePath, "application/octet-stream").Close()
+	if IsPreconditionFailed(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// The error of a write or a delete whose conditions, like DoesNotExist or GenerationMatch, did not hold
+func IsPreconditionFailed(err error) bool {
+	var apiErr *googleapi.Error
+	return errors.As(err, &apiErr) && apiErr.Code == http.StatusPreconditionFailed
+}
+
+// Concatenates the sources, in order, into the destination object. At most 32 sources.
+func Compose(ctx context.Context, storagePath, contentType string, sourcePaths ...string) error {
+	sources := make([]*storage.ObjectHandle, len(sourcePaths))
+	for i, sourcePath := range sourcePaths {
+		sources[i] = Storage.BucketHandle.Object(sourcePath)
+	}
+	composer := Storage.BucketHandle.Object(storagePath).ComposerFrom(sources...)
+	composer.ContentType = contentType
+	_, err := composer.Run(ctx)
+	return err
+}
+
 func Download(ctx context.Context, storagePath, localPath string) error {
 	reader, err := Storage.BucketHandle.Object(storagePath).NewReader(ctx)
 	if err != nil {
@@ -59,3 +223,58 @@ func Download(ctx context.Context, storagePath, localPath string) error {
 	_, err = io.Copy(f, reader)
 	return err
 }
+
+// Deletes the object. Deleting an object that does not exist is not an error.
+func Delete(ctx context.Context, storagePath string) error {
+	err := Storage.BucketHandle.Object(storagePath).Delete(ctx)
+	if err != nil && !errors.Is(err, storage.ErrObjectNotExist) {
+		return err
+	}
+	return nil
+}
+
+// Deletes the generation of the object, only when it is still the live one.
+// Fails with a precondition error when the object was replaced, and storage.ErrObjectNotExist when it is gone.
+func DeleteGeneration(ctx context.Context, storagePath string, generation int64) error {
+	return Storage.BucketHandle.Object(storagePath).If(storage.Conditions{GenerationMatch: generation}).Delete(ctx)
+}
+
+// Lists the objects matching the query, at most limit of them
+func ListObjects(ctx context.Context, query *storage.Query, limit int) ([]*storage.ObjectAttrs, error) {
+	iter := Storage.BucketHandle.Objects(ctx, query)
+
+	objects := []*storage.ObjectAttrs{}
+	for len(objects) < limit {
+		attrs, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("list objects: %w", err)
+		}
+		objects = append(objects, attrs)
+	}
+	return objects, nil
+}
+
+// Deletes every object under the prefix. Returns the number of deleted objects.
+func DeletePrefix(ctx context.Context, prefix string) (int, error) {
+	iter := Storage.BucketHandle.Objects(ctx, &storage.Query{Prefix: prefix})
+
+	deleted := 0
+	for {
+		attrs, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return deleted, fmt.Errorf("list %s: %w", prefix, err)
+		}
+		if err := Delete(ctx, attrs.Name); err != nil {
+			return deleted, fmt.Errorf("delete %s: %w", attrs.Name, err)
+		}
+		deleted++
+	}
+
+	return deleted, nil
+}
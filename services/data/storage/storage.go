@@ -37,6 +37,47 @@ func initStorage() *StorageManager {
 	}
 }
 
-func FinalAudioUploadPath(userId, sessionId string) string {
-	return fmt.Sprintf("%s/%s/%s/final.wav", outputDir, userId, sessionId)
+// Final audio of the session, archived with its codec, like final.ogg.
+// Sessions processed before the archives were compressed have a final.wav until it is migrated.
+func FinalAudioPath(userId, sessionId, extension string) string {
+	return fmt.Sprintf("%s/%s/%s/final%s", outputDir, userId, sessionId, extension)
+}
+
+// Matches the final audio archived as wav of every session, for a storage.Query MatchGlob
+func WAVFinalAudioGlob() string {
+	return outputDir + "/*/*/final.wav"
+}
+
+// Lease of the migration of a wav archive, an empty object next to it that no reader of the final audio matches
+func ArchiveMigrationLeasePath(wavPath string) string {
+	return wavPath + ".migrating"
+}
+
+// Waveform peaks of the final audio at a zoom level, in the audiowaveform binary format
+func WaveformPeaksPath(userId, sessionId string, zoom int) string {
+	return fmt.Sprintf("%s/%s/%s/peaks-%d.dat", outputDir, userId, sessionId, zoom)
+}
+
+// Audio clip cut from the final audio, deleted with the other outputs of the session
+func ClipPath(userId, sessionId, clipId, extension string) string {
+	return fmt.Sprintf("%s/%s/%s/clips/%s%s", outputDir, userId, sessionId, clipId, extension)
+}
+
+// Folder with the audio chunks and the manifest uploaded by the client
+func RecordingSessionPrefix(userId, sessionId string) string {
+	return fmt.Sprintf("%s/%s/%s/", recordingSessionsDir, userId, sessionId)
+}
+
+// Folder with the files generated while processing a recording session, like the final audio
+func OutputsPrefix(userId, sessionId string) string {
+	return fmt.Sprintf("%s/%s/%s/", outputDir, userId, sessionId)
+}
+
+func ManifestPath(userId, sessionId string) string {
+	return fmt.Sprintf("%s/%s/%s/manifest.json", recordingSessionsDir, userId, sessionId)
+}
+
+// chunk-000123.webm, the index is zero padded so the names sort like the indexes
+func ChunkPath(userId, sessionId string, index int, extension string) string {
+	return fmt.Sprintf("%s/%s/%s/chunk-%06d%s", recordingSessionsDir, userId, sessionId, index, extension)
 }
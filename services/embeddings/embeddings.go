@@ -0,0 +1,31 @@
+package embeddings
+
+import (
+	"context"
+	cnfgs "eavesdropper/configurations"
+	"fmt"
+)
+
+type TaskType string
+
+const (
+	RetrievalDocument TaskType = "RETRIEVAL_DOCUMENT" // for the stored passages
+	RetrievalQuery    TaskType = "RETRIEVAL_QUERY"    // for the search text
+)
+
+// Turns texts into vectors of cnfgs.EmbeddingDimensions size, in the same order as the input
+type Provider interface {
+	Name() cnfgs.EmbeddingProvider
+	Embed(ctx context.Context, texts []string, taskType TaskType) ([][]float32, error)
+}
+
+func GetProvider() (Provider, error) {
+	switch cnfgs.SelectedEmbeddingProvider {
+	case cnfgs.GeminiEmbeddings:
+		return &GeminiProvider{}, nil
+	case cnfgs.LocalEmbeddings:
+		return &LocalProvider{}, nil
+	default:
+		return nil, fmt.Errorf("unknown embedding provider: %s", cnfgs.SelectedEmbeddingProvider)
+	}
+}
@@ -0,0 +1,59 @@
+package embeddings
+
+import (
+	"context"
+	cnfgs "eavesdropper/configurations"
+	"fmt"
+
+	"google.golang.org/genai"
+)
+
+// The gemini API accepts at most this many texts per embed request
+const geminiMaxBatchSize = 100
+
+type GeminiProvider struct{}
+
+func (p *GeminiProvider) Name() cnfgs.EmbeddingProvider {
+	return cnfgs.GeminiEmbeddings
+}
+
+func (p *GeminiProvider) Embed(ctx context.Context, texts []string, taskType TaskType) ([][]float32, error) {
+	client, err := genai.NewClient(ctx, &genai.ClientConfig{
+		APIKey:  cnfgs.GenAIApiKey,
+		Backend: genai.BackendGeminiAPI,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	dimensions := int32(cnfgs.EmbeddingDimensions)
+	config := &genai.EmbedContentConfig{
+		TaskType:             string(taskType),
+		OutputDimensionality: &dimensions,
+	}
+
+	vectors := make([][]float32, 0, len(texts))
+	for start := 0; start < len(texts); start += geminiMaxBatchSize {
+		end := min(start+geminiMaxBatchSize, len(texts))
+
+		contents := make([]*genai.Content, 0, end-start)
+		for _, text := range texts[start:end] {
+			contents = append(contents, genai.NewContentFromText(text, genai.RoleUser))
+		}
+
+		response, err := client.Models.EmbedContent(ctx, cnfgs.GeminiEmbeddingModel, contents, config)
+		if err != nil {
+			return nil, fmt.Errorf("gemini embed content: %w", err)
+		}
+		if len(response.Embeddings) != len(contents) {
+			return nil, fmt.Errorf("gemini returned %d embeddings for %d texts", len(response.Embeddings), len(contents))
+		}
+
+		// Vectors truncated to a smaller dimensionality are not unit length, normalize them for cosine/dot product use
+		for _, embedding := range response.Embeddings {
+			vectors = append(vectors, normalize(embedding.Values))
+		}
+	}
+
+	return vectors, nil
+}
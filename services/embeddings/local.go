@@ -0,0 +1,72 @@
+package embeddings
+
+import (
+	"context"
+	cnfgs "eavesdropper/configurations"
+	"hash/fnv"
+	"math"
+	"strings"
+	"unicode"
+)
+
+// Deterministic embedding based on feature hashing of words and word pairs.
+// Only captures word overlap, not meaning, but the same text always gives the same vector
+// which makes it usable in tests and without API access.
+type LocalProvider struct{}
+
+func (p *LocalProvider) Name() cnfgs.EmbeddingProvider {
+	return cnfgs.LocalEmbeddings
+}
+
+func (p *LocalProvider) Embed(ctx context.Context, texts []string, taskType TaskType) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vectors[i] = hashEmbedding(text)
+	}
+	return vectors, nil
+}
+
+func hashEmbedding(text string) []float32 {
+	vector := make([]float32, cnfgs.EmbeddingDimensions)
+
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for i, word := range words {
+		addFeature(vector, word, 1)
+		if i > 0 {
+			addFeature(vector, words[i-1]+" "+word, 0.5)
+		}
+	}
+
+	return normalize(vector)
+}
+
+// Adds the weight to a bucket picked by the feature hash, with a hash derived sign to spread collisions
+func addFeature(vector []float32, feature string, weight float32) {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(feature))
+	sum := h.Sum64()
+
+	bucket := sum % uint64(len(vector))
+	if sum>>63 == 1 {
+		weight = -weight
+	}
+	vector[bucket] += weight
+}
+
+// Scales the vector to unit length. A zero vector is returned unchanged.
+func normalize(vector []float32) []float32 {
+	var sum float64
+	for _, v := range vector {
+		sum += float64(v) * float64(v)
+	}
+	if sum == 0 {
+		return vector
+	}
+	norm := float32(math.Sqrt(sum))
+	for i := range vector {
+		vector[i] /= norm
+	}
+	return vector
+}
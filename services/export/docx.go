@@ -0,0 +1,107 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"strconv"
+	"strings"
+)
+
+const docxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">
+<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>
+<Default Extension="xml" ContentType="application/xml"/>
+<Override PartName="/word/document.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.document.main+xml"/>
+</Types>`
+
+const docxRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="word/document.xml"/>
+</Relationships>`
+
+// Builds a minimal WordprocessingML package (content types, relationships and the document body).
+// Styling is applied inline on each run so no styles.xml part is needed.
+func renderDocx(doc *document) ([]byte, error) {
+	var body bytes.Buffer
+
+	writeDocxParagraph(&body, docxRun{text: doc.Title, bold: true, size: 36})
+	writeDocxParagraph(&body, docxRun{text: "Created: ", bold: true}, docxRun{text: formatDate(doc.CreatedAt)})
+	writeDocxParagraph(&body, docxRun{text: "Duration: ", bold: true}, docxRun{text: formatDuration(doc.DurationSeconds)})
+	writeDocxParagraph(&body, docxRun{text: "Speakers: ", bold: true}, docxRun{text: speakersLine(doc.Speakers)})
+
+	writeDocxParagraph(&body, docxRun{text: "Transcript", bold: true, size: 28})
+	for _, segment := range doc.Segments {
+		runs := []docxRun{}
+		if segment.Speaker != "" {
+			runs = append(runs, docxRun{text: segment.Speaker + ": ", bold: true})
+		}
+		runs = append(runs, docxRun{text: segment.Text})
+		writeDocxParagraph(&body, runs...)
+	}
+
+	var documentXML bytes.Buffer
+	documentXML.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>`)
+	documentXML.WriteString(`<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>`)
+	documentXML.Write(body.Bytes())
+	documentXML.WriteString(`</w:body></w:document>`)
+
+	var out bytes.Buffer
+	zw := zip.NewWriter(&out)
+	parts := []struct {
+		name    string
+		content []byte
+	}{
+		{"[Content_Types].xml", []byte(docxContentTypes)},
+		{"_rels/.rels", []byte(docxRels)},
+		{"word/document.xml", documentXML.Bytes()},
+	}
+	for _, part := range parts {
+		f, err := zw.Create(part.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := f.Write(part.content); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+
+	return out.Bytes(), nil
+}
+
+type docxRun struct {
+	text string
+	bold bool
+	size int // in half points, 0 keeps the default
+}
+
+func writeDocxParagraph(b *bytes.Buffer, runs ...docxRun) {
+	b.WriteString("<w:p>")
+	for _, run := range runs {
+		b.WriteString("<w:r>")
+		if run.bold || run.size > 0 {
+			b.WriteString("<w:rPr>")
+			if run.bold {
+				b.WriteString("<w:b/>")
+			}
+			if run.size > 0 {
+				b.WriteString(`<w:sz w:val="` + strconv.Itoa(run.size) + `"/>`)
+			}
+			b.WriteString("</w:rPr>")
+		}
+		// Line breaks inside a run must be explicit <w:br/> elements
+		for i, line := range strings.Split(run.text, "\n") {
+			if i > 0 {
+				b.WriteString("<w:br/>")
+			}
+			b.WriteString(`<w:t xml:space="preserve">`)
+			_ = xml.EscapeText(b, []byte(line))
+			b.WriteString("</w:t>")
+		}
+		b.WriteString("</w:r>")
+	}
+	b.WriteString("</w:p>")
+}
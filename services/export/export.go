@@ -0,0 +1,111 @@
+package export
+
+import (
+	"eavesdropper/dtos/resources"
+	"eavesdropper/services/transcripts"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+type Format string
+
+const (
+	Markdown Format = "md"
+	Text     Format = "txt"
+	Docx     Format = "docx"
+	Pdf      Format = "pdf"
+)
+
+var contentTypes = map[Format]string{
+	Markdown: "text/markdown; charset=utf-8",
+	Text:     "text/plain; charset=utf-8",
+	Docx:     "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+	Pdf:      "application/pdf",
+}
+
+func IsSupportedFormat(format string) bool {
+	_, ok := contentTypes[Format(format)]
+	return ok
+}
+
+// Format agnostic representation of an exported transcript.
+// Every format writer renders the same fields in the same order.
+// Transcripts have no summary yet, a summary section belongs here once one is generated.
+type document struct {
+	Title           string
+	CreatedAt       time.Time
+	DurationSeconds int
+	Speakers        []string
+	Segments        []transcripts.Segment
+}
+
+func newDocument(transcript *resources.Transcript) *document {
+	return &document{
+		Title:           transcript.Tittle,
+		CreatedAt:       transcript.CreatedAt,
+		DurationSeconds: transcript.ConsumedInputAudioSeconds,
+		Speakers:        transcripts.Speakers(transcript.Content),
+		Segments:        transcripts.ParseSegments(transcript.Content),
+	}
+}
+
+// Renders the transcript in the requested format.
+// Returns the file bytes, its content type and a suggested file name.
+func Export(transcript *resources.Transcript, format Format) (data []byte, contentType string, fileName string, err error) {
+	contentType, ok := contentTypes[format]
+	if !ok {
+		return nil, "", "", fmt.Errorf("unsupported export format: %s", format)
+	}
+
+	doc := newDocument(transcript)
+
+	switch format {
+	case Markdown:
+		data = renderMarkdown(doc)
+	case Text:
+		data = renderText(doc)
+	case Docx:
+		data, err = renderDocx(doc)
+	case Pdf:
+		data = renderPdf(doc)
+	}
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	return data, contentType, fileNameFor(doc.Title, format), nil
+}
+
+var unsafeFileNameChars = regexp.MustCompile(`[^\w\-. ]+`)
+
+func fileNameFor(title string, format Format) string {
+	name := strings.TrimSpace(unsafeFileNameChars.ReplaceAllString(title, ""))
+	if name == "" {
+		name = "transcript"
+	}
+	return name + "." + string(format)
+}
+
+func formatDate(t time.Time) string {
+	return t.UTC().Format("2006-01-02 15:04 MST")
+}
+
+// 3723 -> "1h 02m 03s"
+func formatDuration(seconds int) string {
+	h := seconds / 3600
+	m := (seconds % 3600) / 60
+	s := seconds % 60
+	if h > 0 {
+		return fmt.Sprintf("%dh %02dm %02ds", h, m, s)
+	}
+	return fmt.Sprintf("%dm %02ds", m, s)
+}
+
+func speakersLine(speakers []string) string {
+	if len(speakers) == 0 {
+		return "-"
+	}
+	return strings.Join(speakers, ", ")
+}
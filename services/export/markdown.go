@@ -0,0 +1,27 @@
+package export
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+func renderMarkdown(doc *document) []byte {
+	var b bytes.Buffer
+
+	fmt.Fprintf(&b, "# %s\n\n", doc.Title)
+	fmt.Fprintf(&b, "- **Created:** %s\n", formatDate(doc.CreatedAt))
+	fmt.Fprintf(&b, "- **Duration:** %s\n", formatDuration(doc.DurationSeconds))
+	fmt.Fprintf(&b, "- **Speakers:** %s\n\n", speakersLine(doc.Speakers))
+
+	b.WriteString("## Transcript\n\n")
+	for _, segment := range doc.Segments {
+		if segment.Speaker != "" {
+			fmt.Fprintf(&b, "**%s:** ", segment.Speaker)
+		}
+		// Two trailing spaces keep the line breaks inside a speaker turn
+		fmt.Fprintf(&b, "%s\n\n", strings.ReplaceAll(segment.Text, "\n", "  \n"))
+	}
+
+	return b.Bytes()
+}
@@ -0,0 +1,242 @@
+package export
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// Minimal PDF 1.4 writer using the standard Helvetica fonts, which every viewer ships,
+// so no font needs to be embedded. Text is encoded in WinAnsi; characters outside it become '?'.
+
+const (
+	pdfPageWidth    = 595.0 // A4 in points
+	pdfPageHeight   = 842.0
+	pdfMargin       = 56.0
+	pdfBodyFontSize = 10.0
+)
+
+const (
+	pdfRegular = "F1"
+	pdfBold    = "F2"
+)
+
+type pdfLine struct {
+	font    string
+	size    float64
+	text    string
+	spacing float64 // vertical space taken by the line, including leading
+}
+
+func renderPdf(doc *document) []byte {
+	lines := []pdfLine{}
+	add := func(font string, size float64, text string, spaceAfter float64) {
+		wrapped := wrapPdfText(text, font, size, pdfPageWidth-2*pdfMargin)
+		for i, line := range wrapped {
+			spacing := size * 1.4
+			if i == len(wrapped)-1 {
+				spacing += spaceAfter
+			}
+			lines = append(lines, pdfLine{font: font, size: size, text: line, spacing: spacing})
+		}
+	}
+
+	add(pdfBold, 18, doc.Title, 8)
+	add(pdfRegular, pdfBodyFontSize, "Created: "+formatDate(doc.CreatedAt), 0)
+	add(pdfRegular, pdfBodyFontSize, "Duration: "+formatDuration(doc.DurationSeconds), 0)
+	add(pdfRegular, pdfBodyFontSize, "Speakers: "+speakersLine(doc.Speakers), 12)
+
+	add(pdfBold, 13, "Transcript", 4)
+	for _, segment := range doc.Segments {
+		text := segment.Text
+		if segment.Speaker != "" {
+			text = segment.Speaker + ": " + text
+		}
+		for i, paragraph := range strings.Split(text, "\n") {
+			spaceAfter := 0.0
+			if i == strings.Count(text, "\n") {
+				spaceAfter = 6
+			}
+			add(pdfRegular, pdfBodyFontSize, paragraph, spaceAfter)
+		}
+	}
+
+	// Splits the lines into pages
+	pages := [][]pdfLine{}
+	current := []pdfLine{}
+	y := pdfPageHeight - pdfMargin
+	for _, line := range lines {
+		if y-line.spacing < pdfMargin && len(current) > 0 {
+			pages = append(pages, current)
+			current = []pdfLine{}
+			y = pdfPageHeight - pdfMargin
+		}
+		current = append(current, line)
+		y -= line.spacing
+	}
+	pages = append(pages, current)
+
+	return writePdf(pages)
+}
+
+func writePdf(pages [][]pdfLine) []byte {
+	var b bytes.Buffer
+	offsets := []int{}
+	startObject := func() int {
+		offsets = append(offsets, b.Len())
+		id := len(offsets)
+		fmt.Fprintf(&b, "%d 0 obj\n", id)
+		return id
+	}
+
+	b.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	// Object ids are fixed for the catalog, the page tree and the fonts. Pages follow in pairs (page, content).
+	startObject()
+	b.WriteString("<< /Type /Catalog /Pages 2 0 R >>\nendobj\n")
+
+	startObject()
+	kids := []string{}
+	for i := range pages {
+		kids = append(kids, fmt.Sprintf("%d 0 R", 5+2*i))
+	}
+	fmt.Fprintf(&b, "<< /Type /Pages /Kids [%s] /Count %d >>\nendobj\n", strings.Join(kids, " "), len(pages))
+
+	startObject()
+	b.WriteString("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>\nendobj\n")
+
+	startObject()
+	b.WriteString("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>\nendobj\n")
+
+	for _, page := range pages {
+		pageID := startObject()
+		fmt.Fprintf(&b,
+			"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.0f %.0f] /Resources << /Font << /%s 3 0 R /%s 4 0 R >> >> /Contents %d 0 R >>\nendobj\n",
+			pdfPageWidth, pdfPageHeight, pdfRegular, pdfBold, pageID+1,
+		)
+
+		var content bytes.Buffer
+		y := pdfPageHeight - pdfMargin
+		for _, line := range page {
+			y -= line.size
+			fmt.Fprintf(&content, "BT /%s %.1f Tf %.2f %.2f Td (%s) Tj ET\n", line.font, line.size, pdfMargin, y, escapePdfText(line.text))
+			y -= line.spacing - line.size
+		}
+
+		startObject()
+		fmt.Fprintf(&b, "<< /Length %d >>\nstream\n", content.Len())
+		b.Write(content.Bytes())
+		b.WriteString("endstream\nendobj\n")
+	}
+
+	xrefOffset := b.Len()
+	fmt.Fprintf(&b, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&b, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&b, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xrefOffset)
+
+	return b.Bytes()
+}
+
+// WinAnsi code points for the typographic characters gemini tends to output
+var winAnsiExtras = map[rune]byte{
+	'€': 0x80, '…': 0x85, '‘': 0x91, '’': 0x92, '“': 0x93, '”': 0x94, '•': 0x95, '–': 0x96, '—': 0x97,
+}
+
+func toWinAnsi(r rune) byte {
+	if r == '\t' {
+		return ' '
+	}
+	if (r >= 0x20 && r < 0x7f) || (r >= 0xa0 && r <= 0xff) {
+		return byte(r)
+	}
+	if c, ok := winAnsiExtras[r]; ok {
+		return c
+	}
+	return '?'
+}
+
+func escapePdfText(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		c := toWinAnsi(r)
+		switch {
+		case c == '(' || c == ')' || c == '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case c >= 0x80:
+			fmt.Fprintf(&b, "\\%03o", c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// Helvetica advance widths for printable ASCII (32..126), in 1/1000 of the font size
+var helveticaWidths = [95]int{
+	278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+	1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+	333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+	556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+}
+
+func pdfTextWidth(text, font string, size float64) float64 {
+	total := 0
+	for _, r := range text {
+		c := toWinAnsi(r)
+		if c >= 32 && c <= 126 {
+			total += helveticaWidths[c-32]
+		} else {
+			total += 556
+		}
+	}
+	width := float64(total) * size / 1000
+	if font == pdfBold {
+		width *= 1.06 // Helvetica-Bold is slightly wider, close enough for wrapping
+	}
+	return width
+}
+
+// Greedy word wrap. Words wider than a full line are hard split.
+func wrapPdfText(text, font string, size, maxWidth float64) []string {
+	words := strings.Fields(text)
+	if len(words) == 0 {
+		return []string{""}
+	}
+
+	lines := []string{}
+	current := ""
+	for _, word := range words {
+		for pdfTextWidth(word, font, size) > maxWidth {
+			runes := []rune(word)
+			cut := len(runes) - 1
+			for cut > 1 && pdfTextWidth(string(runes[:cut]), font, size) > maxWidth {
+				cut--
+			}
+			if current != "" {
+				lines = append(lines, current)
+				current = ""
+			}
+			lines = append(lines, string(runes[:cut]))
+			word = string(runes[cut:])
+		}
+
+		candidate := word
+		if current != "" {
+			candidate = current + " " + word
+		}
+		if pdfTextWidth(candidate, font, size) > maxWidth {
+			lines = append(lines, current)
+			current = word
+			continue
+		}
+		current = candidate
+	}
+	lines = append(lines, current)
+
+	return lines
+}
@@ -0,0 +1,26 @@
+package export
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+func renderText(doc *document) []byte {
+	var b bytes.Buffer
+
+	fmt.Fprintf(&b, "%s\n%s\n\n", doc.Title, strings.Repeat("=", len([]rune(doc.Title))))
+	fmt.Fprintf(&b, "Created:  %s\n", formatDate(doc.CreatedAt))
+	fmt.Fprintf(&b, "Duration: %s\n", formatDuration(doc.DurationSeconds))
+	fmt.Fprintf(&b, "Speakers: %s\n\n", speakersLine(doc.Speakers))
+
+	b.WriteString("Transcript\n----------\n")
+	for _, segment := range doc.Segments {
+		if segment.Speaker != "" {
+			fmt.Fprintf(&b, "%s: ", segment.Speaker)
+		}
+		fmt.Fprintf(&b, "%s\n\n", segment.Text)
+	}
+
+	return b.Bytes()
+}
@@ -0,0 +1,208 @@
+package folders
+
+import (
+	"context"
+	"eavesdropper/dtos/requests"
+	"eavesdropper/dtos/resources"
+	"eavesdropper/dtos/responses"
+	"eavesdropper/errs"
+	db "eavesdropper/services/data/firestore/operations"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const maxFolderNameLength = 100
+
+// What happens to the transcripts and subfolders of a deleted folder
+type ContentsAction string
+
+const (
+	AskForContents ContentsAction = ""      // only empty folders are deleted
+	MoveContentsUp ContentsAction = "move"  // contents go to the parent of the deleted folder
+	TrashContents  ContentsAction = "trash" // transcripts go to the trash, subfolders are deleted
+)
+
+func IsValidContentsAction(action string) bool {
+	switch ContentsAction(action) {
+	case AskForContents, MoveContentsUp, TrashContents:
+		return true
+	}
+	return false
+}
+
+func CreateFolder(ctx context.Context, userID string, req *requests.CreateFolder) (*resources.Folder, error) {
+	name, err := validateName(req.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.ParentID != "" {
+		if _, err := db.GetFolder(ctx, userID, req.ParentID); err != nil {
+			return nil, err
+		}
+	}
+
+	return db.CreateFolder(ctx, userID, name, req.ParentID)
+}
+
+func GetFolders(ctx context.Context, userID string) ([]resources.Folder, error) {
+	return db.GetFolders(ctx, userID)
+}
+
+// Renames and/or moves the folder. Fails with errs.ErrFolderCycle when moved inside itself.
+func UpdateFolder(ctx context.Context, userID, folderID string, req *requests.UpdateFolder) (*resources.Folder, error) {
+	folder, err := db.GetFolder(ctx, userID, folderID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil {
+		folder.Name, err = validateName(*req.Name)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if req.ParentID != nil && *req.ParentID != folder.ParentID {
+		if *req.ParentID != "" {
+			allFolders, err := db.GetFolders(ctx, userID)
+			if err != nil {
+				return nil, err
+			}
+			byID := map[string]resources.Folder{}
+			for _, f := range allFolders {
+				byID[f.ID] = f
+			}
+			if _, ok := byID[*req.ParentID]; !ok {
+				return nil, errs.ErrFolderNotFound
+			}
+			// Walks up from the new parent, reaching the folder means it would be inside itself
+			for id := *req.ParentID; id != ""; id = byID[id].ParentID {
+				if id == folder.ID {
+					return nil, errs.ErrFolderCycle
+				}
+			}
+		}
+		folder.ParentID = *req.ParentID
+	}
+
+	err = db.UpdateFolder(ctx, userID, folder)
+	if err != nil {
+		return nil, err
+	}
+	return folder, nil
+}
+
+// Deletes the folder and handles its contents according to the action.
+// With AskForContents a folder with contents is not deleted, errs.ErrFolderNotEmpty is returned
+// along with the counts of what it holds so the user can choose.
+func DeleteFolder(ctx context.Context, userID, folderID string, action ContentsAction) (*responses.DeleteFolderResponse, error) {
+	folder, err := db.GetFolder(ctx, userID, folderID)
+	if err != nil {
+		return nil, err
+	}
+
+	allFolders, err := db.GetFolders(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	children := map[string][]string{}
+	for _, f := range allFolders {
+		children[f.ParentID] = append(children[f.ParentID], f.ID)
+	}
+
+	transcriptIDs, err := db.GetFolderTranscriptIDs(ctx, userID, folder.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &responses.DeleteFolderResponse{}
+
+	switch action {
+	case AskForContents:
+		if len(transcriptIDs) > 0 || len(children[folder.ID]) > 0 {
+			response.ContainedTranscripts = len(transcriptIDs)
+			response.ContainedFolders = len(children[folder.ID])
+			return response, errs.ErrFolderNotEmpty
+		}
+
+	case MoveContentsUp:
+		for start := 0; start < len(transcriptIDs); start += db.MaxBulkTranscripts {
+			end := min(start+db.MaxBulkTranscripts, len(transcriptIDs))
+			err = db.MoveTranscriptsToFolder(ctx, userID, transcriptIDs[start:end], folder.ParentID)
+			if err != nil {
+				return nil, err
+			}
+		}
+		response.MovedTranscripts = len(transcriptIDs)
+
+		for _, childID := range children[folder.ID] {
+			child, err := db.GetFolder(ctx, userID, childID)
+			if err != nil {
+				return nil, err
+			}
+			child.ParentID = folder.ParentID
+			err = db.UpdateFolder(ctx, userID, child)
+			if err != nil {
+				return nil, err
+			}
+			response.MovedFolders++
+		}
+
+	case TrashContents:
+		// Depth first so a failure never leaves subfolders without their parent
+		var trashFolder func(id string, ids []string) error
+		trashFolder = func(id string, ids []string) error {
+			for _, childID := range children[id] {
+				childTranscriptIDs, err := db.GetFolderTranscriptIDs(ctx, userID, childID)
+				if err != nil {
+					return err
+				}
+				if err := trashFolder(childID, childTranscriptIDs); err != nil {
+					return err
+				}
+			}
+			for _, transcriptID := range ids {
+				_, trashedNow, err := db.TrashTranscript(ctx, userID, transcriptID)
+				if err != nil && !errors.Is(err, errs.ErrTranscriptNotFound) {
+					return err
+				}
+				// Deleted meanwhile or already in the trash
+				if trashedNow {
+					response.TrashedTranscripts++
+				}
+			}
+			if id == folder.ID {
+				return nil
+			}
+			response.DeletedFolders++
+			return db.DeleteFolder(ctx, userID, id)
+		}
+		if err := trashFolder(folder.ID, transcriptIDs); err != nil {
+			return nil, err
+		}
+
+	default:
+		return nil, fmt.Errorf("unknown folder contents action: %s", action)
+	}
+
+	err = db.DeleteFolder(ctx, userID, folder.ID)
+	if err != nil {
+		return nil, err
+	}
+	response.DeletedFolders++
+
+	return response, nil
+}
+
+func validateName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", fmt.Errorf("%w: the name cannot be empty", errs.ErrInvalidFolderName)
+	}
+	if len([]rune(name)) > maxFolderNameLength {
+		return "", fmt.Errorf("%w: the name cannot be longer than %d characters", errs.ErrInvalidFolderName, maxFolderNameLength)
+	}
+	return name, nil
+}
@@ -0,0 +1,67 @@
+package pagination
+
+import (
+	"eavesdropper/errs"
+	"encoding/base64"
+	"encoding/json"
+	"time"
+)
+
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+)
+
+// Position after the last item of a page. Listings are ordered by CreatedAt desc and ID desc,
+// the ID breaks ties between items created at the same instant.
+// Listings with other sorts also keep the sort they belong to and the value of the sorted field.
+// Clients only see it encoded, as an opaque string.
+type Cursor struct {
+	CreatedAt time.Time `json:"c"`
+	ID        string    `json:"i"`
+	Sort      string    `json:"s,omitempty"` // empty for the default newest first order
+	Seconds   int       `json:"d,omitempty"` // when sorted by duration
+	Title     string    `json:"t,omitempty"` // when sorted by title
+}
+
+func NewCursor(createdAt time.Time, id string) *Cursor {
+	return &Cursor{CreatedAt: createdAt, ID: id}
+}
+
+func (c *Cursor) Encode() string {
+	if c == nil {
+		return ""
+	}
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// An empty string decodes to a nil cursor, which means the first page
+func Decode(encoded string) (*Cursor, error) {
+	if encoded == "" {
+		return nil, nil
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, errs.ErrInvalidCursor
+	}
+
+	cursor := new(Cursor)
+	if err := json.Unmarshal(data, cursor); err != nil || cursor.ID == "" || cursor.CreatedAt.IsZero() {
+		return nil, errs.ErrInvalidCursor
+	}
+
+	return cursor, nil
+}
+
+// Applies the server side page size bounds. Non positive values get the default.
+func ClampLimit(limit int) int {
+	if limit <= 0 {
+		return DefaultPageSize
+	}
+	if limit > MaxPageSize {
+		return MaxPageSize
+	}
+	return limit
+}
@@ -0,0 +1,62 @@
+package recordings
+
+import (
+	"context"
+	cnfgs "eavesdropper/configurations"
+	db "eavesdropper/services/data/firestore/operations"
+	cloudStorage "eavesdropper/services/data/storage"
+	"log"
+	"time"
+)
+
+// Sessions expired per run, the rest wait for the next run
+const expiryBatchSize = 100
+
+// Expires the sessions without uploads for cnfgs.RecordingSessionIdleTimeout and deletes their chunks.
+// Returns how many were expired.
+func ExpireAbandonedSessions(ctx context.Context) (int, error) {
+	abandoned, err := db.GetAbandonedRecordingSessions(ctx, time.Now(), expiryBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	expired := 0
+	for _, owned := range abandoned {
+		ok, err := db.ExpireRecordingSession(ctx, owned.OwnerID, owned.Session.ID)
+		if err != nil {
+			log.Printf("[Recordings] failed to expire session %s: %s", owned.Session.ID, err)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		_, err = cloudStorage.DeletePrefix(ctx, cloudStorage.RecordingSessionPrefix(owned.OwnerID, owned.Session.ID))
+		if err != nil {
+			log.Printf("[Recordings] failed to delete the chunks of session %s: %s", owned.Session.ID, err)
+		}
+		expired++
+	}
+
+	return expired, nil
+}
+
+// Runs ExpireAbandonedSessions every cnfgs.RecordingSessionExpiryInterval until the context is cancelled
+func RunSessionExpiry(ctx context.Context) {
+	ticker := time.NewTicker(cnfgs.RecordingSessionExpiryInterval)
+	defer ticker.Stop()
+
+	for {
+		expired, err := ExpireAbandonedSessions(ctx)
+		if err != nil {
+			log.Printf("[Recordings] expiry failed: %s", err)
+		} else if expired > 0 {
+			log.Printf("[Recordings] expired %d abandoned sessions", expired)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package recordings
+
+import (
+	"crypto/sha256"
+	"eavesdropper/dtos/resources"
+	"eavesdropper/errs"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// How a transcription handles missing, repeated and corrupt chunks
+type IntegrityMode string
+
+const (
+	// Any missing, repeated or corrupt chunk fails the transcription
+	IntegrityStrict IntegrityMode = "strict"
+	// Transcribes the chunks that are fine and records the audio left out in the transcript
+	IntegrityLenient IntegrityMode = "lenient"
+)
+
+const DefaultIntegrityMode = IntegrityStrict
+
+func IsValidIntegrityMode(mode string) bool {
+	switch IntegrityMode(mode) {
+	case IntegrityStrict, IntegrityLenient:
+		return true
+	}
+	return false
+}
+
+// Chunks of a manifest to download, in index order
+type ChunkPlan struct {
+	Chunks       []PlannedChunk
+	Missing      []int // indexes of the expected chunks the manifest does not list
+	FirstIndex   int   // index of the first chunk of the recording
+	ChunkSeconds float64
+}
+
+// The manifest entries of an index. There is more than one only in lenient mode,
+// when the manifest repeats the index, and the first one that verifies is used.
+type PlannedChunk struct {
+	Index      int
+	Candidates []resources.ManifestChunk
+}
+
+// Orders the chunks of a validated manifest and detects its gaps and repeated indexes.
+// v2 manifests index the chunks from 0 to Count-1. v1 manifests are read from their lowest index,
+// clients numbered the chunks from 0 or from 1.
+// In strict mode gaps fail with errs.ErrMissingRecordingChunks and repeated indexes with errs.ErrInvalidManifest.
+func PlanChunks(manifest *resources.RecordingManifest, mode IntegrityMode) (*ChunkPlan, error) {
+	chunks := manifestChunks(manifest)
+	if len(chunks) == 0 {
+		return nil, errs.NoAudioChunksInManifest
+	}
+
+	byIndex := map[int][]resources.ManifestChunk{}
+	lowest, highest := chunks[0].Index, chunks[0].Index
+	for _, chunk := range chunks {
+		if len(byIndex[chunk.Index]) > 0 && mode == IntegrityStrict {
+			return nil, fmt.Errorf("%w: chunk index %d is repeated", errs.ErrInvalidManifest, chunk.Index)
+		}
+		byIndex[chunk.Index] = append(byIndex[chunk.Index], chunk)
+		lowest, highest = min(lowest, chunk.Index), max(highest, chunk.Index)
+	}
+
+	plan := &ChunkPlan{FirstIndex: lowest, ChunkSeconds: manifest.ChunkSeconds}
+	if manifest.Version >= resources.RecordingManifestV2 {
+		plan.FirstIndex = 0
+	}
+	last := plan.FirstIndex + manifest.Count - 1
+	if highest > last {
+		if mode == IntegrityStrict {
+			return nil, fmt.Errorf("%w: count %d does not cover chunk index %d", errs.ErrInvalidManifest, manifest.Count, highest)
+		}
+		last = highest
+	}
+
+	for index := plan.FirstIndex; index <= last; index++ {
+		candidates, ok := byIndex[index]
+		if !ok {
+			plan.Missing = append(plan.Missing, index)
+			continue
+		}
+		plan.Chunks = append(plan.Chunks, PlannedChunk{Index: index, Candidates: candidates})
+	}
+
+	if len(plan.Missing) > 0 && mode == IntegrityStrict {
+		return nil, fmt.Errorf("%w: %s", errs.ErrMissingRecordingChunks, describeIndexes(plan.Missing))
+	}
+	if len(plan.Chunks) == 0 {
+		return nil, errs.NoAudioChunksInManifest
+	}
+
+	return plan, nil
+}
+
+// Checks the downloaded chunk against the size and SHA-256 of its manifest entry.
+// v1 entries have neither and are only checked not to be empty.
+func VerifyChunk(content io.Reader, chunk resources.ManifestChunk) error {
+	hash := sha256.New()
+	size, err := io.Copy(hash, content)
+	if err != nil {
+		return fmt.Errorf("read chunk %d: %w", chunk.Index, err)
+	}
+
+	if size == 0 {
+		return fmt.Errorf("%w: chunk %d is empty", errs.ErrCorruptRecordingChunk, chunk.Index)
+	}
+	if chunk.Size > 0 && size != chunk.Size {
+		return fmt.Errorf("%w: chunk %d has %d bytes, the manifest says %d", errs.ErrCorruptRecordingChunk, chunk.Index, size, chunk.Size)
+	}
+	if chunk.SHA256 != "" && !strings.EqualFold(hex.EncodeToString(hash.Sum(nil)), chunk.SHA256) {
+		return fmt.Errorf("%w: chunk %d does not match its SHA-256", errs.ErrCorruptRecordingChunk, chunk.Index)
+	}
+
+	return nil
+}
+
+// Groups the missing chunk indexes in ranges, with the recording seconds they covered when the chunk duration is known
+func MissingRanges(plan *ChunkPlan, missing []int) []resources.MissingAudioRange {
+	if len(missing) == 0 {
+		return nil
+	}
+	indexes := make([]int, len(missing))
+	copy(indexes, missing)
+	sort.Ints(indexes)
+
+	ranges := []resources.MissingAudioRange{}
+	for _, index := range indexes {
+		last := len(ranges) - 1
+		if last >= 0 && ranges[last].ToChunk >= index-1 {
+			ranges[last].ToChunk = max(ranges[last].ToChunk, index)
+			continue
+		}
+		ranges = append(ranges, resources.MissingAudioRange{FromChunk: index, ToChunk: index})
+	}
+
+	if plan.ChunkSeconds > 0 {
+		for i := range ranges {
+			start := float64(ranges[i].FromChunk-plan.FirstIndex) * plan.ChunkSeconds
+			end := float64(ranges[i].ToChunk-plan.FirstIndex+1) * plan.ChunkSeconds
+			ranges[i].StartSeconds, ranges[i].EndSeconds = &start, &end
+		}
+	}
+
+	return ranges
+}
+
+// The chunk entries of the manifest, from the details in v2 and from the paths in v1.
+// Paths without an index get -1, ValidateManifest rejects them.
+func manifestChunks(manifest *resources.RecordingManifest) []resources.ManifestChunk {
+	if manifest.Version >= resources.RecordingManifestV2 {
+		return manifest.ChunkDetails
+	}
+	chunks := make([]resources.ManifestChunk, len(manifest.Chunks))
+	for i, path := range manifest.Chunks {
+		index, ok := ChunkIndex(path)
+		if !ok {
+			index = -1
+		}
+		chunks[i] = resources.ManifestChunk{Index: index, Path: path}
+	}
+	return chunks
+}
+
+// "3, 7 to 9" for the sorted indexes 3, 7, 8 and 9
+func describeIndexes(indexes []int) string {
+	parts := []string{}
+	for _, missing := range MissingRanges(&ChunkPlan{}, indexes) {
+		if missing.FromChunk == missing.ToChunk {
+			parts = append(parts, fmt.Sprintf("%d", missing.FromChunk))
+		} else {
+			parts = append(parts, fmt.Sprintf("%d to %d", missing.FromChunk, missing.ToChunk))
+		}
+	}
+	return "missing chunks " + strings.Join(parts, ", ")
+}
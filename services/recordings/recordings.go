@@ -0,0 +1,285 @@
+package recordings
+
+import (
+	"context"
+	"crypto/sha256"
+	cnfgs "eavesdropper/configurations"
+	"eavesdropper/dtos/resources"
+	"eavesdropper/dtos/responses"
+	"eavesdropper/errs"
+	db "eavesdropper/services/data/firestore/operations"
+	cloudStorage "eavesdropper/services/data/storage"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"mime"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/storage"
+)
+
+const (
+	// Upload URLs handed out per request
+	MaxUploadURLsPerRequest = 100
+	// Highest chunk index of a session, about 27 hours of 1 second chunks
+	maxChunkIndex = 99999
+)
+
+// Starts a recording session for the MIME type the client records in.
+// chunkSeconds is the recording time of each chunk, 0 when unknown. It places missing chunks in the recording.
+func StartSession(ctx context.Context, userID, mimeType string, chunkSeconds float64) (*resources.RecordingSession, error) {
+	baseType, err := recordingMIME(mimeType)
+	if err != nil {
+		return nil, err
+	}
+	if chunkSeconds < 0 {
+		return nil, fmt.Errorf("%w: negative chunk duration", errs.ErrInvalidRecordingChunk)
+	}
+	return db.CreateRecordingSession(ctx, userID, baseType, chunkSeconds)
+}
+
+func GetSession(ctx context.Context, userID, sessionID string) (*resources.RecordingSession, error) {
+	return db.GetRecordingSession(ctx, userID, sessionID)
+}
+
+// Signs an upload URL for each chunk index. The client PUTs each chunk with the session MIME type as Content-Type,
+// then registers it. Keeps the session alive.
+func GetUploadURLs(ctx context.Context, userID, sessionID string, indexes []int) ([]responses.ChunkUploadURL, error) {
+	if len(indexes) == 0 || len(indexes) > MaxUploadURLsPerRequest {
+		return nil, fmt.Errorf("%w: between 1 and %d chunk indexes must be given", errs.ErrInvalidRecordingChunk, MaxUploadURLsPerRequest)
+	}
+	for _, index := range indexes {
+		if index < 0 || index > maxChunkIndex {
+			return nil, fmt.Errorf("%w: chunk index %d out of range", errs.ErrInvalidRecordingChunk, index)
+		}
+	}
+
+	session, err := db.TouchRecordingSession(ctx, userID, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	expiresAt := time.Now().Add(cnfgs.ChunkUploadURLExpiry)
+	urls := make([]responses.ChunkUploadURL, len(indexes))
+	for i, index := range indexes {
+		url, err := cloudStorage.SignedUploadURL(chunkPath(userID, session, index), session.MIME, expiresAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to sign upload url: %w", err)
+		}
+		urls[i] = responses.ChunkUploadURL{
+			Index:       index,
+			URL:         url,
+			Method:      "PUT",
+			ContentType: session.MIME,
+			ExpiresAt:   expiresAt,
+		}
+	}
+	return urls, nil
+}
+
+// Registers an uploaded chunk after checking it is in storage, not empty, not too large and of the session MIME type.
+// The SHA-256 the client computed goes to the manifest and is verified when the session is transcribed.
+func RegisterChunk(ctx context.Context, userID, sessionID string, index int, sha256 string) (*resources.RecordingSession, error) {
+	if index < 0 || index > maxChunkIndex {
+		return nil, fmt.Errorf("%w: chunk index %d out of range", errs.ErrInvalidRecordingChunk, index)
+	}
+	if !isSHA256(sha256) {
+		return nil, fmt.Errorf("%w: chunk %d needs the hex SHA-256 of its content", errs.ErrInvalidRecordingChunk, index)
+	}
+
+	session, err := db.GetRecordingSession(ctx, userID, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if session.Status != resources.RecordingSessionRecording {
+		return nil, errs.ErrRecordingSessionClosed
+	}
+
+	path := chunkPath(userID, session, index)
+	attrs, err := cloudStorage.GetObjectAttrs(ctx, path)
+	if errors.Is(err, storage.ErrObjectNotExist) {
+		return nil, fmt.Errorf("%w: chunk %d was not uploaded", errs.ErrInvalidRecordingChunk, index)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read chunk %d: %w", index, err)
+	}
+	if attrs.Size == 0 || attrs.Size > cnfgs.MaxChunkBytes {
+		return nil, fmt.Errorf("%w: chunk %d has %d bytes, at most %d are accepted", errs.ErrInvalidRecordingChunk, index, attrs.Size, cnfgs.MaxChunkBytes)
+	}
+	if contentType, _ := recordingMIME(attrs.ContentType); contentType != session.MIME {
+		return nil, fmt.Errorf("%w: chunk %d is %s, the session records %s", errs.ErrInvalidRecordingChunk, index, attrs.ContentType, session.MIME)
+	}
+
+	return db.RegisterRecordingChunk(ctx, userID, sessionID, resources.RecordingChunk{
+		Index:  index,
+		Path:   path,
+		Size:   attrs.Size,
+		SHA256: strings.ToLower(sha256),
+	})
+}
+
+// Writes the v2 manifest from the registered chunks and closes the session, which can then be transcribed.
+// count is the number of chunks the client recorded, so chunks it never registered are detected as missing.
+// Nil takes the highest registered index. Finalizing a finalized session returns it as it is.
+func Finalize(ctx context.Context, userID, sessionID string, count *int) (*resources.RecordingSession, error) {
+	session, err := db.GetRecordingSession(ctx, userID, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	switch session.Status {
+	case resources.RecordingSessionFinalized:
+		return session, nil
+	case resources.RecordingSessionExpired:
+		return nil, errs.ErrRecordingSessionClosed
+	}
+
+	if len(session.Chunks) == 0 {
+		return nil, errs.NoAudioChunksInManifest
+	}
+
+	manifest := &resources.RecordingManifest{
+		UID:          userID,
+		SessionID:    sessionID,
+		Chunks:       make([]string, len(session.Chunks)),
+		Count:        session.Chunks[len(session.Chunks)-1].Index + 1,
+		MIME:         session.MIME,
+		Version:      resources.RecordingManifestV2,
+		ChunkSeconds: session.ChunkSeconds,
+		ChunkDetails: make([]resources.ManifestChunk, len(session.Chunks)),
+	}
+	if count != nil {
+		if *count < manifest.Count {
+			return nil, fmt.Errorf("%w: chunk %d was registered beyond the count of %d", errs.ErrInvalidRecordingChunk, manifest.Count-1, *count)
+		}
+		manifest.Count = *count
+	}
+	for i, chunk := range session.Chunks {
+		manifest.Chunks[i] = chunk.Path
+		manifest.ChunkDetails[i] = resources.ManifestChunk{
+			Index:  chunk.Index,
+			Path:   chunk.Path,
+			Size:   chunk.Size,
+			SHA256: chunk.SHA256,
+		}
+	}
+	if err := ValidateManifest(manifest, userID, sessionID); err != nil {
+		return nil, err
+	}
+
+	err = cloudStorage.SaveManifest(ctx, manifest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save manifest: %w", err)
+	}
+
+	return db.FinalizeRecordingSession(ctx, userID, sessionID, len(session.Chunks))
+}
+
+// Checks the session can be transcribed. Sessions the client managed itself have no server side state and pass.
+func CheckTranscribable(ctx context.Context, userID, sessionID string) error {
+	session, err := db.GetRecordingSession(ctx, userID, sessionID)
+	if errors.Is(err, errs.ErrRecordingSessionNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	switch session.Status {
+	case resources.RecordingSessionRecording:
+		return errs.ErrRecordingSessionNotFinalized
+	case resources.RecordingSessionExpired:
+		return errs.ErrRecordingSessionClosed
+	}
+	return nil
+}
+
+// Checks the manifest belongs to the user and the session, its chunks are in the session folder
+// and named after their index, and its MIME type is accepted. v2 entries need a size and a SHA-256.
+// Gaps and repeated indexes are left to PlanChunks, which knows the integrity mode.
+// Manifests written by clients before the server managed sessions go through the same checks.
+func ValidateManifest(manifest *resources.RecordingManifest, userID, sessionID string) error {
+	if manifest.UID != userID {
+		return fmt.Errorf("%w: it belongs to another user", errs.ErrInvalidManifest)
+	}
+	if manifest.SessionID != sessionID {
+		return fmt.Errorf("%w: it belongs to another session", errs.ErrInvalidManifest)
+	}
+	if manifest.Version > resources.RecordingManifestV2 {
+		return fmt.Errorf("%w: unknown version %d", errs.ErrInvalidManifest, manifest.Version)
+	}
+	if manifest.Count <= 0 || manifest.ChunkSeconds < 0 {
+		return fmt.Errorf("%w: invalid count or chunk duration", errs.ErrInvalidManifest)
+	}
+	if _, err := recordingMIME(manifest.MIME); err != nil {
+		return fmt.Errorf("%w: %s", errs.ErrInvalidManifest, err)
+	}
+
+	chunks := manifestChunks(manifest)
+	if len(chunks) == 0 {
+		return errs.NoAudioChunksInManifest
+	}
+
+	prefix := cloudStorage.RecordingSessionPrefix(userID, sessionID)
+	v2 := manifest.Version >= resources.RecordingManifestV2
+	for _, chunk := range chunks {
+		if !strings.HasPrefix(chunk.Path, prefix) || strings.Contains(strings.TrimPrefix(chunk.Path, prefix), "/") {
+			return fmt.Errorf("%w: chunk %s is outside the session folder", errs.ErrInvalidManifest, chunk.Path)
+		}
+		index, ok := ChunkIndex(chunk.Path)
+		if !ok || index > maxChunkIndex {
+			return fmt.Errorf("%w: chunk %s has no index", errs.ErrInvalidManifest, chunk.Path)
+		}
+		if !v2 {
+			continue
+		}
+		if index != chunk.Index {
+			return fmt.Errorf("%w: chunk %s is listed with index %d", errs.ErrInvalidManifest, chunk.Path, chunk.Index)
+		}
+		if chunk.Size <= 0 || chunk.Size > cnfgs.MaxChunkBytes {
+			return fmt.Errorf("%w: chunk %d has an invalid size", errs.ErrInvalidManifest, chunk.Index)
+		}
+		if !isSHA256(chunk.SHA256) {
+			return fmt.Errorf("%w: chunk %d has an invalid SHA-256", errs.ErrInvalidManifest, chunk.Index)
+		}
+	}
+
+	return nil
+}
+
+// Index of a chunk-000123.<ext> path
+func ChunkIndex(path string) (int, bool) {
+	name := path[strings.LastIndex(path, "/")+1:]
+	if !strings.HasPrefix(name, "chunk-") {
+		return 0, false
+	}
+	name = strings.TrimPrefix(name, "chunk-")
+	if dot := strings.Index(name, "."); dot >= 0 {
+		name = name[:dot]
+	}
+	var index int
+	if _, err := fmt.Sscanf(name, "%d", &index); err != nil || index < 0 {
+		return 0, false
+	}
+	return index, true
+}
+
+func isSHA256(value string) bool {
+	decoded, err := hex.DecodeString(value)
+	return err == nil && len(decoded) == sha256.Size
+}
+
+func chunkPath(userID string, session *resources.RecordingSession, index int) string {
+	return cloudStorage.ChunkPath(userID, session.ID, index, cnfgs.RecordingMIMETypes[session.MIME])
+}
+
+// The base type of an accepted recording MIME type, without its parameters (like codecs=opus)
+func recordingMIME(mimeType string) (string, error) {
+	baseType, _, err := mime.ParseMediaType(mimeType)
+	if err != nil {
+		return "", fmt.Errorf("%w: invalid MIME type %q", errs.ErrInvalidRecordingChunk, mimeType)
+	}
+	if _, ok := cnfgs.RecordingMIMETypes[baseType]; !ok {
+		return "", fmt.Errorf("%w: unsupported MIME type %s", errs.ErrInvalidRecordingChunk, baseType)
+	}
+	return baseType, nil
+}
@@ -0,0 +1,307 @@
+package search
+
+import (
+	"context"
+	"eavesdropper/dtos/resources"
+	"eavesdropper/dtos/responses"
+	"eavesdropper/errs"
+	db "eavesdropper/services/data/firestore/operations"
+	"eavesdropper/services/pagination"
+	"eavesdropper/services/transcripts"
+	"errors"
+	"log"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultResultsLimit     = 20
+	MaxResultsLimit         = 50
+	maxMatchesPerTranscript = 20
+	snippetContextBytes     = 80
+)
+
+var ErrEmptySearchQuery = errors.New("search query has no words")
+
+type Query struct {
+	Phrases  [][]string // every phrase must match. A single word is a one term phrase.
+	Speakers []string   // when set, only matches said by one of these speakers count
+	From     *time.Time
+	To       *time.Time
+	Limit    int
+}
+
+// Parses the raw search text. Text between double quotes is matched as an exact phrase,
+// every other word is matched on its own.
+// `budget "next quarter"` -> [[budget] [next quarter]]
+func ParsePhrases(text string) [][]string {
+	phrases := [][]string{}
+	for i, part := range strings.Split(text, `"`) {
+		terms := []string{}
+		for _, t := range tokenize(part) {
+			terms = append(terms, t.Term)
+		}
+		if len(terms) == 0 {
+			continue
+		}
+		// Odd parts are inside quotes
+		if i%2 == 1 {
+			phrases = append(phrases, terms)
+			continue
+		}
+		for _, term := range terms {
+			phrases = append(phrases, []string{term})
+		}
+	}
+	return phrases
+}
+
+//////// INDEXING ////////
+
+// Creates or replaces the search index entry of the transcript.
+// Must be called whenever the transcript content is saved or edited.
+func IndexTranscript(ctx context.Context, userID string, transcript *resources.Transcript) error {
+	return db.SaveTranscriptSearchIndex(ctx, userID, &resources.TranscriptSearchIndex{
+		TranscriptID: transcript.ID,
+		Terms:        distinctTerms(transcript.Content),
+		Speakers:     transcripts.Speakers(transcript.Content),
+		CreatedAt:    transcript.CreatedAt,
+		IndexedAt:    time.Now(),
+	})
+}
+
+// Must be called when the transcript is deleted. Searches remove the entries a failed cleanup left behind.
+func RemoveTranscriptIndex(ctx context.Context, userID, transcriptID string) error {
+	return db.DeleteTranscriptSearchIndex(ctx, userID, transcriptID)
+}
+
+// Rebuilds the index entries of every transcript of the user. Used for transcripts saved before indexing existed.
+func ReindexUserTranscripts(ctx context.Context, userID string) (int, error) {
+	count := 0
+	var cursor *pagination.Cursor
+	for {
+		userTranscripts, next, err := db.GetUserTranscripts(ctx, userID, nil, cursor, pagination.MaxPageSize)
+		if err != nil {
+			return count, err
+		}
+
+		for i := range userTranscripts {
+			err = IndexTranscript(ctx, userID, &userTranscripts[i])
+			if err != nil {
+				return count, err
+			}
+			count++
+		}
+
+		if next == nil {
+			return count, nil
+		}
+		cursor = next
+	}
+}
+
+//////// SEARCH ////////
+
+func SearchUserTranscripts(ctx context.Context, userID string, query *Query) (*responses.SearchResponse, error) {
+	if len(query.Phrases) == 0 {
+		return nil, ErrEmptySearchQuery
+	}
+	limit := min(query.Limit, MaxResultsLimit)
+	if limit <= 0 {
+		limit = DefaultResultsLimit
+	}
+
+	// Firestore allows a single array-contains per query, so the index is queried with the
+	// most selective looking term (the longest) and the other terms are checked in memory.
+	lookupTerm := ""
+	for _, phrase := range query.Phrases {
+		for _, term := range phrase {
+			if len(term) > len(lookupTerm) {
+				lookupTerm = term
+			}
+		}
+	}
+
+	indexes, err := db.GetTranscriptSearchIndexesWithTerm(ctx, userID, lookupTerm, query.From, query.To)
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := []resources.TranscriptSearchIndex{}
+	for _, index := range indexes {
+		if containsAllTerms(index.Terms, query.Phrases) && containsAnySpeaker(index.Speakers, query.Speakers) {
+			candidates = append(candidates, index)
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].CreatedAt.After(candidates[j].CreatedAt)
+	})
+
+	response := &responses.SearchResponse{Results: []responses.TranscriptSearchResult{}}
+	for _, candidate := range candidates {
+		if len(response.Results) >= limit {
+			break
+		}
+
+		transcript, err := db.GetUserTranscript(ctx, userID, candidate.TranscriptID)
+		if errors.Is(err, errs.ErrTranscriptNotFound) {
+			// Left behind by a deletion whose cleanup failed or raced a reindex, it must not break the search
+			if err := RemoveTranscriptIndex(ctx, userID, candidate.TranscriptID); err != nil {
+				log.Printf("[Search] failed to remove the stale index of transcript %s: %s", candidate.TranscriptID, err)
+			}
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if transcript.TrashedAt != nil {
+			continue
+		}
+
+		matches := findMatches(transcript.Content, query.Phrases, query.Speakers)
+		if len(matches) == 0 {
+			continue
+		}
+
+		result := responses.TranscriptSearchResult{
+			TranscriptID: transcript.ID,
+			Tittle:       transcript.Tittle,
+			CreatedAt:    transcript.CreatedAt,
+			MatchCount:   len(matches),
+			Matches:      []responses.SearchMatch{},
+		}
+		for i, match := range matches {
+			if i >= maxMatchesPerTranscript {
+				break
+			}
+			result.Matches = append(result.Matches, buildSnippet(transcript.Content, match, matches))
+		}
+		response.Results = append(response.Results, result)
+	}
+
+	return response, nil
+}
+
+type match struct {
+	Start   int
+	End     int
+	Speaker string
+}
+
+// Finds every occurrence of every phrase in the content.
+// Returns nothing unless each phrase occurs at least once (after the speaker filter).
+func findMatches(content string, phrases [][]string, speakers []string) []match {
+	tokens := tokenize(content)
+	segments := transcripts.ParseSegments(content)
+
+	matches := []match{}
+	for _, phrase := range phrases {
+		phraseMatched := false
+		for i := 0; i+len(phrase) <= len(tokens); i++ {
+			if !phraseAt(tokens, i, phrase) {
+				continue
+			}
+			start := tokens[i].Start
+			speaker := speakerAt(segments, start)
+			if !containsAnySpeaker([]string{speaker}, speakers) {
+				continue
+			}
+			matches = append(matches, match{Start: start, End: tokens[i+len(phrase)-1].End, Speaker: speaker})
+			phraseMatched = true
+		}
+		if !phraseMatched {
+			return nil
+		}
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		return matches[i].Start < matches[j].Start
+	})
+	return matches
+}
+
+func phraseAt(tokens []token, i int, phrase []string) bool {
+	for j, term := range phrase {
+		if tokens[i+j].Term != term {
+			return false
+		}
+	}
+	return true
+}
+
+// Segments are in content order, so the speaker is the one of the last segment starting before the position
+func speakerAt(segments []transcripts.Segment, position int) string {
+	speaker := ""
+	for _, segment := range segments {
+		if segment.Offset > position {
+			break
+		}
+		speaker = segment.Speaker
+	}
+	return speaker
+}
+
+// Cuts a window of the content around the match, on word boundaries, and marks every match inside it
+func buildSnippet(content string, m match, all []match) responses.SearchMatch {
+	start := runeStart(content, max(0, m.Start-snippetContextBytes))
+	if start > 0 {
+		if i := strings.IndexAny(content[start:m.Start], " \n"); i != -1 {
+			start += i + 1
+		}
+	}
+	end := runeStart(content, min(len(content), m.End+snippetContextBytes))
+	if end < len(content) {
+		if i := strings.LastIndexAny(content[m.End:end], " \n"); i != -1 {
+			end = m.End + i
+		}
+	}
+
+	snippet := responses.SearchMatch{
+		Start:      m.Start,
+		End:        m.End,
+		Speaker:    m.Speaker,
+		Snippet:    strings.ReplaceAll(content[start:end], "\n", " "),
+		Highlights: []responses.SnippetRange{},
+	}
+	for _, other := range all {
+		if other.Start >= start && other.End <= end {
+			snippet.Highlights = append(snippet.Highlights, responses.SnippetRange{
+				Start: other.Start - start,
+				End:   other.End - start,
+			})
+		}
+	}
+
+	return snippet
+}
+
+func containsAllTerms(terms []string, phrases [][]string) bool {
+	set := make(map[string]bool, len(terms))
+	for _, term := range terms {
+		set[term] = true
+	}
+	for _, phrase := range phrases {
+		for _, term := range phrase {
+			if !set[term] {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// An empty filter accepts every speaker
+func containsAnySpeaker(speakers []string, filter []string) bool {
+	if len(filter) == 0 {
+		return true
+	}
+	for _, speaker := range speakers {
+		for _, wanted := range filter {
+			if strings.EqualFold(speaker, wanted) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,58 @@
+package search
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// A normalized word and its byte range in the original text
+type token struct {
+	Term  string
+	Start int
+	End   int
+}
+
+// Splits the text into lower cased words. Anything that is not a letter or a digit is a separator.
+func tokenize(text string) []token {
+	tokens := []token{}
+
+	start := -1
+	for i, r := range text {
+		isWordRune := unicode.IsLetter(r) || unicode.IsDigit(r)
+		if isWordRune && start == -1 {
+			start = i
+		}
+		if !isWordRune && start != -1 {
+			tokens = append(tokens, token{Term: strings.ToLower(text[start:i]), Start: start, End: i})
+			start = -1
+		}
+	}
+	if start != -1 {
+		tokens = append(tokens, token{Term: strings.ToLower(text[start:]), Start: start, End: len(text)})
+	}
+
+	return tokens
+}
+
+// Distinct terms of the text, in order of first appearance
+func distinctTerms(text string) []string {
+	terms := []string{}
+	seen := map[string]bool{}
+	for _, t := range tokenize(text) {
+		if seen[t.Term] {
+			continue
+		}
+		seen[t.Term] = true
+		terms = append(terms, t.Term)
+	}
+	return terms
+}
+
+// Moves the byte position back until it sits on the start of a rune
+func runeStart(text string, i int) int {
+	for i > 0 && i < len(text) && !utf8.RuneStart(text[i]) {
+		i--
+	}
+	return i
+}
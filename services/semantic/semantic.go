@@ -0,0 +1,231 @@
+package semantic
+
+import (
+	"context"
+	"eavesdropper/dtos/resources"
+	"eavesdropper/dtos/responses"
+	db "eavesdropper/services/data/firestore/operations"
+	"eavesdropper/services/embeddings"
+	"eavesdropper/services/pagination"
+	"eavesdropper/services/transcripts"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	DefaultResultsLimit = 10
+	MaxResultsLimit     = 50
+	maxPassageBytes     = 1200 // long speaker turns are split so each passage stays focused
+)
+
+var ErrEmptySemanticQuery = errors.New("semantic search query is empty")
+
+//////// INDEXING ////////
+
+// Embeds every passage of the transcript and replaces the stored vectors.
+// Must be called whenever the transcript content is saved or edited.
+func IndexTranscript(ctx context.Context, userID string, transcript *resources.Transcript) error {
+	provider, err := embeddings.GetProvider()
+	if err != nil {
+		return err
+	}
+
+	passages := splitPassages(transcripts.ParseSegments(transcript.Content))
+	if len(passages) == 0 {
+		return db.DeleteTranscriptPassageEmbeddings(ctx, userID, transcript.ID)
+	}
+
+	texts := make([]string, len(passages))
+	for i, passage := range passages {
+		texts[i] = passage.Text
+		if passage.Speaker != "" {
+			texts[i] = passage.Speaker + ": " + passage.Text
+		}
+	}
+
+	vectors, err := provider.Embed(ctx, texts, embeddings.RetrievalDocument)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	embedded := make([]resources.TranscriptPassageEmbedding, len(passages))
+	for i, passage := range passages {
+		embedded[i] = resources.TranscriptPassageEmbedding{
+			ID:           fmt.Sprintf("%s_%d", transcript.ID, i),
+			TranscriptID: transcript.ID,
+			PassageIndex: i,
+			Speaker:      passage.Speaker,
+			Text:         passage.Text,
+			Offset:       passage.Offset,
+			Provider:     string(provider.Name()),
+			Vector:       vectors[i],
+			CreatedAt:    now,
+		}
+	}
+
+	return db.ReplaceTranscriptPassageEmbeddings(ctx, userID, transcript.ID, embedded)
+}
+
+// Must be called when the transcript is deleted
+func RemoveTranscript(ctx context.Context, userID, transcriptID string) error {
+	return db.DeleteTranscriptPassageEmbeddings(ctx, userID, transcriptID)
+}
+
+// Embeds every transcript of the user again. Used for transcripts saved before embeddings existed
+// or after switching the embedding provider.
+func ReindexUserTranscripts(ctx context.Context, userID string) (int, error) {
+	count := 0
+	var cursor *pagination.Cursor
+	for {
+		userTranscripts, next, err := db.GetUserTranscripts(ctx, userID, nil, cursor, pagination.MaxPageSize)
+		if err != nil {
+			return count, err
+		}
+
+		for i := range userTranscripts {
+			err = IndexTranscript(ctx, userID, &userTranscripts[i])
+			if err != nil {
+				return count, err
+			}
+			count++
+		}
+
+		if next == nil {
+			return count, nil
+		}
+		cursor = next
+	}
+}
+
+// Speaker turns longer than maxPassageBytes are split on whitespace into several passages
+func splitPassages(segments []transcripts.Segment) []transcripts.Segment {
+	passages := []transcripts.Segment{}
+	for _, segment := range segments {
+		text := segment.Text
+		offset := segment.Offset
+		for len(text) > maxPassageBytes {
+			cut := strings.LastIndexAny(text[:maxPassageBytes], " \n")
+			if cut <= 0 {
+				// No whitespace to split on, like CJK text: cut before the rune crossing the limit
+				cut = maxPassageBytes
+				for cut > 0 && !utf8.RuneStart(text[cut]) {
+					cut--
+				}
+			}
+			passages = append(passages, transcripts.Segment{Speaker: segment.Speaker, Text: text[:cut], Offset: offset})
+			text = strings.TrimLeft(text[cut:], " \n")
+			offset = segment.Offset + len(segment.Text) - len(text)
+		}
+		if strings.TrimSpace(text) != "" {
+			passages = append(passages, transcripts.Segment{Speaker: segment.Speaker, Text: text, Offset: offset})
+		}
+	}
+	for i := range passages {
+		passages[i].Index = i
+	}
+	return passages
+}
+
+//////// SEARCH ////////
+
+// Returns the passages most similar to the text, across the transcripts the user owns
+// and the ones other users whitelisted the user on.
+func SearchSimilarPassages(ctx context.Context, userID, text string, limit int) (*responses.SemanticSearchResponse, error) {
+	if strings.TrimSpace(text) == "" {
+		return nil, ErrEmptySemanticQuery
+	}
+	limit = min(limit, MaxResultsLimit)
+	if limit <= 0 {
+		limit = DefaultResultsLimit
+	}
+
+	provider, err := embeddings.GetProvider()
+	if err != nil {
+		return nil, err
+	}
+	vectors, err := provider.Embed(ctx, []string{text}, embeddings.RetrievalQuery)
+	if err != nil {
+		return nil, err
+	}
+	vector := vectors[0]
+
+	type ownedPassage struct {
+		ownerID string
+		passage resources.TranscriptPassageEmbedding
+	}
+	candidates := []ownedPassage{}
+
+	owned, err := db.FindNearestTranscriptPassages(ctx, userID, string(provider.Name()), vector, nil, limit)
+	if err != nil {
+		return nil, err
+	}
+	for _, passage := range owned {
+		candidates = append(candidates, ownedPassage{ownerID: userID, passage: passage})
+	}
+
+	whitelisted, err := db.GetTranscriptsWhitelistedForUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	transcriptIDsByOwner := map[string][]string{}
+	for _, ref := range whitelisted {
+		if ref.OwnerID == userID {
+			continue
+		}
+		transcriptIDsByOwner[ref.OwnerID] = append(transcriptIDsByOwner[ref.OwnerID], ref.TranscriptID)
+	}
+	for ownerID, transcriptIDs := range transcriptIDsByOwner {
+		shared, err := db.FindNearestTranscriptPassages(ctx, ownerID, string(provider.Name()), vector, transcriptIDs, limit)
+		if err != nil {
+			return nil, err
+		}
+		for _, passage := range shared {
+			candidates = append(candidates, ownedPassage{ownerID: ownerID, passage: passage})
+		}
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].passage.VectorDistance < candidates[j].passage.VectorDistance
+	})
+	if len(candidates) > limit {
+		candidates = candidates[:limit]
+	}
+
+	// Trashed transcripts are left out of the results
+	titles := map[string]*string{}
+	response := &responses.SemanticSearchResponse{Results: []responses.SimilarPassage{}}
+	for _, candidate := range candidates {
+		key := candidate.ownerID + "/" + candidate.passage.TranscriptID
+		title, ok := titles[key]
+		if !ok {
+			transcript, err := db.GetUserTranscript(ctx, candidate.ownerID, candidate.passage.TranscriptID)
+			if err != nil {
+				return nil, err
+			}
+			if transcript.TrashedAt == nil {
+				title = &transcript.Tittle
+			}
+			titles[key] = title
+		}
+		if title == nil {
+			continue
+		}
+
+		response.Results = append(response.Results, responses.SimilarPassage{
+			TranscriptID: candidate.passage.TranscriptID,
+			OwnerID:      candidate.ownerID,
+			Tittle:       *title,
+			Speaker:      candidate.passage.Speaker,
+			Text:         candidate.passage.Text,
+			Offset:       candidate.passage.Offset,
+			Score:        1 - candidate.passage.VectorDistance, // cosine distance -> cosine similarity
+		})
+	}
+
+	return response, nil
+}
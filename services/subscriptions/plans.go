@@ -24,6 +24,11 @@ func GetSubscriptionPlans() ([]responses.SubscriptionPlan, error) {
 			return nil, err
 		}
 
+		billsSpeechOnly, err := cnfgs.BillsSpeechOnly(tier)
+		if err != nil {
+			return nil, err
+		}
+
 		var freeAudioSeconds = 0
 		if tier == cnfgs.FreeTrial {
 			freeAudioSeconds = cnfgs.FreeAudioSeconds
@@ -35,6 +40,7 @@ func GetSubscriptionPlans() ([]responses.SubscriptionPlan, error) {
 			Name:                        planName,
 			TranscriptionMonthlySeconds: monthlyLimit,
 			FreeTranscriptionSeconds:    freeAudioSeconds,
+			BillsSpeechOnly:             billsSpeechOnly,
 		}
 		plans = append(plans, plan)
 	}
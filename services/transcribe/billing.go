@@ -0,0 +1,29 @@
+package transcribe
+
+import (
+	cnfgs "eavesdropper/configurations"
+	"time"
+)
+
+// Seconds billed for audio of the exact duration, rounded with cnfgs.AudioBillingRoundingPolicy.
+// Non empty audio is billed at least a second.
+func BilledSeconds(duration time.Duration) int {
+	if duration <= 0 {
+		return 0
+	}
+
+	var seconds time.Duration
+	switch cnfgs.AudioBillingRoundingPolicy {
+	case cnfgs.RoundNearest:
+		seconds = duration.Round(time.Second)
+	case cnfgs.RoundDown:
+		seconds = duration.Truncate(time.Second)
+	default:
+		seconds = duration.Truncate(time.Second)
+		if seconds < duration {
+			seconds += time.Second
+		}
+	}
+
+	return max(int(seconds/time.Second), 1)
+}
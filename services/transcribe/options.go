@@ -0,0 +1,80 @@
+package transcribe
+
+import (
+	cnfgs "eavesdropper/configurations"
+	"fmt"
+	"slices"
+)
+
+type PromptTemplate string
+
+const (
+	DefaultPrompt  PromptTemplate = "default"
+	VerbatimPrompt PromptTemplate = "verbatim" // keeps filler words, false starts and repetitions
+	CleanPrompt    PromptTemplate = "clean"    // removes filler words and fixes punctuation
+)
+
+// Every template keeps the speaker labels, the segments parser and the exports depend on them
+var promptTemplates = map[PromptTemplate]string{
+	DefaultPrompt: `
+			Transcribe the audio. Include speaker labels such as:
+			Speaker 1: ...
+			Speaker 2: ...
+			Try to distinguish between speakers whenever the voice changes.
+			`,
+	VerbatimPrompt: `
+			Transcribe the audio verbatim, word by word. Keep filler words, false starts, repetitions and interruptions.
+			Include speaker labels such as:
+			Speaker 1: ...
+			Speaker 2: ...
+			Try to distinguish between speakers whenever the voice changes.
+			`,
+	CleanPrompt: `
+			Transcribe the audio as clean, readable text. Remove filler words, false starts and repetitions,
+			and fix the punctuation, without changing the meaning. Include speaker labels such as:
+			Speaker 1: ...
+			Speaker 2: ...
+			Try to distinguish between speakers whenever the voice changes.
+			`,
+}
+
+type Options struct {
+	Model    string
+	Template PromptTemplate
+	Language string // spoken language hint, empty lets the model detect it
+}
+
+func DefaultOptions() *Options {
+	return &Options{Model: cnfgs.DefaultTranscriptionModel, Template: DefaultPrompt}
+}
+
+// Fills the empty fields with the defaults and checks the model and the template are supported
+func (o *Options) Validate() error {
+	if o.Model == "" {
+		o.Model = cnfgs.DefaultTranscriptionModel
+	}
+	if o.Template == "" {
+		o.Template = DefaultPrompt
+	}
+	if !slices.Contains(cnfgs.TranscriptionModels, o.Model) {
+		return fmt.Errorf("unsupported model %s, use one of %v", o.Model, cnfgs.TranscriptionModels)
+	}
+	if _, ok := promptTemplates[o.Template]; !ok {
+		return fmt.Errorf("unknown prompt template %s, use default, verbatim or clean", o.Template)
+	}
+	if len(o.Language) > 40 {
+		return fmt.Errorf("language cannot be longer than 40 characters")
+	}
+	return nil
+}
+
+func (o *Options) prompt() string {
+	prompt := promptTemplates[o.Template]
+	if prompt == "" {
+		prompt = promptTemplates[DefaultPrompt]
+	}
+	if o.Language != "" {
+		prompt += fmt.Sprintf("The audio is spoken in %s, write the transcript in that language.\n", o.Language)
+	}
+	return prompt
+}
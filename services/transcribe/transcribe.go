@@ -9,90 +9,121 @@ import (
 	"eavesdropper/services/users"
 	"errors"
 	"fmt"
+	"io"
 	"time"
 
 	s "github.com/stripe/stripe-go/v82"
 	"google.golang.org/genai"
 )
 
+// Seconds of a recording, the plan of the user decides which ones are billed
+type AudioSeconds struct {
+	Total  int
+	Speech int // the total without the non-speech spans
+}
+
+// Same seconds billed whatever the plan, like re-transcriptions billed as the original
+func FixedAudioSeconds(seconds int) AudioSeconds {
+	return AudioSeconds{Total: seconds, Speech: seconds}
+}
+
 func TranscriptionAllowed(
 	ctx context.Context,
 	userID string,
-	audioSeconds int,
+	audio AudioSeconds,
 ) (
 	subscriptionID string,
+	billedSeconds int,
 	consumedFreeSeconds int,
 	err error,
 ) {
 
 	user, err := db.GetUser(userID)
 	if err != nil {
-		return "", 0, err
+		return "", 0, 0, err
 	}
 
-	if user.FreeTranscriptionSeconds >= audioSeconds {
-		return "", audioSeconds, nil
+	freeAudioSeconds, err := billedAudioSeconds(cnfgs.FreeTrial, audio)
+	if err != nil {
+		return "", 0, 0, err
+	}
+	if user.FreeTranscriptionSeconds >= freeAudioSeconds {
+		return "", freeAudioSeconds, freeAudioSeconds, nil
 	}
 
 	// Checks if the user has a valid stripe subscription
 	if user.StripeCustomerID == "" {
-		return "", 0, errs.ErrUserHasNoStripeAccount
+		return "", 0, 0, errs.ErrUserHasNoStripeAccount
 	}
 	timeb4sub := time.Now()
 	stripeSubscription, err := stripe.CheckUserSubscription(ctx, user.StripeCustomerID)
 	if err != nil {
-		return "", 0, err
+		return "", 0, 0, err
 	}
 	fmt.Println("took ", time.Since(timeb4sub), " to check user subscription")
 	if stripeSubscription == nil {
-		return "", 0, errs.ErrUserHasNoActiveSubscription
+		return "", 0, 0, errs.ErrUserHasNoActiveSubscription
 	}
 	if stripeSubscription.Status == s.SubscriptionStatusPastDue {
-		return "", 0, errs.ErrUserSubscriptionIsExpired
+		return "", 0, 0, errs.ErrUserSubscriptionIsExpired
 	}
 	if stripeSubscription.Status != s.SubscriptionStatusActive {
-		return "", 0, errs.ErrUserHasNoActiveSubscription
+		return "", 0, 0, errs.ErrUserHasNoActiveSubscription
 	}
 
 	subscription, err := db.GetSubscription(userID, stripeSubscription.ID)
 	if err != nil {
-		return "", 0, err
+		return "", 0, 0, err
 	}
 	if subscription == nil {
-		return "", 0, errors.New("Subscription not found")
+		return "", 0, 0, errors.New("Subscription not found")
 	}
 	subscriptionTier, err := cnfgs.GetSubscriptionTier(stripeSubscription.PriceID)
 	if err != nil {
-		return "", 0, err
+		return "", 0, 0, err
 	}
 	transcriptionSecondsLimit, err := cnfgs.GetMonthlyAudioSeconds(subscriptionTier)
 	if err != nil {
-		return "", 0, err
+		return "", 0, 0, err
+	}
+	audioSeconds, err := billedAudioSeconds(subscriptionTier, audio)
+	if err != nil {
+		return "", 0, 0, err
 	}
 
 	billingCycleUsage, err := users.GetCurrentBillingCycleUsage(ctx, userID)
 	if err != nil {
-		return "", 0, err
+		return "", 0, 0, err
 	}
 
 	audioSecondsToPay := audioSeconds - user.FreeTranscriptionSeconds
 	if billingCycleUsage.ConsumedInputAudioSeconds+audioSecondsToPay > transcriptionSecondsLimit {
-		return subscription.ID, 0, errs.ErrExceededSubscriptionTranscriptionLimits
+		return subscription.ID, audioSeconds, 0, errs.ErrExceededSubscriptionTranscriptionLimits
 	}
 
-	return subscription.ID, user.FreeTranscriptionSeconds, nil
+	return subscription.ID, audioSeconds, user.FreeTranscriptionSeconds, nil
 }
 
-func TranscribeAudioFile(ctx context.Context, audioFilePath, audioFormat string) (*genai.GenerateContentResponse, error) {
-
-	fmt.Printf("transcribing audio with path %s and format %s", audioFilePath, audioFormat)
+// The speech seconds for the plans billed on speech only, the total seconds for the others
+func billedAudioSeconds(tier cnfgs.SubscriptionTier, audio AudioSeconds) (int, error) {
+	speechOnly, err := cnfgs.BillsSpeechOnly(tier)
+	if err != nil {
+		return 0, err
+	}
+	if speechOnly {
+		return audio.Speech, nil
+	}
+	return audio.Total, nil
+}
 
+// Uploads the audio to gemini as it is read, without knowing its length, and waits until it can be transcribed
+func UploadAudio(ctx context.Context, audio io.Reader, audioFormat string) (*genai.File, error) {
 	client, err := getGenaiClient(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	uploadedFile, err := client.Files.UploadFromPath(ctx, audioFilePath, nil)
+	uploadedFile, err := client.Files.Upload(ctx, audio, &genai.UploadFileConfig{MIMEType: audioFormat})
 	if err != nil {
 		return nil, err
 	}
@@ -102,14 +133,36 @@ func TranscribeAudioFile(ctx context.Context, audioFilePath, audioFormat string)
 		fmt.Println("got err await ready file: ", err)
 	}
 
+	return uploadedFile, nil
+}
+
+// Deletes audio uploaded with UploadAudio that will not be transcribed, gemini would keep it until it expires
+func DeleteUploadedAudio(ctx context.Context, uploadedFile *genai.File) error {
+	client, err := getGenaiClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = client.Files.Delete(ctx, uploadedFile.Name, nil)
+	if err != nil {
+		return fmt.Errorf("failed to delete uploaded audio %s: %w", uploadedFile.Name, err)
+	}
+	return nil
+}
+
+// Transcribes audio uploaded with UploadAudio, with the model and prompt of the options
+func TranscribeUploadedAudio(ctx context.Context, uploadedFile *genai.File, options *Options) (*genai.GenerateContentResponse, error) {
+
+	fmt.Printf("transcribing audio file %s with format %s", uploadedFile.Name, uploadedFile.MIMEType)
+
+	client, err := getGenaiClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	parts := []*genai.Part{
-		genai.NewPartFromText(`
-			Transcribe the audio. Include speaker labels such as:
-			Speaker 1: ...
-			Speaker 2: ...
-			Try to distinguish between speakers whenever the voice changes.
-			`),
-		genai.NewPartFromURI(uploadedFile.URI, audioFormat),
+		genai.NewPartFromText(options.prompt()),
+		genai.NewPartFromURI(uploadedFile.URI, uploadedFile.MIMEType),
 	}
 	contents := []*genai.Content{
 		genai.NewContentFromParts(parts, genai.RoleUser),
@@ -117,7 +170,7 @@ func TranscribeAudioFile(ctx context.Context, audioFilePath, audioFormat string)
 
 	return client.Models.GenerateContent(
 		ctx,
-		"gemini-2.5-flash",
+		options.Model,
 		contents,
 		nil,
 	)
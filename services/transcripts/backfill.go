@@ -0,0 +1,32 @@
+package transcripts
+
+import (
+	"context"
+	db "eavesdropper/services/data/firestore/operations"
+	"log"
+)
+
+const sharingBackfillMigration = "transcriptSharing"
+
+// Sets the sharing fields the listing filters on, on the transcripts saved before they existed.
+// Runs once: the instances started after it completed skip it. Until then the shared filter misses those transcripts.
+func BackfillTranscriptSharing(ctx context.Context) {
+	done, err := db.IsMigrationDone(ctx, sharingBackfillMigration)
+	if err != nil {
+		log.Printf("[Backfill] failed to check the sharing backfill: %s", err)
+		return
+	}
+	if done {
+		return
+	}
+
+	updated, err := db.BackfillTranscriptSharing(ctx)
+	if err != nil {
+		log.Printf("[Backfill] sharing backfill failed after %d transcripts: %s", updated, err)
+		return
+	}
+	if err := db.MarkMigrationDone(ctx, sharingBackfillMigration); err != nil {
+		log.Printf("[Backfill] %s", err)
+	}
+	log.Printf("[Backfill] set the sharing fields of %d transcripts", updated)
+}
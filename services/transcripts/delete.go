@@ -0,0 +1,93 @@
+package transcripts
+
+import (
+	"context"
+	"eavesdropper/dtos/responses"
+	"eavesdropper/errs"
+	db "eavesdropper/services/data/firestore/operations"
+	cloudStorage "eavesdropper/services/data/storage"
+	"errors"
+	"fmt"
+)
+
+// Deletes the transcript and everything derived from it: whitelist, revisions, search index,
+// embeddings, clips and the audio in storage. The billed usage is kept for the billing cycle.
+// The audio stays while another transcript of the same audio (a re-transcription) still uses it.
+//
+// Once the transcript document is gone the cleanup steps are best effort, failed ones are listed in the response.
+// Calling it again for an already deleted transcript retries the cleanup, so it can be safely repeated.
+// Fails with errs.ErrTranscriptNotFound when the transcript never existed.
+func DeleteTranscript(ctx context.Context, userID, transcriptID string) (*responses.DeleteTranscriptResponse, error) {
+	var sessionID string
+
+	transcript, err := db.GetUserTranscript(ctx, userID, transcriptID)
+	switch {
+	case errors.Is(err, errs.ErrTranscriptNotFound):
+		usage, err := db.GetDeletedTranscriptUsage(ctx, userID, transcriptID)
+		if err != nil {
+			return nil, err
+		}
+		if usage == nil {
+			return nil, errs.ErrTranscriptNotFound
+		}
+		sessionID = usage.RecordingSessionID
+	case err != nil:
+		return nil, err
+	default:
+		err = db.DeleteTranscriptKeepingUsage(ctx, userID, transcript)
+		if err != nil {
+			return nil, err
+		}
+		sessionID = db.TranscriptAudioSession(transcript)
+	}
+
+	response := &responses.DeleteTranscriptResponse{
+		TranscriptID: transcriptID,
+		Complete:     true,
+		Failures:     []responses.DeletionFailure{},
+	}
+	step := func(name string, err error) {
+		if err != nil {
+			response.Complete = false
+			response.Failures = append(response.Failures, responses.DeletionFailure{Step: name, Error: err.Error()})
+		}
+	}
+
+	step("whitelist and revisions", db.DeleteTranscriptSubcollections(ctx, userID, transcriptID))
+	step("search index", db.DeleteTranscriptSearchIndex(ctx, userID, transcriptID))
+	step("embeddings", db.DeleteTranscriptPassageEmbeddings(ctx, userID, transcriptID))
+	step("clips", deleteTranscriptClips(ctx, userID, transcriptID))
+
+	if sessionID == "" {
+		return response, nil
+	}
+
+	remaining, err := db.CountSessionTranscripts(ctx, userID, sessionID)
+	step("session transcripts count", err)
+	if err == nil && remaining == 0 {
+		_, err = cloudStorage.DeletePrefix(ctx, cloudStorage.RecordingSessionPrefix(userID, sessionID))
+		step("recording session chunks and manifest", err)
+		_, err = cloudStorage.DeletePrefix(ctx, cloudStorage.OutputsPrefix(userID, sessionID))
+		step("processed audio", err)
+	}
+
+	return response, nil
+}
+
+// Deletes the clips of the transcript with their audio. The audio of the session can outlive the transcript,
+// used by a re-transcription, its clips do not.
+func deleteTranscriptClips(ctx context.Context, userID, transcriptID string) error {
+	transcriptClips, err := db.GetTranscriptAudioClips(ctx, userID, transcriptID)
+	if err != nil {
+		return err
+	}
+	for _, clip := range transcriptClips {
+		if err := cloudStorage.Delete(ctx, clip.StoragePath); err != nil {
+			return fmt.Errorf("failed to delete the audio of clip %s: %w", clip.ID, err)
+		}
+		if err := db.DeleteAudioClip(ctx, userID, clip.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
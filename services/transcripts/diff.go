@@ -0,0 +1,129 @@
+package transcripts
+
+import "strings"
+
+type DiffOpType string
+
+const (
+	DiffEqual  DiffOpType = "equal"
+	DiffInsert DiffOpType = "insert"
+	DiffDelete DiffOpType = "delete"
+)
+
+// Consecutive lines with the same operation
+type DiffOp struct {
+	Op    DiffOpType
+	Lines []string
+}
+
+// Past this many changed lines the diff stops looking for common lines and reports a full replacement.
+// Bounds the memory of the trace, which grows with the square of the edit distance.
+const maxDiffEditDistance = 2000
+
+// Line based diff of two texts using Myers' algorithm (shortest edit script)
+func DiffLines(from, to string) []DiffOp {
+	a := splitLines(from)
+	b := splitLines(to)
+	n, m := len(a), len(b)
+
+	maxD := min(n+m, maxDiffEditDistance)
+	offset := maxD + 1
+	v := make([]int, 2*maxD+3)
+
+	// trace[d] holds the furthest x reached on every diagonal k in [-(d-1), d-1] before step d
+	trace := [][]int{}
+	found := false
+	for d := 0; d <= maxD && !found; d++ {
+		if d == 0 {
+			trace = append(trace, []int{})
+		} else {
+			trace = append(trace, append([]int{}, v[offset-(d-1):offset+d]...))
+		}
+
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1] // down, an insertion
+			} else {
+				x = v[offset+k-1] + 1 // right, a deletion
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				found = true
+				break
+			}
+		}
+	}
+
+	if !found {
+		return mergeOps([]DiffOp{{Op: DiffDelete, Lines: a}, {Op: DiffInsert, Lines: b}})
+	}
+
+	// Walks the trace back from the end to rebuild the edit script, in reverse
+	reversed := []DiffOp{}
+	x, y := n, m
+	for d := len(trace) - 1; d > 0; d-- {
+		previous := trace[d]
+		at := func(k int) int { return previous[k+d-1] }
+
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && at(k-1) < at(k+1)) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := at(prevK)
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			reversed = append(reversed, DiffOp{Op: DiffEqual, Lines: []string{a[x-1]}})
+			x--
+			y--
+		}
+		if x == prevX {
+			reversed = append(reversed, DiffOp{Op: DiffInsert, Lines: []string{b[y-1]}})
+		} else {
+			reversed = append(reversed, DiffOp{Op: DiffDelete, Lines: []string{a[x-1]}})
+		}
+		x, y = prevX, prevY
+	}
+	for x > 0 && y > 0 {
+		reversed = append(reversed, DiffOp{Op: DiffEqual, Lines: []string{a[x-1]}})
+		x--
+		y--
+	}
+
+	ops := make([]DiffOp, len(reversed))
+	for i, op := range reversed {
+		ops[len(reversed)-1-i] = op
+	}
+	return mergeOps(ops)
+}
+
+func mergeOps(ops []DiffOp) []DiffOp {
+	merged := []DiffOp{}
+	for _, op := range ops {
+		if len(op.Lines) == 0 {
+			continue
+		}
+		if len(merged) > 0 && merged[len(merged)-1].Op == op.Op {
+			merged[len(merged)-1].Lines = append(merged[len(merged)-1].Lines, op.Lines...)
+			continue
+		}
+		merged = append(merged, DiffOp{Op: op.Op, Lines: append([]string{}, op.Lines...)})
+	}
+	return merged
+}
+
+func splitLines(text string) []string {
+	if text == "" {
+		return []string{}
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
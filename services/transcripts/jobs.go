@@ -0,0 +1,57 @@
+package transcripts
+
+import (
+	"context"
+	cnfgs "eavesdropper/configurations"
+	"eavesdropper/dtos/resources"
+	db "eavesdropper/services/data/firestore/operations"
+	"log"
+	"time"
+)
+
+// Claims the transcription of the recording session, see db.ClaimTranscriptionJob.
+// With force a session that already has a transcript is transcribed again, and billed again.
+func ClaimTranscription(ctx context.Context, userID, sessionID string, force bool) (*db.TranscriptionClaim, error) {
+	return db.ClaimTranscriptionJob(ctx, userID, sessionID, force)
+}
+
+// Refreshes the claimed job every cnfgs.TranscriptionJobHeartbeatInterval until the returned stop is called,
+// so a retry does not take over a transcription that is still running and bill it twice
+func KeepTranscriptionAlive(userID, sessionID string) (stop func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(cnfgs.TranscriptionJobHeartbeatInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			if err := db.TouchTranscriptionJob(ctx, userID, sessionID); err != nil && ctx.Err() == nil {
+				log.Printf("[Transcription] failed to refresh job of session %s: %s", sessionID, err)
+			}
+		}
+	}()
+
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+func CompleteTranscription(ctx context.Context, userID, sessionID, transcriptID string) error {
+	return db.CompleteTranscriptionJob(ctx, userID, sessionID, transcriptID)
+}
+
+func FailTranscription(ctx context.Context, userID, sessionID string, cause error) error {
+	return db.FailTranscriptionJob(ctx, userID, sessionID, cause)
+}
+
+func GetTranscriptionJob(ctx context.Context, userID, sessionID string) (*resources.TranscriptionJob, error) {
+	return db.GetTranscriptionJob(ctx, userID, sessionID)
+}
@@ -0,0 +1,52 @@
+package transcripts
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Frequent short words of each supported language, rarely shared with the others
+var languageStopwords = map[string][]string{
+	"en": {"the", "and", "is", "are", "you", "that", "this", "with", "have", "what", "was", "not", "it's", "for"},
+	"es": {"el", "los", "las", "que", "y", "es", "con", "por", "para", "una", "pero", "está", "como", "muy"},
+	"pt": {"o", "os", "que", "e", "é", "com", "não", "uma", "para", "mas", "você", "isso", "está", "muito"},
+	"fr": {"le", "les", "et", "est", "une", "avec", "pour", "pas", "que", "vous", "nous", "c'est", "mais", "très"},
+	"de": {"der", "die", "und", "ist", "nicht", "das", "mit", "ich", "sie", "ein", "eine", "auch", "aber", "wir"},
+	"it": {"il", "che", "è", "di", "con", "per", "non", "una", "sono", "ma", "anche", "questo", "molto", "gli"},
+}
+
+// Below this many stopword hits the text is too short to tell
+const minLanguageHits = 5
+
+// Guesses the ISO 639-1 code of the transcript language from its stopwords.
+// Returns an empty string when the language is not supported or the text is too short.
+func DetectLanguage(content string) string {
+	words := map[string]int{}
+	for _, segment := range ParseSegments(content) {
+		for _, word := range strings.FieldsFunc(strings.ToLower(segment.Text), func(r rune) bool {
+			return !unicode.IsLetter(r) && r != '\''
+		}) {
+			words[word]++
+		}
+	}
+
+	best, bestHits, secondHits := "", 0, 0
+	for language, stopwords := range languageStopwords {
+		hits := 0
+		for _, stopword := range stopwords {
+			hits += words[stopword]
+		}
+		switch {
+		case hits > bestHits:
+			best, bestHits, secondHits = language, hits, bestHits
+		case hits > secondHits:
+			secondHits = hits
+		}
+	}
+
+	// Close languages (es and pt) share some words, a tie means no clear winner
+	if bestHits < minLanguageHits || bestHits == secondHits {
+		return ""
+	}
+	return best
+}
@@ -0,0 +1,85 @@
+package transcripts
+
+import (
+	"context"
+	"eavesdropper/errs"
+	db "eavesdropper/services/data/firestore/operations"
+	"fmt"
+	"strings"
+)
+
+const (
+	maxTagsPerTranscript = 20
+	maxTagLength         = 40
+)
+
+// Trims, lowercases and deduplicates the tags so filtering by tag does not depend on how they were typed.
+// Fails with errs.ErrInvalidTags when there are too many or one is too long.
+func NormalizeTags(tags []string) ([]string, error) {
+	normalized := []string{}
+	seen := map[string]bool{}
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.Join(strings.Fields(tag), " "))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		if len([]rune(tag)) > maxTagLength {
+			return nil, fmt.Errorf("%w: tag %q is longer than %d characters", errs.ErrInvalidTags, tag, maxTagLength)
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	if len(normalized) > maxTagsPerTranscript {
+		return nil, fmt.Errorf("%w: a transcript cannot have more than %d tags", errs.ErrInvalidTags, maxTagsPerTranscript)
+	}
+	return normalized, nil
+}
+
+func SetTranscriptTags(ctx context.Context, userID, transcriptID string, tags []string) ([]string, error) {
+	tags, err := NormalizeTags(tags)
+	if err != nil {
+		return nil, err
+	}
+	return tags, db.SetTranscriptTags(ctx, userID, transcriptID, tags)
+}
+
+// Moves the transcripts to the folder, or out of any folder when folderID is empty.
+// All or none are moved.
+func MoveTranscripts(ctx context.Context, userID string, transcriptIDs []string, folderID string) error {
+	if err := checkBulkSize(transcriptIDs); err != nil {
+		return err
+	}
+	if folderID != "" {
+		if _, err := db.GetFolder(ctx, userID, folderID); err != nil {
+			return err
+		}
+	}
+	return db.MoveTranscriptsToFolder(ctx, userID, transcriptIDs, folderID)
+}
+
+// Adds and removes tags on every transcript, all or none are updated.
+// The per transcript tag limit is not enforced here, it would need reading every transcript first.
+func TagTranscripts(ctx context.Context, userID string, transcriptIDs []string, add, remove []string) error {
+	if err := checkBulkSize(transcriptIDs); err != nil {
+		return err
+	}
+	add, err := NormalizeTags(add)
+	if err != nil {
+		return err
+	}
+	remove, err = NormalizeTags(remove)
+	if err != nil {
+		return err
+	}
+	return db.UpdateTranscriptsTags(ctx, userID, transcriptIDs, add, remove)
+}
+
+func checkBulkSize(transcriptIDs []string) error {
+	if len(transcriptIDs) == 0 {
+		return fmt.Errorf("no transcripts given")
+	}
+	if len(transcriptIDs) > db.MaxBulkTranscripts {
+		return fmt.Errorf("cannot update more than %d transcripts at once", db.MaxBulkTranscripts)
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+package transcripts
+
+import (
+	"context"
+	cnfgs "eavesdropper/configurations"
+	"eavesdropper/dtos/requests"
+	"eavesdropper/dtos/resources"
+	"eavesdropper/errs"
+	"eavesdropper/services/audio"
+	db "eavesdropper/services/data/firestore/operations"
+	"eavesdropper/services/transcribe"
+	"eavesdropper/services/users"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// Transcribes the transcript again from the audio archived by its first transcription, without the chunks.
+// The result replaces the content as a new revision or is saved as a sibling transcript.
+// Billed re-transcriptions go through the same limits as new ones, complaints are not billed
+// and limited to cnfgs.MaxComplaintRetranscriptions per audio. Every run is recorded with its usage.
+// Returns the updated or the new transcript.
+func Retranscribe(ctx context.Context, userID, transcriptID string, req *requests.RetranscribeTranscript) (*resources.Transcript, error) {
+	options := &transcribe.Options{
+		Model:    req.Model,
+		Template: transcribe.PromptTemplate(req.PromptTemplate),
+		Language: strings.TrimSpace(req.Language),
+	}
+	if err := options.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %s", errs.ErrInvalidRetranscription, err)
+	}
+	if req.SaveAs == "" {
+		req.SaveAs = requests.RetranscribeAsRevision
+	}
+	if req.SaveAs != requests.RetranscribeAsRevision && req.SaveAs != requests.RetranscribeAsSibling {
+		return nil, fmt.Errorf("%w: saveAs must be revision or sibling", errs.ErrInvalidRetranscription)
+	}
+	if req.Billing != requests.RetranscriptionBilled && req.Billing != requests.RetranscriptionComplaint {
+		return nil, fmt.Errorf("%w: billing must be billed or complaint", errs.ErrInvalidRetranscription)
+	}
+	billed := req.Billing == requests.RetranscriptionBilled
+
+	original, err := db.GetUserTranscript(ctx, userID, transcriptID)
+	if err != nil {
+		return nil, err
+	}
+	if original.TrashedAt != nil {
+		return nil, errs.ErrTranscriptNotFound
+	}
+	audioSessionID := db.TranscriptAudioSession(original)
+	if audioSessionID == "" {
+		return nil, errs.ErrArchivedAudioNotFound
+	}
+
+	// Checked again when saving, this only avoids transcribing a complaint that cannot be saved
+	if !billed {
+		complaints, err := db.CountComplaintRetranscriptions(ctx, userID, audioSessionID)
+		if err != nil {
+			return nil, err
+		}
+		if complaints >= cnfgs.MaxComplaintRetranscriptions {
+			return nil, errs.ErrComplaintRetranscriptionsExceeded
+		}
+	}
+
+	// The audio is the same, so is its duration
+	audioSeconds := original.ConsumedInputAudioSeconds
+	consumedFreeAudioSeconds := 0
+	if billed {
+		_, _, consumedFreeAudioSeconds, err = transcribe.TranscriptionAllowed(ctx, userID, transcribe.FixedAudioSeconds(audioSeconds))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Streams the archived audio from storage to gemini, which reads the compressed formats as they are
+	archived, err := audio.OpenArchivedAudio(ctx, userID, audioSessionID)
+	if err != nil {
+		return nil, err
+	}
+	uploadedFile, err := transcribe.UploadAudio(ctx, archived.Content, archived.ContentType)
+	archived.Content.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to upload archived audio: %w", err)
+	}
+	// Only needed for this run, gemini would keep it until it expires
+	defer func() {
+		if err := transcribe.DeleteUploadedAudio(context.Background(), uploadedFile); err != nil {
+			log.Printf("[Retranscribe] failed to delete the uploaded audio of transcript %s: %s", transcriptID, err)
+		}
+	}()
+
+	genaiResponse, err := transcribe.TranscribeUploadedAudio(ctx, uploadedFile, options)
+	if err != nil {
+		return nil, fmt.Errorf("failed to transcribe audio file: %w", err)
+	}
+	content := genaiResponse.Text()
+	inputTokens := int(genaiResponse.UsageMetadata.PromptTokenCount)
+	outputTokens := int(genaiResponse.UsageMetadata.CandidatesTokenCount)
+
+	usage := &resources.RetranscriptionUsage{
+		TranscriptID:              transcriptID,
+		AudioSessionID:            audioSessionID,
+		Model:                     options.Model,
+		Billed:                    billed,
+		ConsumedInputAudioSeconds: audioSeconds,
+		ConsumedFreeAudioSeconds:  consumedFreeAudioSeconds,
+		ConsumedInputTokens:       inputTokens,
+		ConsumedOutputTokens:      outputTokens,
+	}
+
+	var result *resources.Transcript
+	if req.SaveAs == requests.RetranscribeAsSibling {
+		result, err = db.SaveSiblingTranscript(
+			ctx, userID, original, content, DetectLanguage(content),
+			consumedFreeAudioSeconds, inputTokens, outputTokens, !billed, usage,
+		)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		// Applied on top of the latest version, edits made meanwhile stay in the revisions
+		current, err := db.GetUserTranscript(ctx, userID, transcriptID)
+		if err != nil {
+			return nil, err
+		}
+		result, err = db.SaveRetranscribedRevision(
+			ctx, userID, transcriptID, content, DetectLanguage(content), Version(current), usage,
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if consumedFreeAudioSeconds > 0 {
+		go users.DecrementFreeTier(userID, consumedFreeAudioSeconds)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,142 @@
+package transcripts
+
+import (
+	"context"
+	"eavesdropper/dtos/resources"
+	"eavesdropper/dtos/responses"
+	"eavesdropper/errs"
+	db "eavesdropper/services/data/firestore/operations"
+)
+
+// Current content version of the transcript, used as its ETag
+func Version(transcript *resources.Transcript) int {
+	return db.TranscriptVersion(transcript)
+}
+
+func UpdateTranscriptContent(
+	ctx context.Context,
+	userID, transcriptID, authorID string,
+	content string,
+	expectedVersion int,
+) (*resources.Transcript, error) {
+	return db.UpdateTranscriptContent(
+		ctx, userID, transcriptID, authorID, content, DetectLanguage(content), expectedVersion, resources.RevisionFromEdit, 0,
+	)
+}
+
+// Makes the content of an earlier revision the current one. The history is kept, the restore is a new revision.
+// Trashed transcripts are not found, they are restored from the trash before being changed.
+func RestoreTranscriptRevision(
+	ctx context.Context,
+	userID, transcriptID, authorID string,
+	version int,
+	expectedVersion int,
+) (*resources.Transcript, error) {
+	revision, err := GetTranscriptRevision(ctx, userID, transcriptID, version)
+	if err != nil {
+		return nil, err
+	}
+
+	return db.UpdateTranscriptContent(
+		ctx,
+		userID, transcriptID, authorID,
+		revision.Content,
+		DetectLanguage(revision.Content),
+		expectedVersion,
+		resources.RevisionFromRestore,
+		revision.Version,
+	)
+}
+
+// Newest first. The revisions of a trashed transcript are not found, like the transcript.
+func GetTranscriptRevisions(ctx context.Context, userID, transcriptID string) ([]resources.TranscriptRevision, error) {
+	transcript, err := untrashedTranscript(ctx, userID, transcriptID)
+	if err != nil {
+		return nil, err
+	}
+	revisions, err := db.GetTranscriptRevisions(ctx, userID, transcriptID)
+	if err != nil {
+		return nil, err
+	}
+	if len(revisions) > 0 {
+		return revisions, nil
+	}
+
+	// Transcripts saved before revisions existed and never edited have their content as the only revision
+	legacy, err := legacyRevision(transcript, db.FirstTranscriptVersion)
+	if err != nil {
+		return nil, err
+	}
+	return []resources.TranscriptRevision{*legacy}, nil
+}
+
+func GetTranscriptRevision(ctx context.Context, userID, transcriptID string, version int) (*resources.TranscriptRevision, error) {
+	transcript, err := untrashedTranscript(ctx, userID, transcriptID)
+	if err != nil {
+		return nil, err
+	}
+	revision, err := db.GetTranscriptRevision(ctx, userID, transcriptID, version)
+	if err == errs.ErrTranscriptRevisionNotFound && version == db.FirstTranscriptVersion {
+		return legacyRevision(transcript, version)
+	}
+	return revision, err
+}
+
+// Fails with errs.ErrTranscriptNotFound when the transcript is missing or in the trash
+func untrashedTranscript(ctx context.Context, userID, transcriptID string) (*resources.Transcript, error) {
+	transcript, err := db.GetUserTranscript(ctx, userID, transcriptID)
+	if err != nil {
+		return nil, err
+	}
+	if transcript.TrashedAt != nil {
+		return nil, errs.ErrTranscriptNotFound
+	}
+	return transcript, nil
+}
+
+func legacyRevision(transcript *resources.Transcript, version int) (*resources.TranscriptRevision, error) {
+	if transcript.Version != 0 {
+		return nil, errs.ErrTranscriptRevisionNotFound
+	}
+
+	return &resources.TranscriptRevision{
+		Version:   version,
+		Content:   transcript.Content,
+		Source:    resources.RevisionFromTranscription,
+		AuthorRef: transcript.UserRef,
+		CreatedAt: transcript.CreatedAt,
+	}, nil
+}
+
+// Line diff going from the content of one revision to the other
+func DiffTranscriptRevisions(
+	ctx context.Context,
+	userID, transcriptID string,
+	fromVersion, toVersion int,
+) (*responses.TranscriptRevisionDiff, error) {
+	from, err := GetTranscriptRevision(ctx, userID, transcriptID, fromVersion)
+	if err != nil {
+		return nil, err
+	}
+	to, err := GetTranscriptRevision(ctx, userID, transcriptID, toVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := &responses.TranscriptRevisionDiff{
+		FromVersion: fromVersion,
+		ToVersion:   toVersion,
+		Ops:         []responses.DiffOp{},
+	}
+	for _, op := range DiffLines(from.Content, to.Content) {
+		switch op.Op {
+		case DiffInsert:
+			diff.Insertions += len(op.Lines)
+		case DiffDelete:
+			diff.Deletions += len(op.Lines)
+		}
+		diff.Ops = append(diff.Ops, responses.DiffOp{Op: string(op.Op), Lines: op.Lines})
+	}
+
+	return diff, nil
+}
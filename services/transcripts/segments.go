@@ -0,0 +1,74 @@
+package transcripts
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Matches the speaker labels gemini is prompted to write, like "Speaker 1: ..."
+var speakerLabelRegex = regexp.MustCompile(`^\s*\**(Speaker \d+)\**\s*:\s*`)
+
+// A piece of the transcript content said by a single speaker.
+// Offset is the byte position of Text inside the transcript content.
+type Segment struct {
+	Index   int
+	Speaker string
+	Text    string
+	Offset  int
+}
+
+// Splits the transcript content into segments, one per speaker turn.
+// Lines without a speaker label are appended to the previous segment.
+func ParseSegments(content string) []Segment {
+	segments := []Segment{}
+
+	offset := 0
+	for _, line := range strings.SplitAfter(content, "\n") {
+		lineOffset := offset
+		offset += len(line)
+
+		trimmed := strings.TrimRight(line, "\r\n")
+		if strings.TrimSpace(trimmed) == "" {
+			continue
+		}
+
+		match := speakerLabelRegex.FindStringSubmatchIndex(trimmed)
+		if match != nil {
+			segments = append(segments, Segment{
+				Index:   len(segments),
+				Speaker: trimmed[match[2]:match[3]],
+				Text:    trimmed[match[1]:],
+				Offset:  lineOffset + match[1],
+			})
+			continue
+		}
+
+		if len(segments) == 0 {
+			segments = append(segments, Segment{
+				Index:  0,
+				Text:   trimmed,
+				Offset: lineOffset,
+			})
+			continue
+		}
+
+		last := &segments[len(segments)-1]
+		last.Text += "\n" + trimmed
+	}
+
+	return segments
+}
+
+// Returns the distinct speaker labels in order of first appearance
+func Speakers(content string) []string {
+	speakers := []string{}
+	seen := map[string]bool{}
+	for _, segment := range ParseSegments(content) {
+		if segment.Speaker == "" || seen[segment.Speaker] {
+			continue
+		}
+		seen[segment.Speaker] = true
+		speakers = append(speakers, segment.Speaker)
+	}
+	return speakers
+}
@@ -2,9 +2,11 @@ package transcripts
 
 import (
 	"context"
+	"eavesdropper/dtos/requests"
 	"eavesdropper/dtos/resources"
 	"eavesdropper/services/data/firestore/operations"
 	db "eavesdropper/services/data/firestore/operations"
+	"eavesdropper/services/pagination"
 
 	"google.golang.org/genai"
 )
@@ -15,24 +17,35 @@ func SaveTranscript(
 	userID,
 	recordingSessionID string,
 	audioSeconds, consumedFreeAudioSeconds int,
+	audio operations.TranscriptAudio,
 ) (*resources.Transcript, error) {
 
+	content := genaiResponse.Text()
+
 	transcript, err := operations.SaveTranscript(
 		ctx,
 		userID,
 		recordingSessionID,
-		genaiResponse.Text(),
+		content,
+		DetectLanguage(content),
 		audioSeconds,
 		consumedFreeAudioSeconds,
 		int(genaiResponse.UsageMetadata.PromptTokenCount),
 		int(genaiResponse.UsageMetadata.CandidatesTokenCount),
+		audio,
 	)
 
 	return transcript, err
 }
 
-func GetUserTranscripts(ctx context.Context, userID string, pageI int, pageSize int) ([]resources.Transcript, error) {
-	return db.GetUserTranscripts(ctx, userID, pageI, pageSize)
+func GetUserTranscripts(
+	ctx context.Context,
+	userID string,
+	filters *requests.TranscriptListFilters,
+	after *pagination.Cursor,
+	limit int,
+) ([]resources.Transcript, *pagination.Cursor, error) {
+	return db.GetUserTranscripts(ctx, userID, filters, after, pagination.ClampLimit(limit))
 }
 
 func GetUserTranscript(ctx context.Context, userID, transcriptID string) (*resources.Transcript, error) {
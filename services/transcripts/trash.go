@@ -0,0 +1,102 @@
+package transcripts
+
+import (
+	"context"
+	cnfgs "eavesdropper/configurations"
+	"eavesdropper/dtos/resources"
+	"eavesdropper/errs"
+	db "eavesdropper/services/data/firestore/operations"
+	"errors"
+	"log"
+	"time"
+)
+
+// Transcripts purged per run, the rest wait for the next run
+const trashPurgeBatchSize = 100
+
+// Moves the transcript to the trash. It disappears from the listings, the search and its shared link,
+// keeps counting for billing, and is permanently deleted after cnfgs.TrashRetention.
+func TrashTranscript(ctx context.Context, userID, transcriptID string) (*resources.Transcript, error) {
+	transcript, _, err := db.TrashTranscript(ctx, userID, transcriptID)
+	return transcript, err
+}
+
+// Restores the transcript from the trash. When its folder was deleted meanwhile it goes back to the top level.
+func RestoreTrashedTranscript(ctx context.Context, userID, transcriptID string) error {
+	err := db.RestoreTrashedTranscript(ctx, userID, transcriptID)
+	if err != nil {
+		return err
+	}
+
+	transcript, err := db.GetUserTranscript(ctx, userID, transcriptID)
+	if err != nil {
+		return err
+	}
+	if transcript.FolderID == "" {
+		return nil
+	}
+	_, err = db.GetFolder(ctx, userID, transcript.FolderID)
+	if errors.Is(err, errs.ErrFolderNotFound) {
+		return db.MoveTranscriptsToFolder(ctx, userID, []string{transcriptID}, "")
+	}
+	return err
+}
+
+func GetTrashedUserTranscripts(ctx context.Context, userID string) ([]resources.Transcript, error) {
+	return db.GetTrashedUserTranscripts(ctx, userID)
+}
+
+// When a trashed transcript will be permanently deleted
+func PurgeTime(transcript *resources.Transcript) *time.Time {
+	if transcript.TrashedAt == nil {
+		return nil
+	}
+	purgeAt := transcript.TrashedAt.Add(cnfgs.TrashRetention)
+	return &purgeAt
+}
+
+// Permanently deletes the transcripts trashed for longer than the retention period.
+// Returns how many were purged.
+func PurgeExpiredTrash(ctx context.Context) (int, error) {
+	expired, err := db.GetTranscriptsTrashedBefore(ctx, time.Now().Add(-cnfgs.TrashRetention), trashPurgeBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	for _, owned := range expired {
+		report, err := DeleteTranscript(ctx, owned.OwnerID, owned.Transcript.ID)
+		if err != nil {
+			log.Printf("[Trash] failed to purge transcript %s: %s", owned.Transcript.ID, err)
+			continue
+		}
+		if !report.Complete {
+			log.Printf("[Trash] incomplete cleanup of transcript %s: %v", owned.Transcript.ID, report.Failures)
+		}
+		purged++
+	}
+
+	return purged, nil
+}
+
+// Runs PurgeExpiredTrash every cnfgs.TrashPurgeInterval until the context is cancelled.
+// On Cloud Run this only runs while an instance is alive, a missed run is caught up by the next one.
+func RunTrashPurge(ctx context.Context) {
+	ticker := time.NewTicker(cnfgs.TrashPurgeInterval)
+	defer ticker.Stop()
+
+	for {
+		purged, err := PurgeExpiredTrash(ctx)
+		if err != nil {
+			log.Printf("[Trash] purge failed: %s", err)
+		} else if purged > 0 {
+			log.Printf("[Trash] purged %d transcripts", purged)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"eavesdropper/dtos/requests"
 	"eavesdropper/dtos/resources"
 	"eavesdropper/dtos/responses"
+	"eavesdropper/errs"
 	firestoreClient "eavesdropper/services/data/firestore/client"
 	"eavesdropper/services/data/firestore/collections"
 	"eavesdropper/services/data/firestore/operations"
@@ -93,11 +94,27 @@ func GetUser(id string) (*resources.User, error) {
 	return db.GetUser(id)
 }
 
+// The enhancement profile the user picked for their transcriptions, the default when they picked none
+func PreferredAudioEnhancement(id string) (cnfgs.AudioEnhancement, error) {
+	user, err := db.GetUser(id)
+	if err != nil {
+		return "", err
+	}
+	if !cnfgs.IsAudioEnhancement(user.AudioEnhancement) {
+		return cnfgs.DefaultAudioEnhancement, nil
+	}
+	return cnfgs.AudioEnhancement(user.AudioEnhancement), nil
+}
+
 func HandleIsAvailable(ctx context.Context, handle string) (bool, error) {
 	return db.HandleIsAvailable(ctx, handle)
 }
 
 func UpdateUser(id string, data *requests.UpdateUser) error {
+	if data.AudioEnhancement != nil && !cnfgs.IsAudioEnhancement(*data.AudioEnhancement) {
+		return fmt.Errorf("%w: unknown profile %q", errs.ErrInvalidAudioEnhancement, *data.AudioEnhancement)
+	}
+
 	userRef := collections.Users.Doc(id)
 
 	transaction := func(ctx context.Context, tx *firestore.Transaction) error {
@@ -124,6 +141,9 @@ func UpdateUser(id string, data *requests.UpdateUser) error {
 		if data.PrefersDarkMode != nil {
 			user.PrefersDarkMode = *data.PrefersDarkMode
 		}
+		if data.AudioEnhancement != nil {
+			user.AudioEnhancement = *data.AudioEnhancement
+		}
 
 		return tx.Set(userRef, user)
 	}
@@ -185,22 +205,56 @@ func GetCurrentBillingCycleUsage(ctx context.Context, userID string) (*responses
 
 	fmt.Printf("got %v\n", transcripts)
 
+	// Deleted transcripts still count, their usage was billed
+	deletedUsages, err := db.GetDeletedTranscriptUsages(ctx, userID, billingCycleStart, billingCycleEnd)
+	if err != nil {
+		return nil, err
+	}
+
+	// Re-transcriptions saved as new revisions are billed apart from their transcript
+	retranscriptionUsages, err := db.GetBilledRetranscriptionUsages(ctx, userID, billingCycleStart, billingCycleEnd)
+	if err != nil {
+		return nil, err
+	}
+
 	usage := &responses.SubscriptionUsage{
 		SubscriptonPlanName:        planName,
-		TranscriptsCount:           len(transcripts),
 		SubscriptionMonthlyMinutes: subscriptionMonthlyLimit,
 		RenewsAt:                   renewalDate,
 	}
 
-	fmt.Printf("Calculating usage for %d transcripts\n", usage.TranscriptsCount)
+	fmt.Printf("Calculating usage for %d transcripts\n", len(transcripts)+len(deletedUsages))
 
+	// Complimentary re-transcriptions are neither billed nor counted
 	for _, t := range transcripts {
+		if t.Complimentary {
+			continue
+		}
+		usage.TranscriptsCount++
 		usage.ConsumedInputAudioSeconds += t.ConsumedInputAudioSeconds
 		usage.ConsumedFreeInputAudioSeconds += t.ConsumedFreeAudioSeconds
 		usage.ConsumedPaidInputAudioSeconds += (t.ConsumedInputAudioSeconds - t.ConsumedFreeAudioSeconds)
 		usage.ConsumedTotalInputTokens += t.ConsumedInputTokens
 		usage.ConsumedOutputTokens += t.ConsumedOutputTokens
 	}
+	for _, u := range deletedUsages {
+		if u.Complimentary {
+			continue
+		}
+		usage.TranscriptsCount++
+		usage.ConsumedInputAudioSeconds += u.ConsumedInputAudioSeconds
+		usage.ConsumedFreeInputAudioSeconds += u.ConsumedFreeAudioSeconds
+		usage.ConsumedPaidInputAudioSeconds += (u.ConsumedInputAudioSeconds - u.ConsumedFreeAudioSeconds)
+		usage.ConsumedTotalInputTokens += u.ConsumedInputTokens
+		usage.ConsumedOutputTokens += u.ConsumedOutputTokens
+	}
+	for _, u := range retranscriptionUsages {
+		usage.ConsumedInputAudioSeconds += u.ConsumedInputAudioSeconds
+		usage.ConsumedFreeInputAudioSeconds += u.ConsumedFreeAudioSeconds
+		usage.ConsumedPaidInputAudioSeconds += (u.ConsumedInputAudioSeconds - u.ConsumedFreeAudioSeconds)
+		usage.ConsumedTotalInputTokens += u.ConsumedInputTokens
+		usage.ConsumedOutputTokens += u.ConsumedOutputTokens
+	}
 
 	return usage, nil
 }
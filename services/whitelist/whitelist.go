@@ -6,6 +6,7 @@ import (
 	"eavesdropper/dtos/resources"
 	"eavesdropper/dtos/responses"
 	"eavesdropper/services/data/firestore/operations"
+	"eavesdropper/services/pagination"
 )
 
 func AddUsersToTranscriptWhitelist(ctx context.Context, userID, transcriptID string, req *requests.AddToWhitelistRequest) error {
@@ -25,8 +26,15 @@ func RemoveUsersFromTranscriptWhitelist(ctx context.Context, userID, transcriptI
 	return operations.RemoveTranscriptWhitelistedUsers(ctx, userID, transcriptID, req.Handles, req.Emails)
 }
 
-func GetTranscriptWhitelist(ctx context.Context, userID, transcriptID string) (*responses.WhitelistResponse, error) {
-	whitelistedUsers, err := operations.GetTranscriptWhitelistedUsers(ctx, userID, transcriptID)
+func GetTranscriptWhitelist(
+	ctx context.Context,
+	userID, transcriptID string,
+	after *pagination.Cursor,
+	limit int,
+) (*responses.WhitelistResponse, error) {
+	whitelistedUsers, nextCursor, err := operations.GetTranscriptWhitelistedUsers(
+		ctx, userID, transcriptID, after, pagination.ClampLimit(limit),
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -54,7 +62,8 @@ func GetTranscriptWhitelist(ctx context.Context, userID, transcriptID string) (*
 	}
 
 	return &responses.WhitelistResponse{
-		Users: responseUsers,
+		Users:      responseUsers,
+		NextCursor: nextCursor.Encode(),
 	}, nil
 }
 